@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/shashimalcse/cronuseo/internal/check"
+	"github.com/shashimalcse/cronuseo/internal/config"
+	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
+	"github.com/shashimalcse/cronuseo/internal/event"
+	"github.com/shashimalcse/cronuseo/internal/logger"
+	"github.com/shashimalcse/cronuseo/internal/organization"
+	"github.com/shashimalcse/cronuseo/internal/relation"
+	"github.com/shashimalcse/cronuseo/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+var Version = "1.0.0"
+
+// Default config flag.
+var flagConfig = flag.String("config", "./config/local.yml", "path to the config file")
+
+func main() {
+
+	flag.Parse()
+
+	// Load configurations.
+	cfg, err := config.Load(*flagConfig)
+	if err != nil {
+		log.Fatalf("Error while loading config: %v\n", err)
+	}
+
+	// Set up logger.
+	logger, err := logger.Init(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v\n", err)
+	}
+
+	if cfg.CheckServer.Endpoint == "" || cfg.CheckServer.GrpcEndpoint == "" {
+		logger.Fatal("Check server endpoints are not configured")
+	}
+
+	logger.Info("Config level : ", zap.String("level", cfg.Config.Level))
+	// Mongo client.
+	mongodb, err := db.Init(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize MongoDB client", zap.Error(err))
+	}
+
+	events := event.NewBus()
+	watcherEvents := event.NewBus()
+	checkRepo := check.NewRepository(mongodb)
+	var cache *check.CachedRepository
+	if cfg.CheckCache.Enabled {
+		cache = check.NewCachedRepository(checkRepo, cfg.CheckCache.TTL)
+		checkRepo = cache
+		events.Subscribe(cache.HandleEvent)
+		// Most mutations happen in other processes, so without a watcher snapshots are only refreshed when they expire.
+		if cfg.CheckCache.Watch {
+			watcherEvents.Subscribe(cache.HandleEvent)
+			go event.NewWatcher(mongodb, watcherEvents, logger, cfg.CheckCache.PollInterval).Run(context.Background())
+		}
+	}
+	checkService := check.NewService(checkRepo, logger)
+	events.Subscribe(checkService.HandleEvent)
+	watcherEvents.Subscribe(checkService.HandleEvent)
+	relationService := relation.NewService(relation.NewRepository(mongodb), logger, events)
+	orgService := organization.NewService(organization.NewRepository(mongodb), logger, events)
+
+	// gRPC server.
+	listener, err := net.Listen("tcp", cfg.CheckServer.GrpcEndpoint)
+	if err != nil {
+		logger.Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+	grpcServer := BuildGrpcServer(logger, checkService, relation.NewGrpcService(relationService, orgService, checkService, logger))
+	go func() {
+		logger.Info("Starting gRPC check server", zap.String("grpc_endpoint", cfg.CheckServer.GrpcEndpoint))
+		if err := grpcServer.Serve(listener); err != nil {
+			logger.Fatal("Error while starting gRPC check server", zap.Error(err))
+		}
+	}()
+
+	// REST server.
+	logger.Info("Starting check server", zap.String("server_endpoint", cfg.CheckServer.Endpoint))
+	if err := BuildServer(checkService, cache).Start(cfg.CheckServer.Endpoint); err != nil {
+		logger.Fatal("Error while starting check server", zap.Error(err))
+	}
+}
+
+// BuildGrpcServer builds the gRPC server and registers the check and relation services.
+func BuildGrpcServer(logger *zap.Logger, checkService check.Service, relationServer proto.RelationServer) *grpc.Server {
+
+	grpcServer := grpc.NewServer()
+	proto.RegisterCheckServer(grpcServer, check.NewGrpcService(checkService, logger))
+	proto.RegisterRelationServer(grpcServer, relationServer)
+	return grpcServer
+}
+
+// BuildServer builds the echo server exposing the REST check endpoint, and the cache statistics when caching is enabled.
+func BuildServer(checkService check.Service, cache *check.CachedRepository) *echo.Echo {
+
+	e := echo.New()
+
+	// Logger middleware.
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: "${time_rfc3339}; method=${method}; uri=${uri}; status=${status};\n",
+	}))
+
+	apiV1 := e.Group("/api/v1")
+	check.RegisterHandlers(apiV1, checkService)
+	if cache != nil {
+		check.RegisterCacheHandlers(apiV1, cache)
+	}
+
+	return e
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -11,13 +12,16 @@ import (
 	"github.com/shashimalcse/cronuseo/internal/check"
 	"github.com/shashimalcse/cronuseo/internal/config"
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/group"
 	"github.com/shashimalcse/cronuseo/internal/logger"
 	mw "github.com/shashimalcse/cronuseo/internal/middleware"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
 	"github.com/shashimalcse/cronuseo/internal/organization"
 	"github.com/shashimalcse/cronuseo/internal/policy"
+	"github.com/shashimalcse/cronuseo/internal/relation"
 	"github.com/shashimalcse/cronuseo/internal/resource"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/role"
 	"github.com/shashimalcse/cronuseo/internal/user"
 	echoSwagger "github.com/swaggo/echo-swagger"
@@ -89,15 +93,34 @@ func BuildServer(
 	// API route groups.
 	apiV1 := e.Group("/api/v1")
 
-	requiredPermissions := getRequiredPermissions(cfg.APIEndpoints)
+	requiredPermissions := mw.RequiredPermissions(cfg.APIEndpoints)
+	events := event.NewBus()
+	watcherEvents := event.NewBus()
 	checkRepo := check.NewRepository(mongodb)
+	var cache *check.CachedRepository
+	if cfg.CheckCache.Enabled {
+		cache = check.NewCachedRepository(checkRepo, cfg.CheckCache.TTL)
+		events.Subscribe(cache.HandleEvent)
+		checkRepo = cache
+		if cfg.CheckCache.Watch {
+			// Pick up changes made through other instances.
+			watcherEvents.Subscribe(cache.HandleEvent)
+			go event.NewWatcher(mongodb, watcherEvents, logger, cfg.CheckCache.PollInterval).Run(context.Background())
+		}
+	}
 	checkService := check.NewService(checkRepo, logger)
+	events.Subscribe(checkService.HandleEvent)
+	watcherEvents.Subscribe(checkService.HandleEvent)
 	check.RegisterHandlers(apiV1, checkService)
+	if cache != nil {
+		check.RegisterCacheHandlers(apiV1, cache)
+	}
 	// Apply middleware specific to API routes if needed.
 	apiV1.Use(mw.Auth(cfg, logger, requiredPermissions, checkService))
+	apiV1.Use(revision.Middleware())
 
 	// Register service handlers.
-	registerServiceHandlers(apiV1, mongodb, cfg, logger)
+	registerServiceHandlers(apiV1, mongodb, cfg, logger, events)
 
 	return e
 }
@@ -107,6 +130,7 @@ func setupMiddleware(e *echo.Echo, cfg *config.Config) {
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowCredentials: true,
 		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "API_KEY"},
+		ExposeHeaders:    []string{revision.Header},
 		AllowOrigins:     []string{"http://localhost:3000"},
 	}))
 
@@ -119,7 +143,7 @@ func setupMiddleware(e *echo.Echo, cfg *config.Config) {
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 }
 
-func registerServiceHandlers(e *echo.Group, mongodb *db.MongoDB, cfg *config.Config, logger *zap.Logger) {
+func registerServiceHandlers(e *echo.Group, mongodb *db.MongoDB, cfg *config.Config, logger *zap.Logger, events *event.Bus) {
 	// Initialize repositories.
 	orgRepo := organization.NewRepository(mongodb)
 	userRepo := user.NewRepository(mongodb)
@@ -127,17 +151,24 @@ func registerServiceHandlers(e *echo.Group, mongodb *db.MongoDB, cfg *config.Con
 	roleRepo := role.NewRepository(mongodb)
 	groupRepo := group.NewRepository(mongodb)
 	policyRepo := policy.NewRepository(mongodb)
+	relationRepo := relation.NewRepository(mongodb)
 
 	// Initialize services with repositories.
-	orgService := organization.NewService(orgRepo, logger)
-	resourceService := resource.NewService(resourceRepo, logger)
-	roleService := role.NewService(roleRepo, logger)
-	userService := user.NewService(userRepo, logger, roleService)
-	groupService := group.NewService(groupRepo, logger)
-	policyService := policy.NewService(policyRepo, logger)
+	orgService := organization.NewService(orgRepo, logger, events)
+	resourceService := resource.NewService(resourceRepo, logger, events)
+	roleService := role.NewService(roleRepo, logger, events)
+	userService := user.NewService(userRepo, logger, roleService, events)
+	groupService := group.NewService(groupRepo, logger, events)
+	policyService := policy.NewService(policyRepo, logger, events)
+	relationService := relation.NewService(relationRepo, logger, events)
 
 	initializeRootOrganization(orgService, userService, groupService, roleService, resourceService, cfg, logger)
 
+	// Remove role assignments of users and groups once they expire, from the one instance the sweeper is enabled in.
+	if cfg.RoleSweeper.Enabled {
+		go role.NewSweeper(roleRepo, logger, events, cfg.RoleSweeper.Interval).Run(context.Background())
+	}
+
 	// Register handlers.
 	organization.RegisterHandlers(e, orgService)
 	user.RegisterHandlers(e, userService)
@@ -145,6 +176,7 @@ func registerServiceHandlers(e *echo.Group, mongodb *db.MongoDB, cfg *config.Con
 	role.RegisterHandlers(e, roleService)
 	group.RegisterHandlers(e, groupService)
 	policy.RegisterHandlers(e, policyService)
+	relation.RegisterHandlers(e, relationService)
 
 }
 
@@ -170,7 +202,12 @@ func initializeRootOrganization(orgService organization.Service, userService use
 
 		initializeSystemResources(orgService, resourceService, cfg, logger)
 		initializeAdmin(orgService, userService, roleService, cfg, logger)
+		return
 	}
+
+	// System resources added since the root organization was created have to reach it and its admin role as well.
+	initializeSystemResources(orgService, resourceService, cfg, logger)
+	grantSystemResources(orgService, resourceService, roleService, cfg, logger)
 }
 
 func initializeAdmin(orgService organization.Service, userService user.Service, roleService role.Service, cfg *config.Config, logger *zap.Logger) {
@@ -193,23 +230,10 @@ func initializeAdmin(orgService organization.Service, userService user.Service,
 	adminObjID, _ := primitive.ObjectIDFromHex(adminId)
 
 	var permissions []mongo_entity.Permission
-	for _, action := range cfg.SystemResources.Organizations {
-		permissions = append(permissions, mongo_entity.Permission{Resource: "organizations", Action: action})
-	}
-	for _, action := range cfg.SystemResources.Users {
-		permissions = append(permissions, mongo_entity.Permission{Resource: "users", Action: action})
-	}
-	for _, action := range cfg.SystemResources.Groups {
-		permissions = append(permissions, mongo_entity.Permission{Resource: "groups", Action: action})
-	}
-	for _, action := range cfg.SystemResources.Roles {
-		permissions = append(permissions, mongo_entity.Permission{Resource: "roles", Action: action})
-	}
-	for _, action := range cfg.SystemResources.Resources {
-		permissions = append(permissions, mongo_entity.Permission{Resource: "resources", Action: action})
-	}
-	for _, action := range cfg.SystemResources.Polices {
-		permissions = append(permissions, mongo_entity.Permission{Resource: "policies", Action: action})
+	for _, systemResource := range cfg.SystemResourceList() {
+		for _, action := range systemResource.Actions {
+			permissions = append(permissions, mongo_entity.Permission{Resource: systemResource.Identifier, Action: action})
+		}
 	}
 	adminRole := role.CreateRoleRequest{
 		Identifier:  cfg.RootOrganization.AdminRoleName,
@@ -223,106 +247,69 @@ func initializeAdmin(orgService organization.Service, userService user.Service,
 	roleService.Create(nil, rootOrgId, adminRole)
 }
 
-func initializeSystemResources(orgService organization.Service, resourceService resource.Service, cfg *config.Config, logger *zap.Logger) {
+// grantSystemResources gives the admin role the system resource actions it is missing.
+func grantSystemResources(orgService organization.Service, resourceService resource.Service, roleService role.Service, cfg *config.Config, logger *zap.Logger) {
 
 	rootOrgId, err := orgService.GetIdByIdentifier(nil, cfg.RootOrganization.Name)
 	if err != nil {
 		logger.Fatal("Failed to get root org id", zap.Error(err))
 	}
-
-	// Organization resource
-	var orgActions []mongo_entity.Action
-	for _, action := range cfg.SystemResources.Organizations {
-		orgActions = append(orgActions, mongo_entity.Action{Identifier: action, DisplayName: action})
-	}
-	orgResource := resource.CreateResourceRequest{
-		Identifier:  "organizations",
-		DisplayName: "organizations",
-		Actions:     orgActions,
-		Type:        mongo_entity.SystemResource,
-	}
-	resourceService.Create(nil, rootOrgId, orgResource)
-
-	// User resource
-	var userActions []mongo_entity.Action
-	for _, action := range cfg.SystemResources.Users {
-		userActions = append(userActions, mongo_entity.Action{Identifier: action, DisplayName: action})
-	}
-	userResource := resource.CreateResourceRequest{
-		Identifier:  "users",
-		DisplayName: "users",
-		Actions:     userActions,
-		Type:        mongo_entity.SystemResource,
+	adminRole, err := roleService.GetRoleByIdentifier(nil, rootOrgId, cfg.RootOrganization.AdminRoleName)
+	if err != nil {
+		logger.Warn("Admin role not found, skipping system resource grants", zap.Error(err))
+		return
 	}
-	resourceService.Create(nil, rootOrgId, userResource)
-
-	// Group resource
-	var groupActions []mongo_entity.Action
-	for _, action := range cfg.SystemResources.Groups {
-		groupActions = append(groupActions, mongo_entity.Action{Identifier: action, DisplayName: action})
+	granted, err := roleService.GetPermissions(nil, rootOrgId, adminRole.ID.Hex())
+	if err != nil {
+		logger.Fatal("Failed to get admin role permissions", zap.Error(err))
 	}
-	groupResource := resource.CreateResourceRequest{
-		Identifier:  "groups",
-		DisplayName: "groups",
-		Actions:     groupActions,
-		Type:        mongo_entity.SystemResource,
+	actions, err := resourceService.QueryActions(nil, rootOrgId, resource.Filter{})
+	if err != nil {
+		logger.Fatal("Failed to get root org actions", zap.Error(err))
 	}
-	resourceService.Create(nil, rootOrgId, groupResource)
 
-	// Role resource
-	var roleActions []mongo_entity.Action
-	for _, action := range cfg.SystemResources.Roles {
-		roleActions = append(roleActions, mongo_entity.Action{Identifier: action, DisplayName: action})
+	existing := make(map[mongo_entity.PermissionKey]bool)
+	for _, action := range actions {
+		existing[mongo_entity.PermissionKey{Resource: action.Resource, Action: action.Action}] = true
 	}
-	roleResource := resource.CreateResourceRequest{
-		Identifier:  "roles",
-		DisplayName: "roles",
-		Actions:     roleActions,
-		Type:        mongo_entity.SystemResource,
+	for _, permission := range granted {
+		delete(existing, permission.Key())
 	}
-	resourceService.Create(nil, rootOrgId, roleResource)
 
-	// Resource resource
-	var resourceActions []mongo_entity.Action
-	for _, action := range cfg.SystemResources.Resources {
-		resourceActions = append(resourceActions, mongo_entity.Action{Identifier: action, DisplayName: action})
+	var permissions []mongo_entity.Permission
+	for _, systemResource := range cfg.SystemResourceList() {
+		for _, action := range systemResource.Actions {
+			if existing[mongo_entity.PermissionKey{Resource: systemResource.Identifier, Action: action}] {
+				permissions = append(permissions, mongo_entity.Permission{Resource: systemResource.Identifier, Action: action})
+			}
+		}
 	}
-	resourceResource := resource.CreateResourceRequest{
-		Identifier:  "resources",
-		DisplayName: "resources",
-		Actions:     resourceActions,
-		Type:        mongo_entity.SystemResource,
+	if len(permissions) == 0 {
+		return
 	}
-	resourceService.Create(nil, rootOrgId, resourceResource)
-
-	// Policy resource
-	var policyActions []mongo_entity.Action
-	for _, action := range cfg.SystemResources.Polices {
-		policyActions = append(policyActions, mongo_entity.Action{Identifier: action, DisplayName: action})
+	if _, err := roleService.Patch(nil, rootOrgId, adminRole.ID.Hex(), role.PatchRoleRequest{AddedPermissions: permissions}); err != nil {
+		logger.Fatal("Failed to grant system resources to the admin role", zap.Error(err))
 	}
-	policyResource := resource.CreateResourceRequest{
-		Identifier:  "policies",
-		DisplayName: "policies",
-		Actions:     policyActions,
-		Type:        mongo_entity.SystemResource,
-	}
-	resourceService.Create(nil, rootOrgId, policyResource)
+	logger.Info("Granted system resources to the admin role", zap.Int("permissions", len(permissions)))
 }
 
-func getRequiredPermissions(endpoints []config.APIEndpoint) map[mw.MethodPath][]string {
+func initializeSystemResources(orgService organization.Service, resourceService resource.Service, cfg *config.Config, logger *zap.Logger) {
 
-	requiredPermissions := make(map[mw.MethodPath][]string)
+	rootOrgId, err := orgService.GetIdByIdentifier(nil, cfg.RootOrganization.Name)
+	if err != nil {
+		logger.Fatal("Failed to get root org id", zap.Error(err))
+	}
 
-	for _, endpoint := range endpoints {
-		for _, method := range endpoint.Methods {
-			key := mw.MethodPath{
-				Method:   method.Method,
-				Path:     endpoint.Path,
-				Resource: endpoint.Resource,
-			}
-			requiredPermissions[key] = method.RequiredPermissions
+	for _, systemResource := range cfg.SystemResourceList() {
+		var actions []mongo_entity.Action
+		for _, action := range systemResource.Actions {
+			actions = append(actions, mongo_entity.Action{Identifier: action, DisplayName: action})
 		}
+		resourceService.Create(nil, rootOrgId, resource.CreateResourceRequest{
+			Identifier:  systemResource.Identifier,
+			DisplayName: systemResource.Identifier,
+			Actions:     actions,
+			Type:        mongo_entity.SystemResource,
+		})
 	}
-
-	return requiredPermissions
 }
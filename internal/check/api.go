@@ -12,6 +12,10 @@ func RegisterHandlers(r *echo.Group, service Service) {
 	res := permission_service{service: service}
 	router := r.Group("/o/:org/check")
 	router.POST("", res.check)
+	router.POST("/batch", res.batchCheck)
+	r.GET("/o/:org/users/:id/permissions", res.effectivePermissions)
+	r.GET("/o/:org/permissions/holders", res.permissionHolders)
+	router.GET("/shadows", res.shadows)
 }
 
 type permission_service struct {
@@ -41,3 +45,95 @@ func (r permission_service) check(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, allow)
 }
+
+// @Description Batch check.
+// @Tags        Permission
+// @Accept      json
+// @Param org path string true "Organization"
+// @Param request body BatchCheckRequest true "body"
+// @Produce     json
+// @Success     200 {object}  BatchCheckResponse
+// @failure     400,403,500
+// @Router      /{org}/permission/check/batch [post]
+func (r permission_service) batchCheck(c echo.Context) error {
+	var input BatchCheckRequest
+	api_key := c.Request().Header.Get("API_KEY")
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+
+	result, err := r.service.BatchCheck(context.Background(), c.Param("org"), input, api_key, false)
+	if err != nil {
+		return util.HandleError(err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Description Get effective permissions of a user.
+// @Tags        Permission
+// @Param org path string true "Organization"
+// @Param id path string true "User identifier"
+// @Produce     json
+// @Success     200 {object}  EffectivePermissionsResponse
+// @failure     403,404,500
+// @Router      /{org}/users/{id}/permissions [get]
+func (r permission_service) effectivePermissions(c echo.Context) error {
+	api_key := c.Request().Header.Get("API_KEY")
+
+	result, err := r.service.EffectivePermissions(context.Background(), c.Param("org"), c.Param("id"), api_key, false)
+	if err != nil {
+		return util.HandleError(err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Description Get users and groups holding a permission.
+// @Tags        Permission
+// @Param org path string true "Organization"
+// @Param resource query string true "Resource identifier"
+// @Param action query string true "Action identifier"
+// @Produce     json
+// @Success     200 {object}  PermissionHoldersResponse
+// @failure     400,403,404,500
+// @Router      /{org}/permissions/holders [get]
+func (r permission_service) permissionHolders(c echo.Context) error {
+	var input PermissionHoldersRequest
+	api_key := c.Request().Header.Get("API_KEY")
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+
+	result, err := r.service.PermissionHolders(context.Background(), c.Param("org"), input, api_key, false)
+	if err != nil {
+		return util.HandleError(err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Description Get how the shadow versions of policies compared to their active versions in checks.
+// @Tags        Permission
+// @Param org path string true "Organization"
+// @Produce     json
+// @Success     200 {object}  ShadowsResponse
+// @failure     403,500
+// @Router      /{org}/permission/check/shadows [get]
+func (r permission_service) shadows(c echo.Context) error {
+	api_key := c.Request().Header.Get("API_KEY")
+
+	result, err := r.service.Shadows(context.Background(), c.Param("org"), api_key, false)
+	if err != nil {
+		return util.HandleError(err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// RegisterCacheHandlers exposes the statistics of the check cache.
+func RegisterCacheHandlers(r *echo.Group, cache *CachedRepository) {
+	r.GET("/check/cache/stats", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, cache.Stats())
+	})
+}
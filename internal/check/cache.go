@@ -0,0 +1,261 @@
+package check
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/event"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CachedRepository serves check lookups from a per-organization snapshot which is
+// loaded with a single query and kept until it expires or the organization is invalidated.
+type CachedRepository struct {
+	Repository
+	ttl time.Duration
+
+	mu         sync.Mutex
+	snapshots  map[string]*snapshot
+	generation uint64
+
+	hits      uint64
+	misses    uint64
+	refreshes uint64
+}
+
+type CacheStats struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRate       float64 `json:"hit_rate"`
+	Refreshes     uint64  `json:"refreshes"`
+	Organizations int     `json:"organizations"`
+}
+
+// snapshot is the compiled access data of one organization.
+type snapshot struct {
+	orgID     string
+	org       *mongo_entity.Organization
+	details   map[string]CheckDetails
+	expiresAt time.Time
+}
+
+// NewCachedRepository wraps repo with a snapshot cache. A zero ttl keeps snapshots until they are invalidated.
+func NewCachedRepository(repo Repository, ttl time.Duration) *CachedRepository {
+
+	return &CachedRepository{Repository: repo, ttl: ttl, snapshots: make(map[string]*snapshot)}
+}
+
+func newSnapshot(org *mongo_entity.Organization, ttl time.Duration) *snapshot {
+
+	snap := &snapshot{
+		orgID:   org.ID.Hex(),
+		org:     org,
+		details: make(map[string]CheckDetails),
+	}
+	now := time.Now()
+	if ttl > 0 {
+		snap.expiresAt = now.Add(ttl)
+	}
+	// Roles held for a time are resolved again once one of them starts or stops being held.
+	if next := mongo_entity.NextRoleTransition(org.Users, org.Groups, now); !next.IsZero() && (snap.expiresAt.IsZero() || next.Before(snap.expiresAt)) {
+		snap.expiresAt = next
+	}
+	for _, user := range org.Users {
+		snap.details[user.Identifier] = checkDetailsFor(user, org.Groups, now)
+	}
+	return snap
+}
+
+func (s *snapshot) expired() bool {
+
+	return !s.expiresAt.IsZero() && time.Now().After(s.expiresAt)
+}
+
+func (c *CachedRepository) snapshot(ctx context.Context, org_identifier string) (*snapshot, error) {
+
+	c.mu.Lock()
+	snap, exists := c.snapshots[org_identifier]
+	generation := c.generation
+	c.mu.Unlock()
+	if exists && !snap.expired() {
+		atomic.AddUint64(&c.hits, 1)
+		return snap, nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	org, err := c.Repository.GetOrganization(ctx, org_identifier)
+	if err != nil {
+		return nil, err
+	}
+	snap = newSnapshot(org, c.ttl)
+
+	// Don't keep a snapshot which may have been loaded before an invalidation.
+	c.mu.Lock()
+	if generation == c.generation {
+		c.snapshots[org_identifier] = snap
+	}
+	c.mu.Unlock()
+	return snap, nil
+}
+
+// Invalidate drops the snapshot of the organization with the given id.
+func (c *CachedRepository) Invalidate(org_id string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for identifier, snap := range c.snapshots {
+		if snap.orgID == org_id {
+			delete(c.snapshots, identifier)
+		}
+	}
+}
+
+// HandleEvent invalidates the organization an access data change belongs to.
+func (c *CachedRepository) HandleEvent(e event.Event) {
+
+	if e.OrganizationID == "" {
+		c.InvalidateAll()
+		return
+	}
+	c.Invalidate(e.OrganizationID)
+}
+
+// InvalidateAll drops every snapshot.
+func (c *CachedRepository) InvalidateAll() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.snapshots = make(map[string]*snapshot)
+}
+
+func (c *CachedRepository) Stats() CacheStats {
+
+	c.mu.Lock()
+	organizations := len(c.snapshots)
+	c.mu.Unlock()
+
+	stats := CacheStats{
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Refreshes:     atomic.LoadUint64(&c.refreshes),
+		Organizations: organizations,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// EnsureRevision reloads the snapshot of the organization when it is older than the revision,
+// e.g. when a write made through another instance has not been picked up yet.
+func (c *CachedRepository) EnsureRevision(ctx context.Context, org_identifier string, revision int64) error {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return err
+	}
+	if snap.org.Revision >= revision {
+		return nil
+	}
+	atomic.AddUint64(&c.refreshes, 1)
+	c.Invalidate(snap.orgID)
+	snap, err = c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return err
+	}
+	if snap.org.Revision < revision {
+		return &util.InvalidInputError{Path: "revision, the organization has not reached it."}
+	}
+	return nil
+}
+
+// ValidateAPIKey validates the API key against the snapshot of the organization. Regenerating the key
+// invalidates the snapshot, like any other change to the organization.
+func (c *CachedRepository) ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		if _, notFound := err.(*util.NotFoundError); notFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return apiKey != "" && snap.org.API_KEY == apiKey, nil
+}
+
+func (c *CachedRepository) GetOrganization(ctx context.Context, org_identifier string) (*mongo_entity.Organization, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return nil, err
+	}
+	return snap.org, nil
+}
+
+func (c *CachedRepository) GetCheckDetails(ctx context.Context, org_identifier string, identifier string) (CheckDetails, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return CheckDetails{}, err
+	}
+	details, exists := snap.details[identifier]
+	if !exists {
+		return CheckDetails{}, &util.NotFoundError{Path: "User"}
+	}
+	return details, nil
+}
+
+func (c *CachedRepository) GetRoles(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) ([]mongo_entity.Role, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return nil, err
+	}
+	return withInheritedPermissions(snap.org.Roles, role_ids), nil
+}
+
+func (c *CachedRepository) GetRolePermissions(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) (*[]mongo_entity.Permission, error) {
+
+	roles, err := c.GetRoles(ctx, org_identifier, role_ids)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []mongo_entity.Permission
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
+	}
+	return &permissions, nil
+}
+
+func (c *CachedRepository) GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) ([]ActivePolicyContent, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return nil, err
+	}
+	return activePolicyContents(snap.org.ID, snap.org.Polices, policy_ids), nil
+}
+
+func (c *CachedRepository) GetResourcePolicies(ctx context.Context, org_identifier string, resource string) ([]primitive.ObjectID, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return nil, err
+	}
+	return resourcePolicies(snap.org.Resources, resource), nil
+}
+
+func (c *CachedRepository) GetCombiningAlgorithm(ctx context.Context, org_identifier string) (mongo_entity.CombiningAlgorithm, error) {
+
+	snap, err := c.snapshot(ctx, org_identifier)
+	if err != nil {
+		return "", err
+	}
+	return snap.org.Algorithm(), nil
+}
@@ -0,0 +1,64 @@
+package check
+
+import "github.com/shashimalcse/cronuseo/internal/mongo_entity"
+
+// decision is the outcome of the roles of a subject for a check.
+type decision int
+
+const (
+	// notApplicable means no permission of the subject matches the check.
+	notApplicable decision = iota
+	permit
+	deny
+)
+
+// combine decides a check from its RBAC decision and the policies of the subject, under the combining algorithm.
+// Policies are only evaluated when the RBAC decision does not settle the check on its own, and the traces of
+// the evaluated policies are returned along with the result.
+// The policies of the subject have no resource or action of their own, so they only grant a targeted check:
+// one which a permission or a policy scoped to its resource applies to. A deny of the roles always wins.
+func combine(algorithm mongo_entity.CombiningAlgorithm, rbac decision, targeted bool, evaluate func() ([]PolicyTrace, error)) (bool, []PolicyTrace, error) {
+
+	traces := []PolicyTrace{}
+	if rbac == deny || (rbac == notApplicable && !targeted) {
+		return false, traces, nil
+	}
+	switch algorithm {
+	case mongo_entity.PermitOverrides, mongo_entity.RBACOrABAC, mongo_entity.FirstApplicable:
+		if rbac == permit {
+			return true, traces, nil
+		}
+	case mongo_entity.DenyOverrides:
+		// Every policy is evaluated, as any of them denies.
+	default:
+		if rbac != permit {
+			return false, traces, nil
+		}
+	}
+
+	traces, err := evaluate()
+	if err != nil {
+		return false, nil, err
+	}
+	passed, failed := 0, 0
+	for _, trace := range traces {
+		if trace.Result {
+			passed++
+		} else {
+			failed++
+		}
+	}
+
+	switch algorithm {
+	case mongo_entity.DenyOverrides:
+		return failed == 0 && (rbac == permit || passed > 0), traces, nil
+	case mongo_entity.PermitOverrides:
+		return passed > 0, traces, nil
+	case mongo_entity.FirstApplicable:
+		return len(traces) > 0 && traces[0].Result, traces, nil
+	case mongo_entity.RBACOrABAC:
+		return len(traces) > 0 && failed == 0, traces, nil
+	default:
+		return failed == 0, traces, nil
+	}
+}
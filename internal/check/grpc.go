@@ -23,16 +23,19 @@ type grpcService struct {
 func (s grpcService) Check(ctx context.Context, req *proto.GrpcCheckRequest) (*proto.GrpcCheckResponse, error) {
 
 	s.logger.Info("GRPC method : Check", zap.String("method", "Check"))
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, errors.New("missing metadata from request")
+	apiKey, err := apiKeyFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	apiKey := md.Get("API_KEY")[0]
 
 	input := CheckRequest{
 		Identifier: req.Username,
 		Action:     req.Action,
 		Resource:   req.Resource,
+		ObjectID:   req.ObjectId,
+		Explain:    req.Explain,
+		Revision:   req.Revision,
+		Context:    fromGrpcContext(req.Context),
 	}
 
 	allow, err := s.service.Check(context.Background(), req.Organization, input, apiKey, false)
@@ -40,5 +43,138 @@ func (s grpcService) Check(ctx context.Context, req *proto.GrpcCheckRequest) (*p
 		return nil, util.HandleError(err)
 	}
 
-	return &proto.GrpcCheckResponse{Allow: allow.Allowed}, nil
+	return &proto.GrpcCheckResponse{Allow: allow.Allowed, Trace: toGrpcTrace(allow.Trace)}, nil
+}
+
+func (s grpcService) BatchCheck(ctx context.Context, req *proto.GrpcBatchCheckRequest) (*proto.GrpcBatchCheckResponse, error) {
+
+	s.logger.Info("GRPC method : BatchCheck", zap.String("method", "BatchCheck"))
+	apiKey, err := apiKeyFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input := BatchCheckRequest{Checks: make([]CheckRequest, 0, len(req.Checks)), Revision: req.Revision}
+	for _, item := range req.Checks {
+		input.Checks = append(input.Checks, CheckRequest{
+			Identifier: item.Username,
+			Action:     item.Action,
+			Resource:   item.Resource,
+			ObjectID:   item.ObjectId,
+			Explain:    item.Explain,
+			Context:    fromGrpcContext(item.Context),
+		})
+	}
+
+	result, err := s.service.BatchCheck(context.Background(), req.Organization, input, apiKey, false)
+	if err != nil {
+		return nil, util.HandleError(err)
+	}
+
+	results := make([]*proto.GrpcCheckResponse, 0, len(result.Results))
+	for _, r := range result.Results {
+		results = append(results, &proto.GrpcCheckResponse{Allow: r.Allowed, Trace: toGrpcTrace(r.Trace)})
+	}
+	return &proto.GrpcBatchCheckResponse{Results: results}, nil
+}
+
+func (s grpcService) EffectivePermissions(ctx context.Context, req *proto.GrpcEffectivePermissionsRequest) (*proto.GrpcEffectivePermissionsResponse, error) {
+
+	s.logger.Info("GRPC method : EffectivePermissions", zap.String("method", "EffectivePermissions"))
+	apiKey, err := apiKeyFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.EffectivePermissions(context.Background(), req.Organization, req.Username, apiKey, false)
+	if err != nil {
+		return nil, util.HandleError(err)
+	}
+
+	response := &proto.GrpcEffectivePermissionsResponse{
+		Permissions: toGrpcEffectivePermissions(result.Permissions),
+		Denied:      toGrpcEffectivePermissions(result.Denied),
+	}
+	response.Policies = toGrpcPolicyTraces(result.Policies)
+	return response, nil
+}
+
+func apiKeyFromContext(ctx context.Context) (string, error) {
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errors.New("missing metadata from request")
+	}
+	apiKeys := md.Get("API_KEY")
+	if len(apiKeys) == 0 {
+		return "", errors.New("missing API_KEY from request metadata")
+	}
+	return apiKeys[0], nil
+}
+
+// fromGrpcContext reads the request context of a gRPC check, whose attributes are strings.
+func fromGrpcContext(attributes map[string]string) map[string]interface{} {
+
+	if len(attributes) == 0 {
+		return nil
+	}
+	context := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		context[name] = value
+	}
+	return context
+}
+
+func toGrpcTrace(trace *CheckTrace) *proto.GrpcCheckTrace {
+
+	if trace == nil {
+		return nil
+	}
+	grpcTrace := &proto.GrpcCheckTrace{Reason: trace.Reason, Algorithm: trace.Algorithm}
+	for _, role := range trace.Roles {
+		grpcTrace.Roles = append(grpcTrace.Roles, &proto.GrpcRoleTrace{
+			Id:         role.ID,
+			Identifier: role.Identifier,
+			Source:     role.Source,
+			Group:      role.Group,
+			Granted:    role.Granted,
+			Denied:     role.Denied,
+		})
+	}
+	grpcTrace.Policies = toGrpcPolicyTraces(trace.Policies)
+	return grpcTrace
+}
+
+func toGrpcEffectivePermissions(permissions []EffectivePermission) []*proto.GrpcEffectivePermission {
+
+	var grpcPermissions []*proto.GrpcEffectivePermission
+	for _, permission := range permissions {
+		grpcPermission := &proto.GrpcEffectivePermission{Resource: permission.Resource, Action: permission.Action}
+		for _, grant := range permission.Grants {
+			grpcPermission.Grants = append(grpcPermission.Grants, &proto.GrpcPermissionGrant{
+				RoleId:         grant.RoleID,
+				RoleIdentifier: grant.RoleIdentifier,
+				Source:         grant.Source,
+				Group:          grant.Group,
+			})
+		}
+		grpcPermissions = append(grpcPermissions, grpcPermission)
+	}
+	return grpcPermissions
+}
+
+func toGrpcPolicyTraces(policies []PolicyTrace) []*proto.GrpcPolicyTrace {
+
+	var grpcPolicies []*proto.GrpcPolicyTrace
+	for _, policy := range policies {
+		grpcPolicies = append(grpcPolicies, &proto.GrpcPolicyTrace{
+			Id:         policy.ID,
+			Identifier: policy.Identifier,
+			Version:    policy.Version,
+			Result:     policy.Result,
+			Language:   policy.Language,
+			Scope:      policy.Scope,
+		})
+	}
+	return grpcPolicies
 }
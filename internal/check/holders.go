@@ -0,0 +1,218 @@
+package check
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PermissionHoldersRequest struct {
+	Resource string `json:"resource" query:"resource"`
+	Action   string `json:"action" query:"action"`
+}
+
+func (m PermissionHoldersRequest) Validate() error {
+
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Resource, validation.Required),
+		validation.Field(&m.Action, validation.Required),
+	)
+}
+
+type PermissionHoldersResponse struct {
+	Resource string                      `json:"resource"`
+	Action   string                      `json:"action"`
+	Roles    []mongo_entity.AssignedRole `json:"roles"`
+	// Policies of the resource condition every holder, as they must pass for every check on the resource.
+	Policies []string           `json:"policies,omitempty"`
+	Groups   []PermissionHolder `json:"groups"`
+	Users    []PermissionHolder `json:"users"`
+}
+
+// PermissionHolder is a user or group holding a permission, with every path through which it is held.
+// Policies are not evaluated, as they depend on the attributes of each check, so a holder whose permission
+// depends on policies is conditional: a grant with policies only holds when they pass, a deny with policies
+// takes the permission away when they pass, and the policies of the subject condition or grant the permission
+// under the combining algorithm of the organization.
+type PermissionHolder struct {
+	ID          string            `json:"id"`
+	Identifier  string            `json:"identifier"`
+	Conditional bool              `json:"conditional"`
+	Grants      []PermissionGrant `json:"grants"`
+	Denies      []PermissionGrant `json:"denies,omitempty"`
+	// Policies of the subject, of its own and of its groups.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// GrantSourcePolicy is the source of a grant which comes from the policies of the subject alone.
+const GrantSourcePolicy = "policy"
+
+// Get all users and groups which hold the given permission through their roles or their policies.
+func (s service) PermissionHolders(ctx context.Context, org_identifier string, req PermissionHoldersRequest, apiKey string, skipValidation bool) (PermissionHoldersResponse, error) {
+
+	if err := req.Validate(); err != nil {
+		return PermissionHoldersResponse{}, &util.InvalidInputError{Path: "Invalid input for permission holders."}
+	}
+	if !skipValidation {
+		validated, _ := s.ValidateAPIKey(ctx, org_identifier, apiKey)
+		if !validated {
+			s.logger.Error("Error while validating api key for permission holders")
+			return PermissionHoldersResponse{}, &util.UnauthorizedError{}
+		}
+	}
+
+	org, err := s.repo.GetOrganization(ctx, org_identifier)
+	if err != nil {
+		return PermissionHoldersResponse{}, err
+	}
+	algorithm, err := s.repo.GetCombiningAlgorithm(ctx, org_identifier)
+	if err != nil {
+		return PermissionHoldersResponse{}, err
+	}
+	resourcePolicies, err := s.repo.GetResourcePolicies(ctx, org_identifier, req.Resource)
+	if err != nil {
+		return PermissionHoldersResponse{}, err
+	}
+
+	response := PermissionHoldersResponse{
+		Resource: req.Resource,
+		Action:   req.Action,
+		Roles:    []mongo_entity.AssignedRole{},
+		Policies: hexes(resourcePolicies),
+		Groups:   []PermissionHolder{},
+		Users:    []PermissionHolder{},
+	}
+
+	// Roles which include the permission, directly or through their parent roles.
+	var roleIDs []primitive.ObjectID
+	for _, role := range org.Roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	h := holders{req: req, algorithm: algorithm, resourceScoped: len(resourcePolicies) > 0, roles: make(map[primitive.ObjectID]mongo_entity.Role)}
+	for _, role := range withInheritedPermissions(org.Roles, roleIDs) {
+		h.roles[role.ID] = role
+		for _, permission := range role.Permissions {
+			if permission.Matches(req.Resource, req.Action) && !permission.Denies() {
+				response.Roles = append(response.Roles, mongo_entity.AssignedRole{
+					ID:          role.ID,
+					Identifier:  role.Identifier,
+					DisplayName: role.DisplayName,
+				})
+				break
+			}
+		}
+	}
+
+	// Groups holding the permission themselves or through the groups they are members of.
+	now := time.Now()
+	for _, group := range org.Groups {
+		membership := append([]mongo_entity.Group{group}, mongo_entity.AncestorGroups(org.Groups, group.ID)...)
+		var roleGrants []RoleGrant
+		var policies []primitive.ObjectID
+		for _, member := range membership {
+			for _, roleID := range mongo_entity.HeldRoles(member.Roles, member.TimedRoles, now) {
+				roleGrants = append(roleGrants, RoleGrant{RoleID: roleID, GroupID: member.ID, GroupIdentifier: member.Identifier})
+			}
+			for _, policyID := range member.Policies {
+				if !contains(policies, policyID) {
+					policies = append(policies, policyID)
+				}
+			}
+		}
+		if holder, holds := h.holder(group.ID, group.Identifier, roleGrants, policies); holds {
+			response.Groups = append(response.Groups, holder)
+		}
+	}
+
+	// Users holding the permission directly or through their groups.
+	for _, user := range org.Users {
+		details := checkDetailsFor(user, org.Groups, now)
+		if holder, holds := h.holder(user.ID, user.Identifier, details.RoleGrants, details.Policies); holds {
+			response.Users = append(response.Users, holder)
+		}
+	}
+	return response, nil
+}
+
+// holders decides who holds the permission of a holders request, without evaluating any policy.
+type holders struct {
+	req       PermissionHoldersRequest
+	algorithm mongo_entity.CombiningAlgorithm
+	// roles have the permissions they inherit from their parent roles, conditioned by the policies of the roles.
+	roles          map[primitive.ObjectID]mongo_entity.Role
+	resourceScoped bool
+}
+
+// holder reports how a subject with the roles and policies holds the permission, and whether it may hold it at all.
+// A deny without policies takes the permission away whatever else the subject holds.
+func (h holders) holder(id primitive.ObjectID, identifier string, roleGrants []RoleGrant, policies []primitive.ObjectID) (PermissionHolder, bool) {
+
+	holder := PermissionHolder{ID: id.Hex(), Identifier: identifier, Grants: []PermissionGrant{}}
+	targeted := h.resourceScoped
+	conditional := true
+	for _, roleGrant := range roleGrants {
+		role, exists := h.roles[roleGrant.RoleID]
+		if !exists {
+			continue
+		}
+		grant := PermissionGrant{RoleID: role.ID.Hex(), RoleIdentifier: role.Identifier, Source: RoleSourceDirect}
+		if !roleGrant.GroupID.IsZero() {
+			grant.Source, grant.Group = RoleSourceGroup, roleGrant.GroupIdentifier
+		}
+		var allows []PermissionGrant
+		for _, permission := range role.Permissions {
+			if !permission.Matches(h.req.Resource, h.req.Action) {
+				continue
+			}
+			targeted = true
+			conditioned := grant
+			conditioned.Policies = hexes(permission.Policies)
+			switch {
+			case permission.Denies() && len(conditioned.Policies) == 0:
+				return PermissionHolder{}, false
+			case permission.Denies():
+				holder.Denies = append(holder.Denies, conditioned)
+			case len(conditioned.Policies) == 0:
+				// An unconditional permission of the role makes its conditional ones redundant.
+				allows = []PermissionGrant{conditioned}
+				conditional = false
+			case len(allows) == 0 || len(allows[0].Policies) > 0:
+				allows = append(allows, conditioned)
+			}
+		}
+		holder.Grants = append(holder.Grants, allows...)
+	}
+
+	holder.Policies = hexes(policies)
+	sort.Strings(holder.Policies)
+	if len(holder.Policies) > 0 {
+		// The policies of the subject alone grant a targeted check under every algorithm but rbac-and-abac,
+		// and condition the grants of the roles under rbac-and-abac and deny-overrides.
+		if targeted && h.algorithm != mongo_entity.RBACAndABAC {
+			holder.Grants = append(holder.Grants, PermissionGrant{Source: GrantSourcePolicy, Policies: holder.Policies})
+		}
+		if h.algorithm == mongo_entity.RBACAndABAC || h.algorithm == mongo_entity.DenyOverrides {
+			conditional = true
+		}
+	}
+	if len(holder.Grants) == 0 {
+		return PermissionHolder{}, false
+	}
+	holder.Conditional = conditional || h.resourceScoped || len(holder.Denies) > 0
+	return holder, true
+}
+
+// hexes returns the hex form of the ids, nil when there are none.
+func hexes(ids []primitive.ObjectID) []string {
+
+	var hexIDs []string
+	for _, id := range ids {
+		hexIDs = append(hexIDs, id.Hex())
+	}
+	return hexIDs
+}
@@ -0,0 +1,157 @@
+package check
+
+import (
+	"context"
+
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EffectivePermissionsResponse struct {
+	Identifier  string                `json:"identifier"`
+	Permissions []EffectivePermission `json:"permissions"`
+	Denied      []EffectivePermission `json:"denied"`
+	Policies    []PolicyTrace         `json:"policies"`
+}
+
+type EffectivePermission struct {
+	Resource string            `json:"resource"`
+	Action   string            `json:"action"`
+	Grants   []PermissionGrant `json:"grants"`
+}
+
+// PermissionGrant describes one path through which a subject holds a permission.
+type PermissionGrant struct {
+	RoleID         string `json:"role_id"`
+	RoleIdentifier string `json:"role_identifier"`
+	Source         string `json:"source"`
+	Group          string `json:"group,omitempty"`
+	// Policies condition the grant, which only holds for checks where all of them pass.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// Get all permissions a subject is allowed to perform, along with the roles and groups they came from.
+func (s service) EffectivePermissions(ctx context.Context, org_identifier string, identifier string, apiKey string, skipValidation bool) (EffectivePermissionsResponse, error) {
+
+	if !skipValidation {
+		validated, _ := s.ValidateAPIKey(ctx, org_identifier, apiKey)
+		if !validated {
+			s.logger.Error("Error while validating api key for effective permissions")
+			return EffectivePermissionsResponse{}, &util.UnauthorizedError{}
+		}
+	}
+
+	checkDetails, err := s.repo.GetCheckDetails(ctx, org_identifier, identifier)
+	if err != nil {
+		return EffectivePermissionsResponse{}, err
+	}
+
+	response := EffectivePermissionsResponse{
+		Identifier:  identifier,
+		Permissions: []EffectivePermission{},
+		Denied:      []EffectivePermission{},
+		Policies:    []PolicyTrace{},
+	}
+	if !skipValidation {
+		response.Policies, _, err = s.evaluatePolicies(ctx, org_identifier, CheckRequest{Identifier: identifier}, checkDetails)
+		if err != nil {
+			return EffectivePermissionsResponse{}, err
+		}
+	}
+	// Under some combining algorithms failing policies deny every decision for the subject, so nothing is effective.
+	algorithm, err := s.repo.GetCombiningAlgorithm(ctx, org_identifier)
+	if err != nil {
+		return EffectivePermissionsResponse{}, err
+	}
+	granted, _, _ := combine(algorithm, permit, true, func() ([]PolicyTrace, error) { return response.Policies, nil })
+	if !granted || len(checkDetails.Roles) == 0 {
+		return response, nil
+	}
+
+	roles, err := s.repo.GetRoles(ctx, org_identifier, checkDetails.Roles)
+	if err != nil {
+		return EffectivePermissionsResponse{}, err
+	}
+	rolesByID := make(map[primitive.ObjectID]mongo_entity.Role)
+	for _, role := range roles {
+		rolesByID[role.ID] = role
+	}
+
+	allowed := newPermissionIndex()
+	denied := newPermissionIndex()
+	for _, grant := range checkDetails.RoleGrants {
+		role, exists := rolesByID[grant.RoleID]
+		if !exists {
+			continue
+		}
+		permissionGrant := PermissionGrant{
+			RoleID:         role.ID.Hex(),
+			RoleIdentifier: role.Identifier,
+			Source:         RoleSourceDirect,
+		}
+		if !grant.GroupID.IsZero() {
+			permissionGrant.Source = RoleSourceGroup
+			permissionGrant.Group = grant.GroupIdentifier
+		}
+		for _, permission := range role.Permissions {
+			if permission.Denies() {
+				denied.add(permission, permissionGrant)
+			} else {
+				allowed.add(permission, permissionGrant)
+			}
+		}
+	}
+
+	// Deny overrides allow, so permissions covered by an unconditional deny are never effective.
+	for _, permission := range allowed.permissions {
+		if !denied.covers(permission.Resource, permission.Action) {
+			response.Permissions = append(response.Permissions, permission)
+		}
+	}
+	response.Denied = append(response.Denied, denied.permissions...)
+	return response, nil
+}
+
+// permissionIndex groups grants by resource and action, keeping the order permissions were first seen.
+type permissionIndex struct {
+	index       map[mongo_entity.PermissionKey]int
+	permissions []EffectivePermission
+}
+
+func newPermissionIndex() *permissionIndex {
+
+	return &permissionIndex{index: make(map[mongo_entity.PermissionKey]int)}
+}
+
+func (p *permissionIndex) add(permission mongo_entity.Permission, grant PermissionGrant) {
+
+	key := permission.Key()
+	i, seen := p.index[key]
+	if !seen {
+		i = len(p.permissions)
+		p.index[key] = i
+		p.permissions = append(p.permissions, EffectivePermission{
+			Resource: permission.Resource,
+			Action:   permission.Action,
+		})
+	}
+	grant.Policies = append(grant.Policies, hexes(permission.Policies)...)
+	p.permissions[i].Grants = append(p.permissions[i].Grants, grant)
+}
+
+// covers reports whether a grant without policies matches the resource and action.
+func (p *permissionIndex) covers(resource string, action string) bool {
+
+	for _, permission := range p.permissions {
+		if !mongo_entity.MatchResource(permission.Resource, resource) || !mongo_entity.MatchAction(permission.Action, action) {
+			continue
+		}
+		for _, grant := range permission.Grants {
+			if len(grant.Policies) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package check
+
+import (
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+)
+
+// relationGraph resolves checks against the relation tuples of an organization, following the
+// relation definitions of its resources.
+type relationGraph struct {
+	namespaces map[string][]mongo_entity.RelationDefinition
+	tuples     map[string][]mongo_entity.RelationTuple
+	// groups holds the identifiers of the groups each user is a member of, including nested groups.
+	groups map[string]map[string]bool
+}
+
+func newRelationGraph(org *mongo_entity.Organization) *relationGraph {
+
+	graph := &relationGraph{
+		namespaces: make(map[string][]mongo_entity.RelationDefinition),
+		tuples:     make(map[string][]mongo_entity.RelationTuple),
+		groups:     make(map[string]map[string]bool),
+	}
+	for _, resource := range org.Resources {
+		graph.namespaces[resource.Identifier] = resource.Relations
+	}
+	for _, tuple := range org.Relations {
+		key := relationKey(tuple.ObjectType, tuple.ObjectID, tuple.Relation)
+		graph.tuples[key] = append(graph.tuples[key], tuple)
+	}
+
+	identifiers := make(map[string]string)
+	for _, group := range org.Groups {
+		identifiers[group.ID.Hex()] = group.Identifier
+	}
+	for _, user := range org.Users {
+		memberships := make(map[string]bool)
+		for _, groupID := range user.Groups {
+			memberships[identifiers[groupID.Hex()]] = true
+			for _, ancestor := range mongo_entity.AncestorGroups(org.Groups, groupID) {
+				memberships[ancestor.Identifier] = true
+			}
+		}
+		graph.groups[user.Identifier] = memberships
+	}
+	return graph
+}
+
+func relationKey(objectType string, objectID string, relation string) string {
+
+	return objectType + ":" + objectID + "#" + relation
+}
+
+// relate combines the decision of the roles of a subject with the relation tuples, for a check on an object.
+// Holding the relation permits the check like a permission of the subject, but never lifts a deny of the roles.
+func relate(rbac decision, related bool) decision {
+
+	if rbac == deny || !related {
+		return rbac
+	}
+	return permit
+}
+
+// check reports whether the user has the relation to the object.
+func (g *relationGraph) check(user string, objectType string, objectID string, relation string) bool {
+
+	return g.resolve(user, objectType, objectID, relation, 0, make(map[string]bool))
+}
+
+func (g *relationGraph) resolve(user string, objectType string, objectID string, relation string, depth int, visiting map[string]bool) bool {
+
+	key := relationKey(objectType, objectID, relation)
+	if depth > mongo_entity.MaxRelationDepth || visiting[key] {
+		return false
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	// Members of the organization's groups are members of the built-in group namespace.
+	if objectType == mongo_entity.GroupObjectType && relation == mongo_entity.MemberRelation && g.groups[user][objectID] {
+		return true
+	}
+
+	// A relation without a rewrite only holds its direct tuples.
+	rewrite := []mongo_entity.Userset{{This: true}}
+	if definition, exists := mongo_entity.FindRelation(g.namespaces[objectType], relation); exists && len(definition.Rewrite) > 0 {
+		rewrite = definition.Rewrite
+	}
+	for _, userset := range rewrite {
+		switch {
+		case userset.This:
+			for _, tuple := range g.tuples[key] {
+				if tuple.SubjectRelation == "" {
+					if tuple.SubjectType == mongo_entity.UserSubjectType && tuple.SubjectID == user {
+						return true
+					}
+					continue
+				}
+				if g.resolve(user, tuple.SubjectType, tuple.SubjectID, tuple.SubjectRelation, depth+1, visiting) {
+					return true
+				}
+			}
+		case userset.ComputedUserset != "":
+			if g.resolve(user, objectType, objectID, userset.ComputedUserset, depth+1, visiting) {
+				return true
+			}
+		case userset.TupleToUserset != nil:
+			for _, tuple := range g.tuples[relationKey(objectType, objectID, userset.TupleToUserset.Tupleset)] {
+				if g.resolve(user, tuple.SubjectType, tuple.SubjectID, userset.TupleToUserset.ComputedUserset, depth+1, visiting) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
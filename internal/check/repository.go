@@ -2,6 +2,7 @@ package check
 
 import (
 	"context"
+	"time"
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
@@ -14,9 +15,14 @@ import (
 
 type Repository interface {
 	ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error)
+	GetRoles(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) ([]mongo_entity.Role, error)
 	GetRolePermissions(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) (*[]mongo_entity.Permission, error)
 	GetCheckDetails(ctx context.Context, org_identifier string, identifier string) (CheckDetails, error)
-	GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) (map[string]string, error)
+	GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) ([]ActivePolicyContent, error)
+	GetOrganization(ctx context.Context, org_identifier string) (*mongo_entity.Organization, error)
+	GetResourcePolicies(ctx context.Context, org_identifier string, resource string) ([]primitive.ObjectID, error)
+	GetCombiningAlgorithm(ctx context.Context, org_identifier string) (mongo_entity.CombiningAlgorithm, error)
+	EnsureRevision(ctx context.Context, org_identifier string, revision int64) error
 }
 
 type repository struct {
@@ -47,44 +53,49 @@ func (r repository) ValidateAPIKey(ctx context.Context, org_identifier string, a
 	return false, nil
 }
 
-func (r repository) GetRolePermissions(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) (*[]mongo_entity.Permission, error) {
+// EnsureRevision makes sure checks see the organization at the revision or later. Every lookup of
+// this repository reads the latest data, so it only rejects revisions the organization has not reached.
+func (r repository) EnsureRevision(ctx context.Context, org_identifier string, revision int64) error {
 
-	// Define the aggregation pipeline
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"identifier": org_identifier}}},
-		{{Key: "$project", Value: bson.M{
-			"roles": bson.M{
-				"$filter": bson.M{
-					"input": "$roles",
-					"as":    "role",
-					"cond":  bson.M{"$in": []interface{}{"$$role._id", role_ids}},
-				},
-			},
-		}}},
+	filter := bson.M{"identifier": org_identifier, "revision": bson.M{"$gte": revision}}
+	count, err := r.mongoColl.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
 	}
+	if count == 0 {
+		return &util.InvalidInputError{Path: "revision, the organization has not reached it."}
+	}
+	return nil
+}
 
-	// Aggregate the query results
-	cursor, err := r.mongoColl.Aggregate(ctx, pipeline)
+// Get the given roles, each carrying the permissions it inherits from its ancestor roles.
+func (r repository) GetRoles(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) ([]mongo_entity.Role, error) {
+
+	filter := bson.M{"identifier": org_identifier}
+	projection := bson.M{"roles._id": 1, "roles.identifier": 1, "roles.display_name": 1, "roles.permissions": 1, "roles.parent_roles": 1, "roles.policies": 1}
+
+	// The whole hierarchy is needed to resolve inherited permissions.
+	var org mongo_entity.Organization
+	err := r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &util.NotFoundError{Path: "Organization not found"}
+		}
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	return withInheritedPermissions(org.Roles, role_ids), nil
+}
 
-	// Assuming only one organization will match, decode the first result
-	var org struct {
-		Roles []mongo_entity.Role `bson:"roles"`
-	}
-	if cursor.Next(ctx) {
-		if err := cursor.Decode(&org); err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, &util.NotFoundError{Path: "Organization not found"}
+func (r repository) GetRolePermissions(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) (*[]mongo_entity.Permission, error) {
+
+	roles, err := r.GetRoles(ctx, org_identifier, role_ids)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize a slice to store permissions
 	var permissions []mongo_entity.Permission
-	for _, role := range org.Roles {
+	for _, role := range roles {
 		permissions = append(permissions, role.Permissions...)
 	}
 
@@ -110,23 +121,132 @@ func (r repository) GetCheckDetails(ctx context.Context, org_identifier string,
 	if len(org.Users) == 0 {
 		return CheckDetails{}, nil
 	}
+	return checkDetailsFor(org.Users[0], org.Groups, time.Now()), nil
+}
+
+func (r repository) GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) ([]ActivePolicyContent, error) {
+
+	// Filter to find documents with the specified policy IDs
+	filter := bson.M{
+		"identifier": org_identifier,
+		"policies": bson.M{
+			"$elemMatch": bson.M{
+				"_id": bson.M{"$in": policy_ids},
+			},
+		},
+	}
+
+	// Projection to extract only the relevant policies
+	projection := bson.M{"policies": 1}
+
+	cursor, err := r.mongoColl.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var activePolicies []ActivePolicyContent
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       primitive.ObjectID    `bson:"_id"`
+			Policies []mongo_entity.Policy `bson:"policies"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		activePolicies = append(activePolicies, activePolicyContents(doc.ID, doc.Policies, policy_ids)...)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return activePolicies, nil
+}
+
+// Get users, groups, roles and policies of the organization, which together decide every check, along with
+// the API key checks are made with.
+func (r repository) GetOrganization(ctx context.Context, org_identifier string) (*mongo_entity.Organization, error) {
+
+	filter := bson.M{"identifier": org_identifier}
+	projection := bson.M{"identifier": 1, "api_key": 1, "revision": 1, "combining_algorithm": 1, "users": 1, "groups": 1, "roles": 1, "policies": 1, "resources": 1, "relations": 1}
+
+	var org mongo_entity.Organization
+	err := r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &util.NotFoundError{Path: "Organization"}
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Get the policies of the resources matching the given resource identifier.
+func (r repository) GetResourcePolicies(ctx context.Context, org_identifier string, resource string) ([]primitive.ObjectID, error) {
+
+	filter := bson.M{"identifier": org_identifier}
+	projection := bson.M{"resources.identifier": 1, "resources.policies": 1}
+
+	var org mongo_entity.Organization
+	err := r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &util.NotFoundError{Path: "Organization"}
+		}
+		return nil, err
+	}
+	return resourcePolicies(org.Resources, resource), nil
+}
+
+// Get the combining algorithm the checks of the organization use.
+func (r repository) GetCombiningAlgorithm(ctx context.Context, org_identifier string) (mongo_entity.CombiningAlgorithm, error) {
+
+	filter := bson.M{"identifier": org_identifier}
+	projection := bson.M{"combining_algorithm": 1}
+
+	var org mongo_entity.Organization
+	err := r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", &util.NotFoundError{Path: "Organization"}
+		}
+		return "", err
+	}
+	return org.Algorithm(), nil
+}
+
+// checkDetailsFor collects the roles and policies a user holds directly or through the given groups, leaving out
+// the roles whose assignment is not held at the time.
+// Membership is transitive: a user in a group is also in every group that group is a member of.
+func checkDetailsFor(user mongo_entity.User, groups []mongo_entity.Group, now time.Time) CheckDetails {
 
 	// Create a map to store the unique role IDs
 	roleIDMap := make(map[primitive.ObjectID]struct{})
 	policyIDMap := make(map[primitive.ObjectID]struct{})
 	groupIDs := make(map[primitive.ObjectID]struct{})
 
-	for _, groupID := range org.Users[0].Groups {
+	for _, groupID := range user.Groups {
 		groupIDs[groupID] = struct{}{}
+		for _, ancestor := range mongo_entity.AncestorGroups(groups, groupID) {
+			groupIDs[ancestor.ID] = struct{}{}
+		}
 	}
-	for _, policyID := range org.Users[0].Policies {
+	for _, policyID := range user.Policies {
 		policyIDMap[policyID] = struct{}{}
 	}
 
-	for _, group := range org.Groups {
+	userRoles := mongo_entity.HeldRoles(user.Roles, user.TimedRoles, now)
+	var roleGrants []RoleGrant
+	for _, roleID := range userRoles {
+		roleGrants = append(roleGrants, RoleGrant{RoleID: roleID})
+	}
+
+	for _, group := range groups {
 		if _, exists := groupIDs[group.ID]; exists {
-			for _, roleID := range group.Roles {
+			for _, roleID := range mongo_entity.HeldRoles(group.Roles, group.TimedRoles, now) {
 				roleIDMap[roleID] = struct{}{}
+				roleGrants = append(roleGrants, RoleGrant{RoleID: roleID, GroupID: group.ID, GroupIdentifier: group.Identifier})
 			}
 			for _, policyID := range group.Policies {
 				policyIDMap[policyID] = struct{}{}
@@ -135,7 +255,7 @@ func (r repository) GetCheckDetails(ctx context.Context, org_identifier string,
 	}
 
 	var roleIDs []primitive.ObjectID
-	roleIDs = append(roleIDs, org.Users[0].Roles...)
+	roleIDs = append(roleIDs, userRoles...)
 	for roleID := range roleIDMap {
 		roleIDs = append(roleIDs, roleID)
 	}
@@ -148,64 +268,74 @@ func (r repository) GetCheckDetails(ctx context.Context, org_identifier string,
 	return CheckDetails{
 		Roles:          roleIDs,
 		Policies:       policyIDs,
-		UserProperties: org.Users[0].UserProperties,
-	}, nil
-}
-
-func (r repository) GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) (map[string]string, error) {
-
-	// Filter to find documents with the specified policy IDs
-	filter := bson.M{
-		"identifier": org_identifier,
-		"policies": bson.M{
-			"$elemMatch": bson.M{
-				"_id": bson.M{"$in": policy_ids},
-			},
-		},
+		UserProperties: user.UserProperties,
+		RoleGrants:     roleGrants,
 	}
+}
 
-	// Projection to extract only the relevant policies
-	projection := bson.M{"policies": 1}
-
-	cursor, err := r.mongoColl.Find(ctx, filter, options.Find().SetProjection(projection))
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+// withInheritedPermissions returns the requested roles, with the permissions of their ancestors added to their own.
+// The policies of each role are folded into the permissions it holds, so the resolved roles carry no policies.
+func withInheritedPermissions(roles []mongo_entity.Role, role_ids []primitive.ObjectID) []mongo_entity.Role {
 
-	activePolicies := make(map[string]string)
-	for cursor.Next(ctx) {
-		var doc struct {
-			Policies []struct {
-				ID             primitive.ObjectID `bson:"_id"`
-				ActiveVersion  string             `bson:"active_version"`
-				PolicyContents []struct {
-					Version string `bson:"version"`
-					Policy  string `bson:"policy"`
-				} `bson:"policy_contents"`
-			} `bson:"policies"`
+	var resolved []mongo_entity.Role
+	for _, role := range roles {
+		if !contains(role_ids, role.ID) {
+			continue
 		}
-		if err := cursor.Decode(&doc); err != nil {
-			return nil, err
+		permissions := append([]mongo_entity.Permission{}, role.ConditionedPermissions()...)
+		for _, ancestor := range mongo_entity.AncestorRoles(roles, role.ID) {
+			permissions = append(permissions, ancestor.ConditionedPermissions()...)
 		}
+		role.Permissions = permissions
+		role.Policies = nil
+		resolved = append(resolved, role)
+	}
+	return resolved
+}
 
-		for _, policy := range doc.Policies {
-			if contains(policy_ids, policy.ID) {
-				for _, content := range policy.PolicyContents {
-					if content.Version == policy.ActiveVersion {
-						activePolicies[policy.ID.Hex()] = content.Policy
-						break
+// activePolicyContents picks the active version of each of the given policies of the organization.
+func activePolicyContents(org_id primitive.ObjectID, policies []mongo_entity.Policy, policy_ids []primitive.ObjectID) []ActivePolicyContent {
+
+	var activePolicies []ActivePolicyContent
+	for _, policy := range policies {
+		if contains(policy_ids, policy.ID) {
+			for _, content := range policy.PolicyContents {
+				if content.Version == policy.ActiveVersion {
+					active := ActivePolicyContent{
+						OrganizationID: org_id,
+						ID:             policy.ID,
+						Identifier:     policy.Identifier,
+						Version:        content.Version,
+						Language:       policy.ContentLanguage(content),
+						Policy:         content.Policy,
+					}
+					if shadow, ok := policy.Content(policy.ShadowVersion); ok && policy.ShadowVersion != policy.ActiveVersion {
+						active.Shadow = &ShadowPolicyContent{Version: shadow.Version, Language: policy.ContentLanguage(shadow), Policy: shadow.Policy}
 					}
+					activePolicies = append(activePolicies, active)
+					break
 				}
 			}
 		}
 	}
+	return activePolicies
+}
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
-	}
+// resourcePolicies collects the policies of every resource whose identifier matches the resource, as a pattern or exactly.
+func resourcePolicies(resources []mongo_entity.Resource, resource string) []primitive.ObjectID {
 
-	return activePolicies, nil
+	var policyIDs []primitive.ObjectID
+	for _, r := range resources {
+		if !mongo_entity.MatchResource(r.Identifier, resource) {
+			continue
+		}
+		for _, policyID := range r.Policies {
+			if !contains(policyIDs, policyID) {
+				policyIDs = append(policyIDs, policyID)
+			}
+		}
+	}
+	return policyIDs
 }
 
 func contains(slice []primitive.ObjectID, item primitive.ObjectID) bool {
@@ -2,43 +2,98 @@ package check
 
 import (
 	"context"
-	"encoding/json"
 
+	"github.com/shashimalcse/cronuseo/internal/engine"
+	"github.com/shashimalcse/cronuseo/internal/event"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
-	"github.com/shashimalcse/tunnel_go"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
 type Service interface {
 	Check(ctx context.Context, org_identifier string, req CheckRequest, apiKey string, skipValidation bool) (CheckResponse, error)
+	BatchCheck(ctx context.Context, org_identifier string, req BatchCheckRequest, apiKey string, skipValidation bool) (BatchCheckResponse, error)
+	EffectivePermissions(ctx context.Context, org_identifier string, identifier string, apiKey string, skipValidation bool) (EffectivePermissionsResponse, error)
+	PermissionHolders(ctx context.Context, org_identifier string, req PermissionHoldersRequest, apiKey string, skipValidation bool) (PermissionHoldersResponse, error)
 	ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error)
+	Shadows(ctx context.Context, org_identifier string, apiKey string, skipValidation bool) (ShadowsResponse, error)
+	HandleEvent(e event.Event)
 }
 
 type CheckRequest struct {
 	Identifier string `json:"identifier"`
 	Action     string `json:"action"`
 	Resource   string `json:"resource"`
+	// ObjectID narrows the check to one instance of the resource. The action is then resolved
+	// as a relation of the instance from the organization's relation tuples.
+	ObjectID string `json:"object_id,omitempty"`
+	Explain  bool   `json:"explain,omitempty"`
+	// Revision is a consistency token returned by a write. The check sees that write or later ones.
+	Revision string `json:"revision,omitempty"`
+	// Context holds attributes of the request, such as the address, the time or the device it comes from,
+	// for the policies of the subject. Attributes of the resource go under "resource".
+	Context map[string]interface{} `json:"context,omitempty"`
 }
 
 type CheckResponse struct {
-	Allowed bool `json:"allowed"`
+	Allowed bool        `json:"allowed"`
+	Trace   *CheckTrace `json:"trace,omitempty"`
+}
+
+type BatchCheckRequest struct {
+	Checks   []CheckRequest `json:"checks"`
+	Revision string         `json:"revision,omitempty"`
+}
+
+type BatchCheckResponse struct {
+	Results []CheckResponse `json:"results"`
 }
 
 type service struct {
 	repo   Repository
 	logger *zap.Logger
+	// policies keeps the active policy versions compiled by their engines.
+	policies *engine.Cache
+	shadows  *Shadows
 }
 
 type CheckDetails struct {
 	Roles          []primitive.ObjectID
 	Policies       []primitive.ObjectID
 	UserProperties map[string]interface{}
+	RoleGrants     []RoleGrant
+}
+
+// RoleGrant records how a role reached the subject. GroupID is zero for direct assignments.
+type RoleGrant struct {
+	RoleID          primitive.ObjectID
+	GroupID         primitive.ObjectID
+	GroupIdentifier string
+}
+
+type ActivePolicyContent struct {
+	// OrganizationID is the organization the policy belongs to.
+	OrganizationID primitive.ObjectID
+	ID             primitive.ObjectID
+	Identifier     string
+	Version        string
+	Language       string
+	Policy         string
+	// Shadow is the shadow version of the policy, if it has one.
+	Shadow *ShadowPolicyContent
+}
+
+type ShadowPolicyContent struct {
+	Version  string
+	Language string
+	Policy   string
 }
 
 func NewService(repo Repository, logger *zap.Logger) Service {
 
-	return service{repo: repo, logger: logger}
+	return service{repo: repo, logger: logger, policies: engine.NewCache(engine.Default()), shadows: NewShadows()}
 }
 
 func (s service) Check(ctx context.Context, org_identifier string, req CheckRequest, apiKey string, skipValidation bool) (CheckResponse, error) {
@@ -51,36 +106,383 @@ func (s service) Check(ctx context.Context, org_identifier string, req CheckRequ
 			return CheckResponse{}, &util.UnauthorizedError{}
 		}
 	}
-	checkDetails, err := s.repo.GetCheckDetails(ctx, org_identifier, req.Identifier)
+	if err := s.ensureRevision(ctx, org_identifier, req.Revision); err != nil {
+		return CheckResponse{}, err
+	}
+	if req.Explain {
+		return s.explain(ctx, org_identifier, req, skipValidation)
+	}
+	subject, err := s.loadSubject(ctx, org_identifier, req.Identifier)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	algorithm, err := s.repo.GetCombiningAlgorithm(ctx, org_identifier)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	var graph *relationGraph
+	if req.ObjectID != "" {
+		if graph, err = s.loadRelationGraph(ctx, org_identifier); err != nil {
+			return CheckResponse{}, err
+		}
+	}
+	allowed, err := s.decide(ctx, org_identifier, algorithm, subject, graph, req, skipValidation)
 	if err != nil {
 		return CheckResponse{}, err
 	}
-	allow := false
+	return CheckResponse{Allowed: allowed}, nil
+}
+
+func (s service) BatchCheck(ctx context.Context, org_identifier string, req BatchCheckRequest, apiKey string, skipValidation bool) (BatchCheckResponse, error) {
+
+	if !skipValidation {
+		validated, _ := s.ValidateAPIKey(ctx, org_identifier, apiKey)
+		if !validated {
+			s.logger.Error("Error while validating api key for batch permission check")
+			return BatchCheckResponse{}, &util.UnauthorizedError{}
+		}
+	}
+	if err := s.ensureRevision(ctx, org_identifier, req.Revision); err != nil {
+		return BatchCheckResponse{}, err
+	}
+	for _, item := range req.Checks {
+		if err := s.ensureRevision(ctx, org_identifier, item.Revision); err != nil {
+			return BatchCheckResponse{}, err
+		}
+	}
+
+	algorithm, err := s.repo.GetCombiningAlgorithm(ctx, org_identifier)
+	if err != nil {
+		return BatchCheckResponse{}, err
+	}
+
+	// Load each subject only once, no matter how many items refer to it.
+	subjects := make(map[string]*subjectDetails)
+	var graph *relationGraph
+	results := make([]CheckResponse, 0, len(req.Checks))
+	for _, item := range req.Checks {
+		if item.Explain {
+			result, err := s.explain(ctx, org_identifier, item, skipValidation)
+			if err != nil {
+				if _, notFound := err.(*util.NotFoundError); !notFound {
+					return BatchCheckResponse{}, err
+				}
+				result = CheckResponse{Trace: &CheckTrace{Reason: ReasonUserNotFound, Roles: []RoleTrace{}, Policies: []PolicyTrace{}}}
+			}
+			results = append(results, result)
+			continue
+		}
+		if item.ObjectID != "" && graph == nil {
+			loaded, err := s.loadRelationGraph(ctx, org_identifier)
+			if err != nil {
+				return BatchCheckResponse{}, err
+			}
+			graph = loaded
+		}
+		subject, loaded := subjects[item.Identifier]
+		if !loaded {
+			details, err := s.loadSubject(ctx, org_identifier, item.Identifier)
+			if err != nil {
+				if _, notFound := err.(*util.NotFoundError); !notFound {
+					return BatchCheckResponse{}, err
+				}
+				s.logger.Debug("Subject not found for batch permission check", zap.String("identifier", item.Identifier))
+			}
+			subject = details
+			subjects[item.Identifier] = subject
+		}
+		allowed, err := s.decide(ctx, org_identifier, algorithm, subject, graph, item, skipValidation)
+		if err != nil {
+			return BatchCheckResponse{}, err
+		}
+		results = append(results, CheckResponse{Allowed: allowed})
+	}
+	return BatchCheckResponse{Results: results}, nil
+}
+
+// subjectDetails holds everything needed to decide checks for a single subject.
+type subjectDetails struct {
+	details     CheckDetails
+	permissions []mongo_entity.Permission
+	// policies holds the results of the policies of the subject without a request context, once evaluated
+	// for the action and resource in policiesFor.
+	policies    []PolicyTrace
+	policiesFor string
+}
+
+// rbac applies deny-overrides to the roles of the subject: a matching deny permission wins over every
+// matching allow. Permissions whose policies do not hold for the check take no part in it.
+func (d *subjectDetails) rbac(req CheckRequest, conditions checkConditions) decision {
+
+	result := notApplicable
+	for _, permission := range d.permissions {
+		if !permission.Matches(req.Resource, req.Action) || !conditions.hold(permission.Policies) {
+			continue
+		}
+		if permission.Denies() {
+			return deny
+		}
+		result = permit
+	}
+	return result
+}
+
+// targeted reports whether the check has a target besides the policies of the subject: a permission of the
+// subject matching it, or a policy scoped to its resource. A matching permission left out by its own policies
+// takes the target away, as those policies must hold whatever the combining algorithm is.
+func (d *subjectDetails) targeted(req CheckRequest, conditions checkConditions) bool {
+
+	targeted := conditions.resourceScoped
+	for _, permission := range d.permissions {
+		if !permission.Matches(req.Resource, req.Action) {
+			continue
+		}
+		if !conditions.hold(permission.Policies) {
+			return false
+		}
+		targeted = true
+	}
+	return targeted
+}
+
+// loadSubject resolves the permissions of a subject. Its policies are evaluated when a check needs them.
+func (s service) loadSubject(ctx context.Context, org_identifier string, identifier string) (*subjectDetails, error) {
+
+	checkDetails, err := s.repo.GetCheckDetails(ctx, org_identifier, identifier)
+	if err != nil {
+		return nil, err
+	}
+	subject := &subjectDetails{details: checkDetails}
 	if len(checkDetails.Roles) > 0 {
 		role_permissions, err := s.repo.GetRolePermissions(ctx, org_identifier, checkDetails.Roles)
 		if err != nil {
-			return CheckResponse{}, err
+			return nil, err
+		}
+		subject.permissions = *role_permissions
+	}
+	return subject, nil
+}
+
+// decide combines the RBAC decision of the subject for the check with its policies under the combining algorithm.
+// The policies of the resource and of the matching permissions must hold whatever the algorithm is. A check on
+// an object also takes the relation tuples of the graph into account.
+func (s service) decide(ctx context.Context, org_identifier string, algorithm mongo_entity.CombiningAlgorithm, subject *subjectDetails, graph *relationGraph, req CheckRequest, skipValidation bool) (bool, error) {
+
+	if subject == nil {
+		return false, nil
+	}
+	conditions, err := s.evaluateConditions(ctx, org_identifier, subject, req, skipValidation)
+	if err != nil {
+		return false, err
+	}
+	if !conditions.resourcePassed {
+		return false, nil
+	}
+	rbac := subject.rbac(req, conditions)
+	if req.ObjectID != "" {
+		rbac = relate(rbac, graph.check(req.Identifier, req.Resource, req.ObjectID, req.Action))
+	}
+	allowed, _, err := combine(algorithm, rbac, rbac == permit || subject.targeted(req, conditions), func() ([]PolicyTrace, error) {
+		return s.subjectPolicies(ctx, org_identifier, subject, req, skipValidation)
+	})
+	return allowed, err
+}
+
+// subjectPolicies evaluates the policies of the subject with the context of the check. Without a context,
+// the results are kept on the subject for the other checks of a batch. Without validation there are none.
+func (s service) subjectPolicies(ctx context.Context, org_identifier string, subject *subjectDetails, req CheckRequest, skipValidation bool) ([]PolicyTrace, error) {
+
+	if skipValidation {
+		return []PolicyTrace{}, nil
+	}
+	target := req.Action + "\x00" + req.Resource
+	if len(req.Context) == 0 && subject.policies != nil && subject.policiesFor == target {
+		return subject.policies, nil
+	}
+	traces, _, err := s.evaluatePolicies(ctx, org_identifier, req, subject.details)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Context) == 0 {
+		subject.policies, subject.policiesFor = traces, target
+	}
+	return traces, nil
+}
+
+// ensureRevision makes sure the check sees the organization at the revision of the consistency token or later.
+func (s service) ensureRevision(ctx context.Context, org_identifier string, token string) error {
+
+	if token == "" {
+		return nil
+	}
+	rev, err := revision.Parse(token)
+	if err != nil {
+		return err
+	}
+	return s.repo.EnsureRevision(ctx, org_identifier, rev)
+}
+
+// loadRelationGraph loads the relation tuples and relation definitions of the organization.
+func (s service) loadRelationGraph(ctx context.Context, org_identifier string) (*relationGraph, error) {
+
+	org, err := s.repo.GetOrganization(ctx, org_identifier)
+	if err != nil {
+		return nil, err
+	}
+	return newRelationGraph(org), nil
+}
+
+// checkConditions holds the results of the policies scoping a single check to its resource and permissions.
+type checkConditions struct {
+	results        map[primitive.ObjectID]bool
+	resourcePassed bool
+	// resourceScoped tells whether the resource of the check has policies of its own.
+	resourceScoped bool
+	traces         []PolicyTrace
+}
+
+// hold reports whether all the given policies passed. Policies which were not evaluated,
+// because they no longer exist or have no active version, do not stand in the way.
+func (c checkConditions) hold(policy_ids []primitive.ObjectID) bool {
+
+	for _, policyID := range policy_ids {
+		if passed, evaluated := c.results[policyID]; evaluated && !passed {
+			return false
 		}
-		for _, permission := range *role_permissions {
-			if permission.Resource == req.Resource && permission.Action == req.Action {
-				allow = true
+	}
+	return true
+}
+
+// evaluateConditions evaluates the policies of the resource of the check and of the permissions of the
+// subject matching it. Without validation they are not evaluated and every condition holds.
+func (s service) evaluateConditions(ctx context.Context, org_identifier string, subject *subjectDetails, req CheckRequest, skipValidation bool) (checkConditions, error) {
+
+	conditions := checkConditions{results: make(map[primitive.ObjectID]bool), resourcePassed: true, traces: []PolicyTrace{}}
+	if skipValidation {
+		return conditions, nil
+	}
+	input := policyInput(req, subject.details)
+
+	resourcePolicies, err := s.repo.GetResourcePolicies(ctx, org_identifier, req.Resource)
+	if err != nil {
+		return checkConditions{}, err
+	}
+	traces, err := s.evaluatePolicyIDs(ctx, org_identifier, resourcePolicies, input, PolicyScopeResource)
+	if err != nil {
+		return checkConditions{}, err
+	}
+	for _, trace := range traces {
+		conditions.resourcePassed = conditions.resourcePassed && trace.Result
+	}
+	conditions.resourceScoped = len(traces) > 0
+	conditions.traces = append(conditions.traces, traces...)
+
+	var permissionPolicies []primitive.ObjectID
+	for _, permission := range subject.permissions {
+		if !permission.Matches(req.Resource, req.Action) {
+			continue
+		}
+		for _, policyID := range permission.Policies {
+			if !contains(permissionPolicies, policyID) {
+				permissionPolicies = append(permissionPolicies, policyID)
 			}
 		}
 	}
-	if !skipValidation {
-		properties, err := json.Marshal(*&checkDetails.UserProperties)
+	traces, err = s.evaluatePolicyIDs(ctx, org_identifier, permissionPolicies, input, PolicyScopePermission)
+	if err != nil {
+		return checkConditions{}, err
+	}
+	for _, trace := range traces {
+		id, _ := primitive.ObjectIDFromHex(trace.ID)
+		conditions.results[id] = trace.Result
+	}
+	conditions.traces = append(conditions.traces, traces...)
+	return conditions, nil
+}
+
+// evaluatePolicies evaluates every active policy assigned to the subject with the engine of its language,
+// against the user properties of the subject and the request.
+func (s service) evaluatePolicies(ctx context.Context, org_identifier string, req CheckRequest, checkDetails CheckDetails) ([]PolicyTrace, bool, error) {
+
+	traces, err := s.evaluatePolicyIDs(ctx, org_identifier, checkDetails.Policies, policyInput(req, checkDetails), PolicyScopeSubject)
+	if err != nil {
+		return nil, false, err
+	}
+	passed := true
+	for _, trace := range traces {
+		passed = passed && trace.Result
+	}
+	return traces, passed, nil
+}
+
+// policyInput is what the policies of a check are evaluated against.
+func policyInput(req CheckRequest, checkDetails CheckDetails) engine.Input {
+
+	input := engine.NewInput(req.Identifier, checkDetails.UserProperties, req.Context)
+	input.Action, input.ResourceIdentifier = req.Action, req.Resource
+	return input
+}
+
+// HandleEvent drops the compiled versions of a changed policy, of every policy of an organization when the
+// change is not narrowed to one, or of every policy when the organization is not known. The compiled policies
+// are keyed by organization and policy id, so other changes keep them. The shadow counts of a changed policy
+// start over.
+func (s service) HandleEvent(e event.Event) {
+
+	switch {
+	case e.OrganizationID == "":
+		s.policies.Evict("")
+	case e.ID == "":
+		s.policies.Evict(policyKey(e.OrganizationID, ""))
+	case e.Entity == event.PolicyEntity:
+		s.policies.Evict(policyKey(e.OrganizationID, e.ID))
+		s.shadows.forget(e.ID)
+	}
+}
+
+// policyKey is the key the active version of a policy is compiled under. Keys start with the organization,
+// so the policies of an organization are dropped together.
+func policyKey(org_id string, policy_id string) string {
+
+	return org_id + "/" + policy_id
+}
+
+// evaluatePolicyIDs evaluates the active versions of the given policies, tracing each one under the scope.
+func (s service) evaluatePolicyIDs(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID, input engine.Input, scope string) ([]PolicyTrace, error) {
+
+	traces := []PolicyTrace{}
+	if len(policy_ids) == 0 {
+		return traces, nil
+	}
+	active_policies, err := s.repo.GetActivePolicyVersionContents(ctx, org_identifier, policy_ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range active_policies {
+		language := engine.LanguageOf(policy.Language)
+		result := false
+		compiled, err := s.policies.Compile(policyKey(policy.OrganizationID.Hex(), policy.ID.Hex()), language, policy.Policy)
+		if err == nil {
+			result, err = compiled.Evaluate(input)
+		}
 		if err != nil {
-			return CheckResponse{}, err
+			// A policy which can not be evaluated never passes.
+			s.logger.Error("Error while evaluating policy.", zap.String("policy", policy.Identifier), zap.String("version", policy.Version), zap.Error(err))
+			result = false
 		}
-		active_policies, err := s.repo.GetActivePolicyVersionContents(ctx, org_identifier, checkDetails.Policies)
-		for _, policy := range active_policies {
-			result := tunnel_go.ValidateTunnelPolicy(policy, string(properties))
-			if !result {
-				return CheckResponse{}, nil
-			}
+		if policy.Shadow != nil {
+			s.evaluateShadow(org_identifier, policy, input, result)
 		}
+		traces = append(traces, PolicyTrace{
+			ID:         policy.ID.Hex(),
+			Identifier: policy.Identifier,
+			Version:    policy.Version,
+			Language:   string(language),
+			Scope:      scope,
+			Result:     result,
+		})
 	}
-	return CheckResponse{Allowed: allow}, nil
+	return traces, nil
 }
 
 func (s service) ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error) {
@@ -0,0 +1,981 @@
+package check
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/event"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/test"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_service_BatchCheck(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger)
+
+	ctx := context.Background()
+
+	result, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "alice", Action: "read", Resource: "invoices"},
+		{Identifier: "alice", Action: "delete", Resource: "invoices"},
+		{Identifier: "bob", Action: "read", Resource: "invoices"},
+		{Identifier: "unknown", Action: "read", Resource: "invoices"},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckResponse{{Allowed: true}, {Allowed: false}, {Allowed: false}, {Allowed: false}}, result.Results)
+
+	// details are loaded once per subject
+	assert.Equal(t, 1, repo.detailCalls["alice"])
+
+	// invalid api key
+	_, err = s.BatchCheck(ctx, "org", BatchCheckRequest{}, "wrong", false)
+	assert.NotNil(t, err)
+}
+
+func Test_service_CheckDenyOverrides(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "erin", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "erin", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonPermissionDenied, result.Trace.Reason)
+	assert.Equal(t, []RoleTrace{
+		{ID: readerRole.Hex(), Identifier: readerRole.Hex(), Source: RoleSourceDirect, Granted: true},
+		{ID: blockedRole.Hex(), Identifier: blockedRole.Hex(), Source: RoleSourceDirect, Denied: true},
+	}, result.Trace.Roles)
+
+	permissions, err := s.EffectivePermissions(ctx, "org", "erin", "key", false)
+	assert.Nil(t, err)
+	assert.Empty(t, permissions.Permissions)
+	if assert.Len(t, permissions.Denied, 1) {
+		assert.Equal(t, blockedRole.Hex(), permissions.Denied[0].Grants[0].RoleID)
+	}
+}
+
+func Test_service_CheckPatterns(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	result, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "frank", Action: "write", Resource: "projects/apollo/documents"},
+		{Identifier: "frank", Action: "write", Resource: "projects/apollo/images"},
+		{Identifier: "frank", Action: "write", Resource: "projects/documents"},
+		{Identifier: "frank", Action: "invoices:read", Resource: "invoices"},
+		{Identifier: "frank", Action: "invoices:delete", Resource: "invoices"},
+		{Identifier: "frank", Action: "read", Resource: "invoices"},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckResponse{{Allowed: true}, {Allowed: false}, {Allowed: false}, {Allowed: true}, {Allowed: false}, {Allowed: false}}, result.Results)
+}
+
+func Test_service_CheckInheritedPermissions(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	result, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "gina", Action: "read", Resource: "reports"},
+		{Identifier: "gina", Action: "read", Resource: "invoices"},
+		{Identifier: "alice", Action: "read", Resource: "reports"},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckResponse{{Allowed: true}, {Allowed: true}, {Allowed: false}}, result.Results)
+
+	// cycles in stored data don't loop forever
+	repo := newMockRepository()
+	repo.parents[readerRole] = []primitive.ObjectID{auditorRole}
+	roles, err := repo.GetRoles(ctx, "org", []primitive.ObjectID{readerRole})
+	assert.Nil(t, err)
+	if assert.Len(t, roles, 1) {
+		assert.Len(t, roles[0].Permissions, 2)
+	}
+}
+
+func Test_service_Explain(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	// granted through a group
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "carol", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, ReasonAllowed, result.Trace.Reason)
+	assert.Equal(t, []RoleTrace{{ID: readerRole.Hex(), Identifier: readerRole.Hex(), Source: RoleSourceGroup, Group: "finance", Granted: true}}, result.Trace.Roles)
+
+	// missing permission
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "alice", Action: "delete", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonPermissionNotGranted, result.Trace.Reason)
+
+	// no roles
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonNoRoles, result.Trace.Reason)
+
+	// unknown user fails like it does without explain
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "unknown", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.IsType(t, &util.NotFoundError{}, err)
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "unknown", Action: "read", Resource: "invoices"}, "key", false)
+	assert.IsType(t, &util.NotFoundError{}, err)
+
+	// batch items are explained one by one, and an unknown user is denied
+	batch, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "bob", Action: "read", Resource: "invoices", Explain: true},
+		{Identifier: "unknown", Action: "read", Resource: "invoices", Explain: true},
+		{Identifier: "alice", Action: "read", Resource: "invoices"},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonNoRoles, batch.Results[0].Trace.Reason)
+	assert.False(t, batch.Results[1].Allowed)
+	assert.Equal(t, ReasonUserNotFound, batch.Results[1].Trace.Reason)
+	assert.True(t, batch.Results[2].Allowed)
+	assert.Nil(t, batch.Results[2].Trace)
+
+	// no trace without explain
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "alice", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.Nil(t, result.Trace)
+}
+
+func Test_service_CheckPolicies(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger)
+
+	ctx := context.Background()
+
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, []PolicyTrace{{ID: clearancePolicy.Hex(), Identifier: "clearance", Version: "v1", Language: "cel", Scope: PolicyScopeSubject, Result: true}}, result.Trace.Policies)
+
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "jack", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// a version changed in place is compiled again
+	repo.policies[0].PolicyContents[0].Policy = "user_properties.clearance >= 1 && now > timestamp('2020-01-01T00:00:00Z')"
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "jack", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+
+	// policies which do not compile never pass
+	repo.policies[0].PolicyContents[0].Policy = "user_properties.clearance >="
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// rego policies see the action and the resource of the check
+	repo.policies[0].PolicyContents[0] = mongo_entity.PolicyContent{Version: "v1", Language: "rego",
+		Policy: "package cronuseo\n\nimport rego.v1\n\nallow if {\n  input.action == \"read\"\n  input.resource == \"invoices\"\n  input.user_properties.clearance >= 3\n}"}
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, "rego", result.Trace.Policies[0].Language)
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "jack", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func Test_service_HandleEvent(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger)
+	policies := s.(service).policies
+
+	ctx := context.Background()
+
+	_, err := s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, policies.Len())
+
+	// a new active version takes the place of the old one
+	repo.policies[0].PolicyContents = append(repo.policies[0].PolicyContents, mongo_entity.PolicyContent{Version: "v2", Policy: "user_properties.clearance >= 4"})
+	repo.policies[0].ActiveVersion = "v2"
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 1, policies.Len())
+
+	// changes to other policies keep the compiled ones, a change to the policy drops it
+	org_id := organizationID.Hex()
+	s.HandleEvent(event.Event{OrganizationID: org_id, Entity: event.PolicyEntity, ID: primitive.NewObjectID().Hex()})
+	s.HandleEvent(event.Event{OrganizationID: primitive.NewObjectID().Hex(), Entity: event.PolicyEntity, ID: clearancePolicy.Hex()})
+	s.HandleEvent(event.Event{OrganizationID: org_id, Entity: event.RoleEntity, ID: clearancePolicy.Hex()})
+	assert.Equal(t, 1, policies.Len())
+	s.HandleEvent(event.Event{OrganizationID: org_id, Entity: event.PolicyEntity, ID: clearancePolicy.Hex()})
+	assert.Equal(t, 0, policies.Len())
+
+	// a change to an organization which is not narrowed to one entity drops its policies, and only its policies
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	s.HandleEvent(event.Event{OrganizationID: primitive.NewObjectID().Hex(), Entity: event.PolicyEntity})
+	assert.Equal(t, 1, policies.Len())
+	s.HandleEvent(event.Event{OrganizationID: org_id, Entity: event.PolicyEntity})
+	assert.Equal(t, 0, policies.Len())
+
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	s.HandleEvent(event.Event{})
+	assert.Equal(t, 0, policies.Len())
+}
+
+func Test_service_CheckContext(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	corporate := map[string]interface{}{"ip": "10.1.2.3", "resource": map[string]interface{}{"department": "finance"}}
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "kim", Action: "read", Resource: "invoices", Context: corporate}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+
+	// outside the corporate network, or without the context the policy needs
+	public := map[string]interface{}{"ip": "203.0.113.9", "resource": map[string]interface{}{"department": "finance"}}
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "kim", Action: "read", Resource: "invoices", Context: public}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "kim", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// each item of a batch is evaluated with its own context
+	batch, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "kim", Action: "read", Resource: "invoices", Context: corporate},
+		{Identifier: "kim", Action: "read", Resource: "invoices", Context: public},
+		{Identifier: "kim", Action: "read", Resource: "invoices"},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckResponse{{Allowed: true}, {Allowed: false}, {Allowed: false}}, batch.Results)
+}
+
+func Test_service_CheckScopedPolicies(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	// a policy of the role conditions its permissions
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "leo", Action: "approve", Resource: "invoices", Context: map[string]interface{}{"amount": 500}}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "leo", Action: "approve", Resource: "invoices", Context: map[string]interface{}{"amount": 5000}}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// a conditional deny only applies when its policy passes
+	batch, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "mia", Action: "read", Resource: "invoices", Context: map[string]interface{}{"hour": 10}},
+		{Identifier: "mia", Action: "read", Resource: "invoices", Context: map[string]interface{}{"hour": 20}},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckResponse{{Allowed: true}, {Allowed: false}}, batch.Results)
+
+	// a policy of the resource applies to every subject, unless validation is skipped
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "nina", Action: "read", Resource: "payroll/2024", Context: map[string]interface{}{"ip": "10.1.2.3"}}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "nina", Action: "read", Resource: "payroll/2024", Context: map[string]interface{}{"ip": "203.0.113.9"}}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "nina", Action: "read", Resource: "payroll/2024"}, "", true)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+
+	// the trace tells where each policy comes from
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "nina", Action: "read", Resource: "payroll/2024", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, ReasonPolicyDenied, result.Trace.Reason)
+	if assert.Len(t, result.Trace.Policies, 1) {
+		assert.Equal(t, "corporate", result.Trace.Policies[0].Identifier)
+		assert.Equal(t, PolicyScopeResource, result.Trace.Policies[0].Scope)
+	}
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "leo", Action: "approve", Resource: "invoices", Context: map[string]interface{}{"amount": 5000}, Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonPermissionNotGranted, result.Trace.Reason)
+	if assert.Len(t, result.Trace.Policies, 1) {
+		assert.Equal(t, PolicyScopePermission, result.Trace.Policies[0].Scope)
+		assert.False(t, result.Trace.Policies[0].Result)
+	}
+
+	// conditional grants are effective, with the policies they depend on
+	permissions, err := s.EffectivePermissions(ctx, "org", "leo", "key", false)
+	assert.Nil(t, err)
+	if assert.Len(t, permissions.Permissions, 1) {
+		assert.Equal(t, []string{amountPolicy.Hex()}, permissions.Permissions[0].Grants[0].Policies)
+	}
+	permissions, err = s.EffectivePermissions(ctx, "org", "mia", "key", false)
+	assert.Nil(t, err)
+	assert.Len(t, permissions.Permissions, 1)
+	assert.Len(t, permissions.Denied, 1)
+}
+
+func Test_service_ShadowPolicies(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger)
+
+	ctx := context.Background()
+
+	// a stricter shadow version of the clearance policy
+	repo.policies[0].PolicyContents = append(repo.policies[0].PolicyContents, mongo_entity.PolicyContent{Version: "v2", Policy: "user_properties.clearance >= 4"})
+	repo.policies[0].ShadowVersion = "v2"
+
+	// only the active version decides, and traces
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, []PolicyTrace{{ID: clearancePolicy.Hex(), Identifier: "clearance", Version: "v1", Language: "cel", Scope: PolicyScopeSubject, Result: true}}, result.Trace.Policies)
+
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "jack", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// every evaluation is counted, along with the disagreements
+	shadows, err := s.Shadows(ctx, "org", "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []ShadowStats{{PolicyID: clearancePolicy.Hex(), Identifier: "clearance", ActiveVersion: "v1", ShadowVersion: "v2", Evaluations: 2, Disagreements: 1, Denied: 1}}, shadows.Shadows)
+	// the counts are those of this instance
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, shadows.Instance)
+	assert.False(t, shadows.Since.IsZero())
+
+	shadows, err = s.Shadows(ctx, "other", "key", false)
+	assert.Nil(t, err)
+	assert.Empty(t, shadows.Shadows)
+	_, err = s.Shadows(ctx, "org", "wrong", false)
+	assert.IsType(t, &util.UnauthorizedError{}, err)
+
+	// a shadow version which is the active version is not evaluated again
+	repo.policies[0].ShadowVersion = "v1"
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	shadows, _ = s.Shadows(ctx, "org", "key", false)
+	assert.Equal(t, uint64(2), shadows.Shadows[0].Evaluations)
+
+	// another shadow version starts counting over, in place of the previous one
+	repo.policies[0].PolicyContents = append(repo.policies[0].PolicyContents, mongo_entity.PolicyContent{Version: "v3", Policy: "user_properties.clearance >= 1"})
+	repo.policies[0].ShadowVersion = "v3"
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "ivy", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	shadows, _ = s.Shadows(ctx, "org", "key", false)
+	assert.Equal(t, []ShadowStats{{PolicyID: clearancePolicy.Hex(), Identifier: "clearance", ActiveVersion: "v1", ShadowVersion: "v3", Evaluations: 1}}, shadows.Shadows)
+
+	// a change to the policy drops its counts
+	s.HandleEvent(event.Event{OrganizationID: "org", Entity: event.PolicyEntity, ID: clearancePolicy.Hex()})
+	shadows, _ = s.Shadows(ctx, "org", "key", false)
+	assert.Empty(t, shadows.Shadows)
+}
+
+func Test_service_CombiningAlgorithms(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger)
+
+	ctx := context.Background()
+
+	// jack is granted with a failing policy, olga has no permission for invoices with a passing policy and pat is
+	// denied with a passing policy. The payroll resource has a passing policy of its own for olga to be targeted.
+	office := map[string]interface{}{"ip": "10.1.2.3"}
+	checks := BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "jack", Action: "read", Resource: "invoices"},
+		{Identifier: "olga", Action: "read", Resource: "invoices"},
+		{Identifier: "pat", Action: "read", Resource: "invoices"},
+		{Identifier: "olga", Action: "read", Resource: "payroll/2024", Context: office},
+	}}
+	for _, tc := range []struct {
+		algorithm mongo_entity.CombiningAlgorithm
+		want      []bool
+	}{
+		{"", []bool{false, false, false, false}},
+		{mongo_entity.RBACAndABAC, []bool{false, false, false, false}},
+		{mongo_entity.DenyOverrides, []bool{false, false, false, true}},
+		{mongo_entity.PermitOverrides, []bool{true, false, false, true}},
+		{mongo_entity.FirstApplicable, []bool{true, false, false, true}},
+		{mongo_entity.RBACOrABAC, []bool{true, false, false, true}},
+	} {
+		repo.algorithm = tc.algorithm
+		batch, err := s.BatchCheck(ctx, "org", checks, "key", false)
+		assert.Nil(t, err)
+		for i, result := range batch.Results {
+			assert.Equal(t, tc.want[i], result.Allowed, "%s: %s %s", tc.algorithm, checks.Checks[i].Identifier, checks.Checks[i].Resource)
+		}
+	}
+
+	// the trace reports the algorithm, and policies are not evaluated once the roles decide the check
+	repo.algorithm = ""
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "pat", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, string(mongo_entity.RBACAndABAC), result.Trace.Algorithm)
+	assert.Equal(t, ReasonPermissionDenied, result.Trace.Reason)
+	assert.Empty(t, result.Trace.Policies)
+
+	// a deny of the roles wins over passing policies, and policies alone do not grant what no permission targets
+	for _, algorithm := range mongo_entity.CombiningAlgorithms {
+		repo.algorithm = algorithm
+		result, err = s.Check(ctx, "org", CheckRequest{Identifier: "pat", Action: "read", Resource: "invoices", Explain: true}, "key", false)
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed, algorithm)
+		assert.Equal(t, ReasonPermissionDenied, result.Trace.Reason, algorithm)
+		result, err = s.Check(ctx, "org", CheckRequest{Identifier: "olga", Action: "delete", Resource: "anything", Explain: true}, "key", false)
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed, algorithm)
+		assert.Equal(t, ReasonNoRoles, result.Trace.Reason, algorithm)
+	}
+
+	repo.algorithm = mongo_entity.PermitOverrides
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "olga", Action: "read", Resource: "payroll/2024", Context: office, Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, ReasonAllowed, result.Trace.Reason)
+	assert.Len(t, result.Trace.Policies, 2)
+
+	// grants stand against failing policies when the algorithm lets them
+	permissions, err := s.EffectivePermissions(ctx, "org", "jack", "key", false)
+	assert.Nil(t, err)
+	assert.Len(t, permissions.Permissions, 1)
+}
+
+func Test_service_EffectivePermissions(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+
+	result, err := s.EffectivePermissions(ctx, "org", "dave", "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []EffectivePermission{
+		{Resource: "invoices", Action: "read", Grants: []PermissionGrant{
+			{RoleID: readerRole.Hex(), RoleIdentifier: readerRole.Hex(), Source: RoleSourceDirect},
+			{RoleID: readerRole.Hex(), RoleIdentifier: readerRole.Hex(), Source: RoleSourceGroup, Group: "finance"},
+		}},
+	}, result.Permissions)
+
+	// user without roles
+	result, err = s.EffectivePermissions(ctx, "org", "bob", "key", false)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Permissions)
+
+	// unknown user
+	_, err = s.EffectivePermissions(ctx, "org", "unknown", "key", false)
+	assert.NotNil(t, err)
+}
+
+func Test_service_PermissionHolders(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger)
+
+	ctx := context.Background()
+
+	result, err := s.PermissionHolders(ctx, "org", PermissionHoldersRequest{Resource: "invoices", Action: "read"}, "key", false)
+	assert.Nil(t, err)
+	// the auditor role inherits the permission from the reader role
+	assert.Len(t, result.Roles, 2)
+	// the team group is a member of the finance group
+	financeGrants := []PermissionGrant{{RoleID: readerRole.Hex(), RoleIdentifier: "reader", Source: RoleSourceGroup, Group: "finance"}}
+	assert.Equal(t, []PermissionHolder{
+		{ID: financeGroup.Hex(), Identifier: "finance", Grants: financeGrants},
+		{ID: teamGroup.Hex(), Identifier: "team", Grants: financeGrants},
+	}, result.Groups)
+	if assert.Len(t, result.Users, 5) {
+		assert.Equal(t, "alice", result.Users[0].Identifier)
+		assert.Equal(t, RoleSourceDirect, result.Users[0].Grants[0].Source)
+		assert.False(t, result.Users[0].Conditional)
+		assert.Equal(t, "carol", result.Users[1].Identifier)
+		assert.Equal(t, "finance", result.Users[1].Grants[0].Group)
+		assert.Equal(t, "henry", result.Users[2].Identifier)
+		assert.Equal(t, financeGrants, result.Users[2].Grants)
+		// the policies of the subject condition the grants of its roles under rbac-and-abac
+		assert.Equal(t, "ivy", result.Users[3].Identifier)
+		assert.True(t, result.Users[3].Conditional)
+		assert.Equal(t, []string{clearancePolicy.Hex()}, result.Users[3].Policies)
+		// a deny with policies only takes the permission away when they pass
+		assert.Equal(t, "mia", result.Users[4].Identifier)
+		assert.True(t, result.Users[4].Conditional)
+		assert.Equal(t, []PermissionGrant{{RoleID: offHoursRole.Hex(), RoleIdentifier: "off-hours", Source: RoleSourceDirect, Policies: []string{offHoursPolicy.Hex()}}}, result.Users[4].Denies)
+	}
+
+	// a grant conditioned by the policies of its role
+	result, err = s.PermissionHolders(ctx, "org", PermissionHoldersRequest{Resource: "invoices", Action: "approve"}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []PermissionHolder{{ID: result.Users[0].ID, Identifier: "leo", Conditional: true, Grants: []PermissionGrant{
+		{RoleID: approverRole.Hex(), RoleIdentifier: "approver", Source: RoleSourceDirect, Policies: []string{amountPolicy.Hex()}},
+	}}}, result.Users)
+
+	// the policies of a subject alone grant a check on a resource with policies of its own under permit-overrides
+	repo.algorithm = mongo_entity.PermitOverrides
+	result, err = s.PermissionHolders(ctx, "org", PermissionHoldersRequest{Resource: "payroll/2024", Action: "read"}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{corporatePolicy.Hex()}, result.Policies)
+	assert.Equal(t, []PermissionHolder{
+		{ID: result.Users[0].ID, Identifier: "ivy", Conditional: true, Policies: []string{clearancePolicy.Hex()},
+			Grants: []PermissionGrant{{Source: GrantSourcePolicy, Policies: []string{clearancePolicy.Hex()}}}},
+		{ID: result.Users[1].ID, Identifier: "olga", Conditional: true, Policies: []string{clearancePolicy.Hex()},
+			Grants: []PermissionGrant{{Source: GrantSourcePolicy, Policies: []string{clearancePolicy.Hex()}}}},
+	}, result.Users)
+
+	// nobody holds the permission
+	result, err = s.PermissionHolders(ctx, "org", PermissionHoldersRequest{Resource: "invoices", Action: "delete"}, "key", false)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Users)
+
+	// validation error
+	_, err = s.PermissionHolders(ctx, "org", PermissionHoldersRequest{Resource: "invoices"}, "key", false)
+	assert.NotNil(t, err)
+}
+
+func Test_service_CheckRelations(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(newMockRepository(), logger)
+
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		req     CheckRequest
+		allowed bool
+	}{
+		{"direct tuple", CheckRequest{Identifier: "bob", Action: "editor", Resource: "document", ObjectID: "7"}, true},
+		{"computed userset", CheckRequest{Identifier: "alice", Action: "editor", Resource: "document", ObjectID: "42"}, true},
+		{"nested computed userset", CheckRequest{Identifier: "alice", Action: "viewer", Resource: "document", ObjectID: "42"}, true},
+		{"tuple to userset with group member", CheckRequest{Identifier: "carol", Action: "viewer", Resource: "document", ObjectID: "42"}, true},
+		{"member of nested group", CheckRequest{Identifier: "henry", Action: "viewer", Resource: "document", ObjectID: "42"}, true},
+		{"relation not held", CheckRequest{Identifier: "carol", Action: "editor", Resource: "document", ObjectID: "42"}, false},
+		{"other instance", CheckRequest{Identifier: "bob", Action: "viewer", Resource: "document", ObjectID: "42"}, false},
+		{"unknown relation", CheckRequest{Identifier: "alice", Action: "delete", Resource: "document", ObjectID: "42"}, false},
+		{"cycle", CheckRequest{Identifier: "alice", Action: "viewer", Resource: "folder", ObjectID: "a"}, false},
+		{"relation denied by a role", CheckRequest{Identifier: "quinn", Action: "viewer", Resource: "document", ObjectID: "42"}, false},
+		{"relation with a failing policy", CheckRequest{Identifier: "jack", Action: "viewer", Resource: "document", ObjectID: "42"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Check(ctx, "org", tt.req, "key", false)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.allowed, result.Allowed)
+		})
+	}
+
+	// relation checks and permission checks in one batch
+	result, err := s.BatchCheck(ctx, "org", BatchCheckRequest{Checks: []CheckRequest{
+		{Identifier: "alice", Action: "viewer", Resource: "document", ObjectID: "42"},
+		{Identifier: "alice", Action: "read", Resource: "invoices"},
+		{Identifier: "bob", Action: "viewer", Resource: "document", ObjectID: "42"},
+	}}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []CheckResponse{{Allowed: true}, {Allowed: true}, {Allowed: false}}, result.Results)
+
+	// the trace of a check on an object tells whether the relation holds
+	explained, err := s.Check(ctx, "org", CheckRequest{Identifier: "alice", Action: "viewer", Resource: "document", ObjectID: "42", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, explained.Allowed)
+	assert.Equal(t, ReasonAllowed, explained.Trace.Reason)
+	if assert.NotNil(t, explained.Trace.Relation) {
+		assert.True(t, *explained.Trace.Relation)
+	}
+	explained, err = s.Check(ctx, "org", CheckRequest{Identifier: "quinn", Action: "viewer", Resource: "document", ObjectID: "42", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, explained.Allowed)
+	assert.Equal(t, ReasonPermissionDenied, explained.Trace.Reason)
+	assert.True(t, *explained.Trace.Relation)
+	explained, err = s.Check(ctx, "org", CheckRequest{Identifier: "jack", Action: "viewer", Resource: "document", ObjectID: "42", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonPolicyDenied, explained.Trace.Reason)
+	assert.Len(t, explained.Trace.Policies, 1)
+	explained, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "viewer", Resource: "document", ObjectID: "42", Explain: true}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonPermissionNotGranted, explained.Trace.Reason)
+	assert.False(t, *explained.Trace.Relation)
+
+	tuple, err := mongo_entity.ParseRelationTuple("folder:reports#viewer@group:finance#member")
+	assert.Nil(t, err)
+	assert.Equal(t, "finance", tuple.SubjectID)
+	assert.Equal(t, "member", tuple.SubjectRelation)
+	assert.Equal(t, "folder:reports#viewer@group:finance#member", tuple.String())
+	_, err = mongo_entity.ParseRelationTuple("folder:reports#viewer")
+	assert.NotNil(t, err)
+}
+
+func Test_checkDetailsForNestedGroups(t *testing.T) {
+	platformGroup := primitive.NewObjectID()
+	adminRole := primitive.NewObjectID()
+	groups := []mongo_entity.Group{
+		{ID: financeGroup, Identifier: "finance", Roles: []primitive.ObjectID{readerRole}, Groups: []primitive.ObjectID{teamGroup}},
+		{ID: teamGroup, Identifier: "team", Groups: []primitive.ObjectID{platformGroup}},
+		// a cycle in stored data must not loop forever
+		{ID: platformGroup, Identifier: "platform", Roles: []primitive.ObjectID{adminRole}, Groups: []primitive.ObjectID{financeGroup}},
+	}
+
+	details := checkDetailsFor(mongo_entity.User{Identifier: "henry", Groups: []primitive.ObjectID{teamGroup}}, groups, time.Now())
+	assert.ElementsMatch(t, []primitive.ObjectID{readerRole, adminRole}, details.Roles)
+	assert.ElementsMatch(t, []RoleGrant{
+		{RoleID: readerRole, GroupID: financeGroup, GroupIdentifier: "finance"},
+		{RoleID: adminRole, GroupID: platformGroup, GroupIdentifier: "platform"},
+	}, details.RoleGrants)
+
+	assert.True(t, mongo_entity.CreatesGroupCycle(groups[:2], teamGroup, []primitive.ObjectID{financeGroup}))
+	assert.False(t, mongo_entity.CreatesGroupCycle(groups[:2], financeGroup, []primitive.ObjectID{platformGroup}))
+}
+
+func Test_checkDetailsForTimedRoles(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	adminRole := primitive.NewObjectID()
+	user := mongo_entity.User{
+		Identifier: "henry",
+		Roles:      []primitive.ObjectID{readerRole, editorRole, adminRole},
+		Groups:     []primitive.ObjectID{financeGroup},
+		TimedRoles: []mongo_entity.TimedRole{
+			{Role: editorRole, ExpiresAt: &past},
+			{Role: adminRole, NotBefore: &future},
+		},
+	}
+	groups := []mongo_entity.Group{
+		{ID: financeGroup, Identifier: "finance", Roles: []primitive.ObjectID{adminRole},
+			TimedRoles: []mongo_entity.TimedRole{{Role: adminRole, NotBefore: &past, ExpiresAt: &future}}},
+	}
+
+	// the expired role and the role not held yet are left out, the group role is held for now
+	details := checkDetailsFor(user, groups, now)
+	assert.ElementsMatch(t, []primitive.ObjectID{readerRole, adminRole}, details.Roles)
+	assert.ElementsMatch(t, []RoleGrant{
+		{RoleID: readerRole},
+		{RoleID: adminRole, GroupID: financeGroup, GroupIdentifier: "finance"},
+	}, details.RoleGrants)
+
+	// after the group assignment expires the user holds the role directly
+	later := future.Add(time.Minute)
+	details = checkDetailsFor(user, groups, later)
+	assert.ElementsMatch(t, []RoleGrant{{RoleID: readerRole}, {RoleID: adminRole}}, details.RoleGrants)
+
+	assert.Equal(t, future, mongo_entity.NextRoleTransition([]mongo_entity.User{user}, groups, now))
+	assert.True(t, mongo_entity.NextRoleTransition([]mongo_entity.User{user}, groups, later).IsZero())
+}
+
+func Test_CachedRepository(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	cache := NewCachedRepository(repo, time.Minute)
+	s := NewService(cache, logger)
+
+	ctx := context.Background()
+
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "carol", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// the organization is loaded once and checks never reach the repository, not even to validate the API key
+	assert.Equal(t, 1, repo.organizationCalls)
+	assert.Empty(t, repo.detailCalls)
+	assert.Equal(t, 0, repo.apiKeyCalls)
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices"}, "other", false)
+	assert.IsType(t, &util.UnauthorizedError{}, err)
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Organizations)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Greater(t, stats.HitRate, 0.5)
+
+	// invalidating another organization keeps the snapshot
+	cache.Invalidate(primitive.NewObjectID().Hex())
+	_, err = cache.GetCheckDetails(ctx, "org", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, repo.organizationCalls)
+
+	cache.Invalidate(organizationID.Hex())
+	assert.Equal(t, 0, cache.Stats().Organizations)
+	_, err = cache.GetCheckDetails(ctx, "org", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, repo.organizationCalls)
+
+	// events without an organization drop every snapshot
+	cache.HandleEvent(event.Event{})
+	assert.Equal(t, 0, cache.Stats().Organizations)
+
+	// unknown user
+	_, err = cache.GetCheckDetails(ctx, "org", "unknown")
+	assert.NotNil(t, err)
+
+	// expired snapshots are reloaded
+	expiring := NewCachedRepository(repo, time.Nanosecond)
+	_, _ = expiring.GetCheckDetails(ctx, "org", "alice")
+	time.Sleep(time.Millisecond)
+	_, _ = expiring.GetCheckDetails(ctx, "org", "alice")
+	assert.Equal(t, uint64(2), expiring.Stats().Misses)
+}
+
+func Test_CachedRepositoryRevision(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	cache := NewCachedRepository(repo, time.Minute)
+	s := NewService(cache, logger)
+
+	ctx := context.Background()
+
+	result, err := s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// a write through another instance is not in the snapshot yet
+	repo.revision = 2
+	repo.bobRoles = []primitive.ObjectID{readerRole}
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices"}, "key", false)
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+
+	// the consistency token of the write reloads the snapshot
+	result, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices", Revision: "2"}, "key", false)
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, uint64(1), cache.Stats().Refreshes)
+
+	// older tokens are served from the snapshot
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices", Revision: "1"}, "key", false)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), cache.Stats().Refreshes)
+	assert.Equal(t, 2, repo.organizationCalls)
+
+	// tokens the organization has not reached, or which are malformed
+	_, err = s.Check(ctx, "org", CheckRequest{Identifier: "bob", Action: "read", Resource: "invoices", Revision: "9"}, "key", false)
+	assert.IsType(t, &util.InvalidInputError{}, err)
+	_, err = s.BatchCheck(ctx, "org", BatchCheckRequest{Revision: "two", Checks: []CheckRequest{{Identifier: "bob", Action: "read", Resource: "invoices"}}}, "key", false)
+	assert.IsType(t, &util.InvalidInputError{}, err)
+}
+
+type mockRepository struct {
+	users             map[string]CheckDetails
+	roles             map[primitive.ObjectID][]mongo_entity.Permission
+	parents           map[primitive.ObjectID][]primitive.ObjectID
+	detailCalls       map[string]int
+	organizationCalls int
+	apiKeyCalls       int
+	policies          []mongo_entity.Policy
+	rolePolicies      map[primitive.ObjectID][]primitive.ObjectID
+	resources         []mongo_entity.Resource
+	algorithm         mongo_entity.CombiningAlgorithm
+	// revision of the organization, with the roles granted to bob since revision zero.
+	revision int64
+	bobRoles []primitive.ObjectID
+}
+
+var (
+	organizationID  = primitive.NewObjectID()
+	readerRole      = primitive.NewObjectID()
+	blockedRole     = primitive.NewObjectID()
+	editorRole      = primitive.NewObjectID()
+	auditorRole     = primitive.NewObjectID()
+	financeGroup    = primitive.NewObjectID()
+	teamGroup       = primitive.NewObjectID()
+	clearancePolicy = primitive.NewObjectID()
+	networkPolicy   = primitive.NewObjectID()
+	approverRole    = primitive.NewObjectID()
+	offHoursRole    = primitive.NewObjectID()
+	payrollRole     = primitive.NewObjectID()
+	amountPolicy    = primitive.NewObjectID()
+	offHoursPolicy  = primitive.NewObjectID()
+	corporatePolicy = primitive.NewObjectID()
+	quarantineRole  = primitive.NewObjectID()
+)
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{
+		users: map[string]CheckDetails{
+			"alice": {Roles: []primitive.ObjectID{readerRole}, RoleGrants: []RoleGrant{{RoleID: readerRole}}},
+			"carol": {Roles: []primitive.ObjectID{readerRole}, RoleGrants: []RoleGrant{{RoleID: readerRole, GroupID: financeGroup, GroupIdentifier: "finance"}}},
+			"dave": {Roles: []primitive.ObjectID{readerRole}, RoleGrants: []RoleGrant{
+				{RoleID: readerRole},
+				{RoleID: readerRole, GroupID: financeGroup, GroupIdentifier: "finance"},
+			}},
+			"bob":   {},
+			"gina":  {Roles: []primitive.ObjectID{auditorRole}, RoleGrants: []RoleGrant{{RoleID: auditorRole}}},
+			"frank": {Roles: []primitive.ObjectID{editorRole}, RoleGrants: []RoleGrant{{RoleID: editorRole}}},
+			"erin":  {Roles: []primitive.ObjectID{readerRole, blockedRole}, RoleGrants: []RoleGrant{{RoleID: readerRole}, {RoleID: blockedRole}}},
+			"ivy": {Roles: []primitive.ObjectID{readerRole}, RoleGrants: []RoleGrant{{RoleID: readerRole}},
+				Policies: []primitive.ObjectID{clearancePolicy}, UserProperties: map[string]interface{}{"clearance": 3}},
+			"jack": {Roles: []primitive.ObjectID{readerRole}, RoleGrants: []RoleGrant{{RoleID: readerRole}},
+				Policies: []primitive.ObjectID{clearancePolicy}, UserProperties: map[string]interface{}{"clearance": 1}},
+			"kim": {Roles: []primitive.ObjectID{readerRole}, RoleGrants: []RoleGrant{{RoleID: readerRole}},
+				Policies: []primitive.ObjectID{networkPolicy}, UserProperties: map[string]interface{}{"department": "finance"}},
+			"leo":  {Roles: []primitive.ObjectID{approverRole}, RoleGrants: []RoleGrant{{RoleID: approverRole}}},
+			"olga": {Policies: []primitive.ObjectID{clearancePolicy}, UserProperties: map[string]interface{}{"clearance": 3}},
+			"pat": {Roles: []primitive.ObjectID{blockedRole}, RoleGrants: []RoleGrant{{RoleID: blockedRole}},
+				Policies: []primitive.ObjectID{clearancePolicy}, UserProperties: map[string]interface{}{"clearance": 3}},
+			"nina":  {Roles: []primitive.ObjectID{payrollRole}, RoleGrants: []RoleGrant{{RoleID: payrollRole}}},
+			"mia":   {Roles: []primitive.ObjectID{readerRole, offHoursRole}, RoleGrants: []RoleGrant{{RoleID: readerRole}, {RoleID: offHoursRole}}},
+			"henry": {},
+			"quinn": {Roles: []primitive.ObjectID{quarantineRole}, RoleGrants: []RoleGrant{{RoleID: quarantineRole}}},
+		},
+		policies: []mongo_entity.Policy{
+			{ID: clearancePolicy, Identifier: "clearance", ActiveVersion: "v1", Language: "cel", PolicyContents: []mongo_entity.PolicyContent{
+				{Version: "v1", Policy: "user_properties.clearance >= 2"},
+			}},
+			{ID: networkPolicy, Identifier: "network", ActiveVersion: "v1", Language: "cel", PolicyContents: []mongo_entity.PolicyContent{
+				{Version: "v1", Policy: `context.ip.startsWith("10.") && resource.department == user_properties.department`},
+			}},
+			{ID: amountPolicy, Identifier: "amount", ActiveVersion: "v1", Language: "cel", PolicyContents: []mongo_entity.PolicyContent{
+				{Version: "v1", Policy: "context.amount < 1000"},
+			}},
+			{ID: offHoursPolicy, Identifier: "off-hours", ActiveVersion: "v1", Language: "cel", PolicyContents: []mongo_entity.PolicyContent{
+				{Version: "v1", Policy: "context.hour >= 18"},
+			}},
+			{ID: corporatePolicy, Identifier: "corporate", ActiveVersion: "v1", Language: "cel", PolicyContents: []mongo_entity.PolicyContent{
+				{Version: "v1", Policy: `context.ip.startsWith("10.")`},
+			}},
+		},
+		rolePolicies: map[primitive.ObjectID][]primitive.ObjectID{
+			approverRole: {amountPolicy},
+		},
+		resources: []mongo_entity.Resource{
+			{Identifier: "payroll/*", Policies: []primitive.ObjectID{corporatePolicy}},
+		},
+		roles: map[primitive.ObjectID][]mongo_entity.Permission{
+			readerRole:     {{Action: "read", Resource: "invoices"}},
+			blockedRole:    {{Action: "read", Resource: "invoices", Effect: mongo_entity.DenyEffect}},
+			auditorRole:    {{Action: "read", Resource: "reports"}},
+			approverRole:   {{Action: "approve", Resource: "invoices"}},
+			payrollRole:    {{Action: "read", Resource: "payroll/*"}},
+			offHoursRole:   {{Action: "read", Resource: "invoices", Effect: mongo_entity.DenyEffect, Policies: []primitive.ObjectID{offHoursPolicy}}},
+			quarantineRole: {{Action: "*", Resource: "document", Effect: mongo_entity.DenyEffect}},
+			editorRole: {
+				{Action: "*", Resource: "projects/*/documents"},
+				{Action: "invoices:*", Resource: "invoices"},
+				{Action: "invoices:delete", Resource: "invoices", Effect: mongo_entity.DenyEffect},
+			},
+		},
+		parents: map[primitive.ObjectID][]primitive.ObjectID{
+			auditorRole: {readerRole},
+		},
+		detailCalls: map[string]int{},
+	}
+}
+
+func (m *mockRepository) ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error) {
+	m.apiKeyCalls++
+	return apiKey == "key", nil
+}
+
+func (m *mockRepository) GetRoles(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) ([]mongo_entity.Role, error) {
+	var roles []mongo_entity.Role
+	for id, permissions := range m.roles {
+		roles = append(roles, mongo_entity.Role{ID: id, Identifier: id.Hex(), Permissions: permissions, ParentRoles: m.parents[id], Policies: m.rolePolicies[id]})
+	}
+	return withInheritedPermissions(roles, role_ids), nil
+}
+
+func (m *mockRepository) GetRolePermissions(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) (*[]mongo_entity.Permission, error) {
+	roles, _ := m.GetRoles(ctx, org_identifier, role_ids)
+	var permissions []mongo_entity.Permission
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
+	}
+	return &permissions, nil
+}
+
+func (m *mockRepository) GetCheckDetails(ctx context.Context, org_identifier string, identifier string) (CheckDetails, error) {
+	m.detailCalls[identifier]++
+	details, ok := m.users[identifier]
+	if !ok {
+		return CheckDetails{}, &util.NotFoundError{Path: "User"}
+	}
+	return details, nil
+}
+
+func (m *mockRepository) GetOrganization(ctx context.Context, org_identifier string) (*mongo_entity.Organization, error) {
+	m.organizationCalls++
+	return &mongo_entity.Organization{
+		ID:         organizationID,
+		Identifier: org_identifier,
+		API_KEY:    "key",
+		Revision:   m.revision,
+		Users: []mongo_entity.User{
+			{ID: primitive.NewObjectID(), Identifier: "alice", Roles: []primitive.ObjectID{readerRole}},
+			{ID: primitive.NewObjectID(), Identifier: "bob", Roles: m.bobRoles},
+			{ID: primitive.NewObjectID(), Identifier: "carol", Groups: []primitive.ObjectID{financeGroup}},
+			{ID: primitive.NewObjectID(), Identifier: "erin", Roles: []primitive.ObjectID{readerRole, blockedRole}},
+			{ID: primitive.NewObjectID(), Identifier: "henry", Groups: []primitive.ObjectID{teamGroup}},
+			{ID: primitive.NewObjectID(), Identifier: "jack"},
+			{ID: primitive.NewObjectID(), Identifier: "quinn", Roles: []primitive.ObjectID{quarantineRole}},
+			{ID: primitive.NewObjectID(), Identifier: "ivy", Roles: []primitive.ObjectID{readerRole}, Policies: []primitive.ObjectID{clearancePolicy}},
+			{ID: primitive.NewObjectID(), Identifier: "mia", Roles: []primitive.ObjectID{readerRole, offHoursRole}},
+			{ID: primitive.NewObjectID(), Identifier: "leo", Roles: []primitive.ObjectID{approverRole}},
+			{ID: primitive.NewObjectID(), Identifier: "olga", Policies: []primitive.ObjectID{clearancePolicy}},
+		},
+		Groups: []mongo_entity.Group{
+			{ID: financeGroup, Identifier: "finance", Roles: []primitive.ObjectID{readerRole}, Groups: []primitive.ObjectID{teamGroup}},
+			{ID: teamGroup, Identifier: "team"},
+		},
+		Roles: []mongo_entity.Role{
+			{ID: readerRole, Identifier: "reader", Permissions: m.roles[readerRole]},
+			{ID: blockedRole, Identifier: "blocked", Permissions: m.roles[blockedRole]},
+			{ID: auditorRole, Identifier: "auditor", Permissions: m.roles[auditorRole], ParentRoles: m.parents[auditorRole]},
+			{ID: approverRole, Identifier: "approver", Permissions: m.roles[approverRole], Policies: m.rolePolicies[approverRole]},
+			{ID: offHoursRole, Identifier: "off-hours", Permissions: m.roles[offHoursRole]},
+		},
+		Resources: []mongo_entity.Resource{
+			{Identifier: "folder", Relations: []mongo_entity.RelationDefinition{{Name: "viewer"}}},
+			{Identifier: "document", Relations: []mongo_entity.RelationDefinition{
+				{Name: "parent"},
+				{Name: "owner"},
+				{Name: "editor", Rewrite: []mongo_entity.Userset{{This: true}, {ComputedUserset: "owner"}}},
+				{Name: "viewer", Rewrite: []mongo_entity.Userset{
+					{This: true},
+					{ComputedUserset: "editor"},
+					{TupleToUserset: &mongo_entity.TupleToUserset{Tupleset: "parent", ComputedUserset: "viewer"}},
+				}},
+			}},
+		},
+		Relations: []mongo_entity.RelationTuple{
+			{ObjectType: "document", ObjectID: "42", Relation: "owner", SubjectType: "user", SubjectID: "alice"},
+			{ObjectType: "document", ObjectID: "42", Relation: "parent", SubjectType: "folder", SubjectID: "reports"},
+			{ObjectType: "folder", ObjectID: "reports", Relation: "viewer", SubjectType: "group", SubjectID: "finance", SubjectRelation: "member"},
+			{ObjectType: "document", ObjectID: "7", Relation: "editor", SubjectType: "user", SubjectID: "bob"},
+			{ObjectType: "document", ObjectID: "42", Relation: "viewer", SubjectType: "user", SubjectID: "jack"},
+			{ObjectType: "document", ObjectID: "42", Relation: "viewer", SubjectType: "user", SubjectID: "quinn"},
+			// a cycle between subject sets must not loop forever
+			{ObjectType: "folder", ObjectID: "a", Relation: "viewer", SubjectType: "folder", SubjectID: "b", SubjectRelation: "viewer"},
+			{ObjectType: "folder", ObjectID: "b", Relation: "viewer", SubjectType: "folder", SubjectID: "a", SubjectRelation: "viewer"},
+		},
+	}, nil
+}
+
+func (m *mockRepository) EnsureRevision(ctx context.Context, org_identifier string, revision int64) error {
+	if m.revision < revision {
+		return &util.InvalidInputError{Path: "revision"}
+	}
+	return nil
+}
+
+func (m *mockRepository) GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) ([]ActivePolicyContent, error) {
+	return activePolicyContents(organizationID, m.policies, policy_ids), nil
+}
+
+func (m *mockRepository) GetResourcePolicies(ctx context.Context, org_identifier string, resource string) ([]primitive.ObjectID, error) {
+	return resourcePolicies(m.resources, resource), nil
+}
+
+func (m *mockRepository) GetCombiningAlgorithm(ctx context.Context, org_identifier string) (mongo_entity.CombiningAlgorithm, error) {
+	return mongo_entity.Organization{CombiningAlgorithm: m.algorithm}.Algorithm(), nil
+}
@@ -0,0 +1,147 @@
+package check
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/engine"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.uber.org/zap"
+)
+
+// Shadows counts how often the shadow versions of policies agree with their active versions in checks.
+// Only the latest pair of versions of each policy is counted, and a change to the policy starts over.
+// Counts are kept in memory, for the checks served by this instance since it started.
+type Shadows struct {
+	mu      sync.Mutex
+	counts  map[shadowKey]*ShadowStats
+	started time.Time
+}
+
+type shadowKey struct {
+	organization string
+	policy       string
+}
+
+// ShadowStats counts the evaluations of a shadow version against one active version of a policy.
+type ShadowStats struct {
+	PolicyID      string `json:"policy_id"`
+	Identifier    string `json:"identifier"`
+	ActiveVersion string `json:"active_version"`
+	ShadowVersion string `json:"shadow_version"`
+	Evaluations   uint64 `json:"evaluations"`
+	Disagreements uint64 `json:"disagreements"`
+	// Allowed counts the disagreements the shadow version would allow, and Denied those it would deny.
+	Allowed uint64 `json:"allowed"`
+	Denied  uint64 `json:"denied"`
+}
+
+// ShadowsResponse holds the counts of a single check server instance. Instances do not share their counts,
+// so each instance behind a load balancer reports the checks it served since it started.
+type ShadowsResponse struct {
+	// Instance is the host name of the instance the counts come from, and Since is when it started counting.
+	Instance string        `json:"instance"`
+	Since    time.Time     `json:"since"`
+	Shadows  []ShadowStats `json:"shadows"`
+}
+
+func NewShadows() *Shadows {
+
+	return &Shadows{counts: make(map[shadowKey]*ShadowStats), started: time.Now()}
+}
+
+// record counts an evaluation of the shadow version of the policy, along with its result and the active one.
+// Another pair of versions replaces the counts of the previous one.
+func (s *Shadows) record(org_identifier string, policy ActivePolicyContent, active bool, shadow bool) {
+
+	key := shadowKey{organization: org_identifier, policy: policy.ID.Hex()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.counts[key]
+	if !ok || stats.ActiveVersion != policy.Version || stats.ShadowVersion != policy.Shadow.Version {
+		stats = &ShadowStats{PolicyID: key.policy, Identifier: policy.Identifier, ActiveVersion: policy.Version, ShadowVersion: policy.Shadow.Version}
+		s.counts[key] = stats
+	}
+	stats.Evaluations++
+	if active != shadow {
+		stats.Disagreements++
+		if shadow {
+			stats.Allowed++
+		} else {
+			stats.Denied++
+		}
+	}
+}
+
+// forget drops the counts of the policy.
+func (s *Shadows) forget(policy string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.counts {
+		if key.policy == policy {
+			delete(s.counts, key)
+		}
+	}
+}
+
+// Stats returns the counts of the shadow versions evaluated in the checks of the organization.
+func (s *Shadows) Stats(org_identifier string) []ShadowStats {
+
+	s.mu.Lock()
+	stats := []ShadowStats{}
+	for key, count := range s.counts {
+		if key.organization == org_identifier {
+			stats = append(stats, *count)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Identifier < stats[j].Identifier
+	})
+	return stats
+}
+
+// Shadows reports how the shadow versions of the policies of the organization compared to their active versions
+// in the checks this instance served so far.
+func (s service) Shadows(ctx context.Context, org_identifier string, apiKey string, skipValidation bool) (ShadowsResponse, error) {
+
+	if !skipValidation {
+		validated, _ := s.ValidateAPIKey(ctx, org_identifier, apiKey)
+		if !validated {
+			s.logger.Error("Error while validating api key for shadow policy stats")
+			return ShadowsResponse{}, &util.UnauthorizedError{}
+		}
+	}
+	instance, _ := os.Hostname()
+	return ShadowsResponse{Instance: instance, Since: s.shadows.started, Shadows: s.shadows.Stats(org_identifier)}, nil
+}
+
+// evaluateShadow evaluates the shadow version of the policy against the same input as its active version, and
+// reports when their results disagree. The shadow result never decides a check.
+func (s service) evaluateShadow(org_identifier string, policy ActivePolicyContent, input engine.Input, active bool) {
+
+	shadow := false
+	compiled, err := s.policies.Compile(policyKey(policy.OrganizationID.Hex(), policy.ID.Hex())+"@shadow", engine.LanguageOf(policy.Shadow.Language), policy.Shadow.Policy)
+	if err == nil {
+		shadow, err = compiled.Evaluate(input)
+	}
+	if err != nil {
+		s.logger.Debug("Error while evaluating shadow policy.", zap.String("policy", policy.Identifier), zap.String("version", policy.Shadow.Version), zap.Error(err))
+		shadow = false
+	}
+	s.shadows.record(org_identifier, policy, active, shadow)
+	if active != shadow {
+		s.logger.Info("Shadow policy version disagrees with the active version.",
+			zap.String("organization", org_identifier),
+			zap.String("policy", policy.Identifier),
+			zap.String("active_version", policy.Version),
+			zap.String("shadow_version", policy.Shadow.Version),
+			zap.String("subject", input.Subject),
+			zap.Bool("active_result", active),
+			zap.Bool("shadow_result", shadow))
+	}
+}
@@ -0,0 +1,165 @@
+package check
+
+import (
+	"context"
+
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons reported in a decision trace.
+const (
+	ReasonAllowed = "allowed"
+	// ReasonUserNotFound explains a batch item denied for an unknown user. A single check fails instead.
+	ReasonUserNotFound         = "user_not_found"
+	ReasonNoRoles              = "no_roles"
+	ReasonPermissionNotGranted = "permission_not_granted"
+	ReasonPermissionDenied     = "permission_denied"
+	ReasonPolicyDenied         = "policy_denied"
+)
+
+// Scopes of the policies reported in a decision trace.
+const (
+	PolicyScopeSubject    = "subject"
+	PolicyScopeResource   = "resource"
+	PolicyScopePermission = "permission"
+)
+
+// Role sources reported in a decision trace.
+const (
+	RoleSourceDirect = "direct"
+	RoleSourceGroup  = "group"
+)
+
+type CheckTrace struct {
+	Reason string `json:"reason"`
+	// Algorithm combined the decision of the roles with the policies of the subject.
+	Algorithm string        `json:"algorithm"`
+	Roles     []RoleTrace   `json:"roles"`
+	Policies  []PolicyTrace `json:"policies"`
+	// Relation tells whether the relation tuples grant a check on an object. Other checks leave it out.
+	Relation *bool `json:"relation,omitempty"`
+}
+
+type RoleTrace struct {
+	ID         string `json:"id"`
+	Identifier string `json:"identifier"`
+	Source     string `json:"source"`
+	Group      string `json:"group,omitempty"`
+	Granted    bool   `json:"granted"`
+	Denied     bool   `json:"denied,omitempty"`
+}
+
+type PolicyTrace struct {
+	ID         string `json:"id"`
+	Identifier string `json:"identifier"`
+	Version    string `json:"version"`
+	Language   string `json:"language,omitempty"`
+	// Scope tells whether the policy is assigned to the subject, its resource or a permission of the check.
+	Scope  string `json:"scope,omitempty"`
+	Result bool   `json:"result"`
+}
+
+// explain evaluates a check like Check does, but records every role and policy that took part in the decision.
+func (s service) explain(ctx context.Context, org_identifier string, req CheckRequest, skipValidation bool) (CheckResponse, error) {
+
+	trace := &CheckTrace{Roles: []RoleTrace{}, Policies: []PolicyTrace{}}
+	checkDetails, err := s.repo.GetCheckDetails(ctx, org_identifier, req.Identifier)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+
+	var roles []mongo_entity.Role
+	subject := &subjectDetails{details: checkDetails}
+	if len(checkDetails.Roles) > 0 {
+		roles, err = s.repo.GetRoles(ctx, org_identifier, checkDetails.Roles)
+		if err != nil {
+			return CheckResponse{}, err
+		}
+		for _, role := range roles {
+			subject.permissions = append(subject.permissions, role.Permissions...)
+		}
+	}
+	conditions, err := s.evaluateConditions(ctx, org_identifier, subject, req, skipValidation)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+
+	rbac := notApplicable
+	if len(checkDetails.Roles) > 0 {
+		granted := make(map[primitive.ObjectID]bool)
+		denied := make(map[primitive.ObjectID]bool)
+		identifiers := make(map[primitive.ObjectID]string)
+		for _, role := range roles {
+			identifiers[role.ID] = role.Identifier
+			for _, permission := range role.Permissions {
+				if permission.Matches(req.Resource, req.Action) && conditions.hold(permission.Policies) {
+					if permission.Denies() {
+						denied[role.ID] = true
+					} else {
+						granted[role.ID] = true
+					}
+				}
+			}
+		}
+		for _, grant := range checkDetails.RoleGrants {
+			roleTrace := RoleTrace{
+				ID:         grant.RoleID.Hex(),
+				Identifier: identifiers[grant.RoleID],
+				Source:     RoleSourceDirect,
+				Granted:    granted[grant.RoleID],
+				Denied:     denied[grant.RoleID],
+			}
+			if !grant.GroupID.IsZero() {
+				roleTrace.Source = RoleSourceGroup
+				roleTrace.Group = grant.GroupIdentifier
+			}
+			trace.Roles = append(trace.Roles, roleTrace)
+			if roleTrace.Denied {
+				rbac = deny
+			} else if roleTrace.Granted && rbac == notApplicable {
+				rbac = permit
+			}
+		}
+	}
+
+	if req.ObjectID != "" {
+		graph, err := s.loadRelationGraph(ctx, org_identifier)
+		if err != nil {
+			return CheckResponse{}, err
+		}
+		related := graph.check(req.Identifier, req.Resource, req.ObjectID, req.Action)
+		trace.Relation = &related
+		rbac = relate(rbac, related)
+	}
+
+	algorithm, err := s.repo.GetCombiningAlgorithm(ctx, org_identifier)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	trace.Algorithm = string(algorithm)
+	allowed, policies, err := combine(algorithm, rbac, rbac == permit || subject.targeted(req, conditions), func() ([]PolicyTrace, error) {
+		return s.subjectPolicies(ctx, org_identifier, subject, req, skipValidation)
+	})
+	if err != nil {
+		return CheckResponse{}, err
+	}
+	trace.Policies = append(policies, conditions.traces...)
+	allowed = allowed && conditions.resourcePassed
+
+	switch {
+	case allowed:
+		trace.Reason = ReasonAllowed
+	case !conditions.resourcePassed:
+		trace.Reason = ReasonPolicyDenied
+	case len(checkDetails.Roles) == 0 && req.ObjectID == "":
+		trace.Reason = ReasonNoRoles
+	case rbac == deny:
+		trace.Reason = ReasonPermissionDenied
+	case rbac == notApplicable:
+		trace.Reason = ReasonPermissionNotGranted
+	default:
+		trace.Reason = ReasonPolicyDenied
+	}
+	return CheckResponse{Allowed: allowed, Trace: trace}, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"io/ioutil"
 	"reflect"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"gopkg.in/yaml.v2"
@@ -15,6 +16,23 @@ type Config struct {
 	Server struct {
 		Endpoint string `yaml:"endpoint" env:"endpoint"`
 	} `yaml:"server"`
+	CheckServer struct {
+		Endpoint     string `yaml:"endpoint" env:"endpoint"`
+		GrpcEndpoint string `yaml:"grpc_endpoint" env:"grpc_endpoint"`
+	} `yaml:"check_server"`
+	CheckCache struct {
+		Enabled      bool          `yaml:"enabled" env:"enabled"`
+		TTL          time.Duration `yaml:"ttl" env:"ttl"`
+		Watch        bool          `yaml:"watch" env:"watch"`
+		PollInterval time.Duration `yaml:"poll_interval" env:"poll_interval"`
+	} `yaml:"check_cache"`
+	RoleSweeper struct {
+		// Enabled runs the sweeper in this instance. Enable it in one instance only, as every instance
+		// would otherwise remove the same assignments.
+		Enabled bool `yaml:"enabled" env:"enabled"`
+		// Interval between removals of expired role assignments.
+		Interval time.Duration `yaml:"interval" env:"interval"`
+	} `yaml:"role_sweeper"`
 	Auth struct {
 		JWKS string `yaml:"jwks" env:"JWKS"`
 	} `yaml:"auth"`
@@ -40,10 +58,31 @@ type Config struct {
 		Groups        []string `yaml:"groups"`
 		Resources     []string `yaml:"resources"`
 		Polices       []string `yaml:"policies"`
+		Relations     []string `yaml:"relations"`
 	} `yaml:"system_resources"`
 	APIEndpoints []APIEndpoint `yaml:"endpoints"`
 }
 
+// SystemResource is a resource of the root organization which guards the endpoints of cronuseo itself.
+type SystemResource struct {
+	Identifier string
+	Actions    []string
+}
+
+// SystemResourceList lists every system resource with its actions. The admin role is granted all of them.
+func (c Config) SystemResourceList() []SystemResource {
+
+	return []SystemResource{
+		{Identifier: "organizations", Actions: c.SystemResources.Organizations},
+		{Identifier: "users", Actions: c.SystemResources.Users},
+		{Identifier: "groups", Actions: c.SystemResources.Groups},
+		{Identifier: "roles", Actions: c.SystemResources.Roles},
+		{Identifier: "resources", Actions: c.SystemResources.Resources},
+		{Identifier: "policies", Actions: c.SystemResources.Polices},
+		{Identifier: "relations", Actions: c.SystemResources.Relations},
+	}
+}
+
 type APIEndpoint struct {
 	Path     string         `yaml:"path"`
 	Methods  []MethodDetail `yaml:"methods"`
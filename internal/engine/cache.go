@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+)
+
+// Cache keeps compiled policies by key, such as the policy id, and compiles a policy again
+// only when the language or the content under its key change. Keys which stay the same across
+// versions keep one entry per policy; Evict drops the entries of policies which are gone.
+type Cache struct {
+	registry *Registry
+	mu       sync.RWMutex
+	entries  map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	language Language
+	policy   string
+	compiled CompiledPolicy
+	err      error
+}
+
+func NewCache(registry *Registry) *Cache {
+
+	return &Cache{registry: registry, entries: make(map[string]cacheEntry)}
+}
+
+// Compile returns the compiled policy under the key. A policy which does not compile keeps its
+// error, so it is not compiled again for every evaluation.
+func (c *Cache) Compile(key string, language Language, policy string) (CompiledPolicy, error) {
+
+	language = LanguageOf(string(language))
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && entry.language == language && entry.policy == policy {
+		return entry.compiled, entry.err
+	}
+
+	compiled, err := c.registry.Compile(language, policy)
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{language: language, policy: policy, compiled: compiled, err: err}
+	c.mu.Unlock()
+	return compiled, err
+}
+
+// Evict drops the policies kept under keys starting with the prefix. An empty prefix drops every policy.
+func (c *Cache) Evict(prefix string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns how many policies are kept.
+func (c *Cache) Len() int {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// CELLanguage is the Common Expression Language. A CEL policy is a boolean expression over
+// subject, user_properties, context, resource and now.
+const CELLanguage Language = "cel"
+
+// CEL returns the engine of Common Expression Language policies.
+func CEL() PolicyEngine {
+
+	env, err := cel.NewEnv(
+		cel.Variable("subject", cel.StringType),
+		cel.Variable("user_properties", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("context", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("now", cel.TimestampType),
+		cel.CrossTypeNumericComparisons(true),
+		cel.DefaultUTCTimeZone(true),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("invalid CEL environment: %v", err))
+	}
+	return celEngine{env: env}
+}
+
+type celEngine struct {
+	env *cel.Env
+}
+
+type celPolicy struct {
+	program   cel.Program
+	canonical string
+}
+
+func (celEngine) Language() Language {
+
+	return CELLanguage
+}
+
+func (e celEngine) Compile(policy string) (CompiledPolicy, error) {
+
+	ast, issues := e.env.Compile(policy)
+	if issues != nil && issues.Err() != nil {
+		first := issues.Errors()[0]
+		return nil, &SyntaxError{Line: first.Location.Line(), Column: first.Location.Column() + 1, Message: first.Message}
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("CEL policy must be a boolean expression, got %v", ast.OutputType())
+	}
+	program, err := e.env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := cel.AstToString(ast)
+	if err != nil {
+		canonical = policy
+	}
+	return celPolicy{program: program, canonical: canonical}, nil
+}
+
+func (e celEngine) Validate(policy string) error {
+
+	_, err := e.Compile(policy)
+	return err
+}
+
+func (e celEngine) Evaluate(policy string, input Input) (bool, error) {
+
+	compiled, err := e.Compile(policy)
+	if err != nil {
+		return false, err
+	}
+	return compiled.Evaluate(input)
+}
+
+func (p celPolicy) Evaluate(input Input) (bool, error) {
+
+	activation := map[string]interface{}{
+		"subject": input.Subject,
+		"now":     input.Time.UTC(),
+	}
+	for name, attributes := range map[string]map[string]interface{}{
+		"user_properties": input.UserProperties,
+		"context":         input.Context,
+		"resource":        input.Resource,
+	} {
+		document, err := jsonDocument(attributes)
+		if err != nil {
+			return false, err
+		}
+		activation[name] = document
+	}
+	out, _, err := p.program.Eval(activation)
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL policy evaluated to %v, not a boolean", out.Value())
+	}
+	return result, nil
+}
+
+func (p celPolicy) Hash() string {
+
+	return hash(CELLanguage, p.canonical)
+}
+
+// jsonDocument turns attributes into plain JSON values, whatever types they were decoded into.
+func jsonDocument(attributes map[string]interface{}) (map[string]interface{}, error) {
+
+	document := map[string]interface{}{}
+	if len(attributes) == 0 {
+		return document, nil
+	}
+	raw, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Language names the policy language a policy is written in.
+type Language string
+
+// TunnelLanguage is the policy tunnel language, which policies without a language are written in.
+const TunnelLanguage Language = "tunnel"
+
+// LanguageOf returns the language of a stored policy. Policies stored without a language are tunnel policies.
+func LanguageOf(language string) Language {
+
+	if language == "" {
+		return TunnelLanguage
+	}
+	return Language(language)
+}
+
+// Input is the document a policy is evaluated against.
+type Input struct {
+	Subject string `json:"subject"`
+	// Action and ResourceIdentifier are what the check asks for, when the policy is evaluated in a check.
+	Action             string                 `json:"action,omitempty"`
+	ResourceIdentifier string                 `json:"resource_identifier,omitempty"`
+	UserProperties     map[string]interface{} `json:"user_properties"`
+	// Context holds the attributes of the request, such as the address or the device it comes from.
+	Context map[string]interface{} `json:"context"`
+	// Resource holds the attributes of the resource the request is about.
+	Resource map[string]interface{} `json:"resource"`
+	Time     time.Time              `json:"time"`
+}
+
+// NewInput builds the input of a policy evaluated now, for a subject with the attributes of a request.
+// Attributes of the resource are taken from the "resource" attribute of the request.
+func NewInput(subject string, userProperties map[string]interface{}, context map[string]interface{}) Input {
+
+	input := Input{Subject: subject, UserProperties: userProperties, Context: context, Time: time.Now()}
+	if resource, ok := context["resource"].(map[string]interface{}); ok {
+		input.Resource = resource
+	}
+	return input
+}
+
+// Attributes merges the user properties with the request context into one document. The stored
+// user properties win over request attributes of the same name.
+func (i Input) Attributes() map[string]interface{} {
+
+	attributes := make(map[string]interface{}, len(i.Context)+len(i.UserProperties))
+	for name, value := range i.Context {
+		attributes[name] = value
+	}
+	for name, value := range i.UserProperties {
+		attributes[name] = value
+	}
+	return attributes
+}
+
+// PolicyEngine compiles and evaluates the policies of one policy language.
+type PolicyEngine interface {
+	Language() Language
+	// Compile parses the policy into a form which can be evaluated many times.
+	Compile(policy string) (CompiledPolicy, error)
+	// Validate reports why the policy can not be compiled, if it can not.
+	Validate(policy string) error
+	// Evaluate compiles the policy and evaluates it against the input.
+	Evaluate(policy string, input Input) (bool, error)
+}
+
+type CompiledPolicy interface {
+	Evaluate(input Input) (bool, error)
+	// Hash identifies the compiled form of the policy, so policies which only differ in layout hash alike.
+	Hash() string
+}
+
+// SyntaxError is why a policy can not be compiled, along with where in the policy. Line and column are
+// 1-based, and zero when the error has no position.
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// syntaxErrorAt builds the syntax error of the byte at an offset in the policy.
+func syntaxErrorAt(policy string, offset int, message string) *SyntaxError {
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(policy) {
+		offset = len(policy)
+	}
+	before := policy[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return &SyntaxError{Line: line, Column: column, Message: message}
+}
+
+// hash identifies the canonical form of a policy of the language.
+func hash(language Language, canonical string) string {
+
+	sum := sha256.Sum256([]byte(string(language) + "\x00" + canonical))
+	return hex.EncodeToString(sum[:])
+}
+
+// Registry dispatches policies to the engine of their language.
+type Registry struct {
+	engines map[Language]PolicyEngine
+}
+
+func NewRegistry(engines ...PolicyEngine) *Registry {
+
+	registry := &Registry{engines: make(map[Language]PolicyEngine)}
+	for _, engine := range engines {
+		registry.engines[engine.Language()] = engine
+	}
+	return registry
+}
+
+var defaultRegistry = NewRegistry(Tunnel(), CEL(), Rego())
+
+// Default returns the registry of every built-in policy engine.
+func Default() *Registry {
+
+	return defaultRegistry
+}
+
+// Get returns the engine of the language. An empty language is the tunnel language.
+func (r *Registry) Get(language Language) (PolicyEngine, error) {
+
+	engine, ok := r.engines[LanguageOf(string(language))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported policy language %q", language)
+	}
+	return engine, nil
+}
+
+// Languages lists the languages of the registered engines.
+func (r *Registry) Languages() []Language {
+
+	languages := make([]Language, 0, len(r.engines))
+	for language := range r.engines {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i] < languages[j] })
+	return languages
+}
+
+func (r *Registry) Compile(language Language, policy string) (CompiledPolicy, error) {
+
+	engine, err := r.Get(language)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Compile(policy)
+}
+
+func (r *Registry) Validate(language Language, policy string) error {
+
+	engine, err := r.Get(language)
+	if err != nil {
+		return err
+	}
+	return engine.Validate(policy)
+}
+
+func (r *Registry) Evaluate(language Language, policy string, input Input) (bool, error) {
+
+	engine, err := r.Get(language)
+	if err != nil {
+		return false, err
+	}
+	return engine.Evaluate(policy, input)
+}
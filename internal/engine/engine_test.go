@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Registry(t *testing.T) {
+	registry := Default()
+
+	policy := `[[{"attribute":{"name":"department","type":"string"},"operator":"equal","value":["finance"]}]]`
+	input := Input{Subject: "alice", UserProperties: map[string]interface{}{"department": "finance"}}
+
+	// policies without a language are tunnel policies
+	result, err := registry.Evaluate("", policy, input)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	compiled, err := registry.Compile(TunnelLanguage, policy)
+	assert.Nil(t, err)
+	result, err = compiled.Evaluate(Input{UserProperties: map[string]interface{}{"department": "sales"}})
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	// request context is merged with the user properties, which win over it
+	result, err = registry.Evaluate(TunnelLanguage, policy, Input{Context: map[string]interface{}{"department": "finance"}})
+	assert.Nil(t, err)
+	assert.True(t, result)
+	result, err = registry.Evaluate(TunnelLanguage, policy, Input{
+		UserProperties: map[string]interface{}{"department": "sales"},
+		Context:        map[string]interface{}{"department": "finance"},
+	})
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	assert.NotNil(t, registry.Validate(TunnelLanguage, "{not a policy"))
+	_, err = registry.Evaluate("cobol", policy, input)
+	assert.NotNil(t, err)
+	assert.Contains(t, registry.Languages(), TunnelLanguage)
+}
+
+func Test_CEL(t *testing.T) {
+	registry := Default()
+
+	input := Input{
+		Subject:        "alice",
+		UserProperties: map[string]interface{}{"department": "finance", "level": 3},
+		Context:        map[string]interface{}{"ip": "10.0.0.7"},
+		Resource:       map[string]interface{}{"owner": "alice"},
+		Time:           time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	result, err := registry.Evaluate(CELLanguage, `user_properties.department == "finance" && user_properties.level > 2`, input)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	result, err = registry.Evaluate(CELLanguage, `resource.owner == subject && context.ip.startsWith("10.") && now.getHours() < 9`, input)
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	// attributes which are not there are errors, so the policy does not pass
+	_, err = registry.Evaluate(CELLanguage, `context.device == "laptop"`, input)
+	assert.NotNil(t, err)
+
+	// not a boolean expression
+	assert.NotNil(t, registry.Validate(CELLanguage, `user_properties.level + 1`))
+	assert.NotNil(t, registry.Validate(CELLanguage, `user_properties.level >`))
+}
+
+func Test_Cache(t *testing.T) {
+	cache := NewCache(Default())
+
+	first, err := cache.Compile("policy@v1", CELLanguage, `subject == "alice"`)
+	assert.Nil(t, err)
+	second, err := cache.Compile("policy@v1", CELLanguage, `subject == "alice"`)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	// changed content under the same key
+	changed, err := cache.Compile("policy@v1", CELLanguage, `subject == "bob"`)
+	assert.Nil(t, err)
+	result, _ := changed.Evaluate(Input{Subject: "bob"})
+	assert.True(t, result)
+	assert.Equal(t, 1, cache.Len())
+
+	_, err = cache.Compile("policy@v2", CELLanguage, `subject ==`)
+	assert.NotNil(t, err)
+
+	// evicting a policy drops every key under it
+	_, err = cache.Compile("other", CELLanguage, `subject == "alice"`)
+	assert.Nil(t, err)
+	cache.Evict("policy")
+	assert.Equal(t, 1, cache.Len())
+	cache.Evict("")
+	assert.Equal(t, 0, cache.Len())
+}
+
+func Test_Compile(t *testing.T) {
+	registry := Default()
+
+	// syntax errors point into the policy
+	_, err := registry.Compile(CELLanguage, "subject == \"alice\" &&\n  user_properties.level >")
+	assert.IsType(t, &SyntaxError{}, err)
+	assert.Equal(t, 2, err.(*SyntaxError).Line)
+
+	_, err = registry.Compile(TunnelLanguage, "[[{\"attribute\":\n  {\"name\" \"department\"}}]]")
+	assert.Equal(t, &SyntaxError{Line: 2, Column: 11, Message: "invalid character '\"' after object key"}, err)
+
+	// policies which only differ in layout hash alike
+	first, err := registry.Compile(CELLanguage, `subject == "alice" && user_properties.level > 2`)
+	assert.Nil(t, err)
+	second, err := registry.Compile(CELLanguage, "subject == \"alice\"\n  && user_properties.level > 2")
+	assert.Nil(t, err)
+	assert.Equal(t, first.Hash(), second.Hash())
+	third, err := registry.Compile(CELLanguage, `subject == "bob" && user_properties.level > 2`)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Hash(), third.Hash())
+
+	tunnel, err := registry.Compile(TunnelLanguage, `[[{"attribute":{"name":"department","type":"string"},"operator":"equal","value":["finance"]}]]`)
+	assert.Nil(t, err)
+	spaced, err := registry.Compile(TunnelLanguage, `[[ {"attribute": {"name": "department", "type": "string"}, "operator": "equal", "value": ["finance"]} ]]`)
+	assert.Nil(t, err)
+	assert.Equal(t, tunnel.Hash(), spaced.Hash())
+}
+
+func Test_Rego(t *testing.T) {
+	registry := Default()
+
+	policy := `package cronuseo.finance
+
+import future.keywords.if
+import future.keywords.in
+
+default allow := false
+
+# managers may approve within their limit, from the office
+allow if {
+	input.action == "approve"
+	input.user_properties.limit >= input.context.amount
+	in_office
+}
+
+allow if {
+	some role in input.user_properties.roles
+	role == "auditor"
+	input.action in {"read", "list"}
+	not blocked
+}
+
+in_office := net.cidr_contains("10.0.0.0/8", input.context.ip)
+
+blocked {
+	input.subject == input.user_properties.blocked[_]
+}
+`
+	assert.Nil(t, registry.Validate(RegoLanguage, policy))
+	approve := Input{Subject: "alice", Action: "approve", ResourceIdentifier: "invoices",
+		UserProperties: map[string]interface{}{"limit": 1000},
+		Context:        map[string]interface{}{"amount": 250, "ip": "10.1.2.3"}}
+	result, err := registry.Evaluate(RegoLanguage, policy, approve)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	// undefined attributes make a rule fail, and the default decides
+	approve.Context = map[string]interface{}{"amount": 250}
+	result, err = registry.Evaluate(RegoLanguage, policy, approve)
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	auditor := Input{Subject: "bob", Action: "read", UserProperties: map[string]interface{}{"roles": []string{"clerk", "auditor"}}}
+	result, _ = registry.Evaluate(RegoLanguage, policy, auditor)
+	assert.True(t, result)
+	auditor.UserProperties["blocked"] = []string{"eve", "bob"}
+	result, _ = registry.Evaluate(RegoLanguage, policy, auditor)
+	assert.False(t, result)
+	auditor.Action = "delete"
+	delete(auditor.UserProperties, "blocked")
+	result, _ = registry.Evaluate(RegoLanguage, policy, auditor)
+	assert.False(t, result)
+
+	// values and variables
+	for _, tc := range []struct {
+		policy string
+		want   bool
+	}{
+		{"package p\nallow { x := input.context.amount * 2; x > 10 }", true},
+		{"package p\nallow { input.context.tags[i] == \"vip\"; i == 1 }", true},
+		{"package p\nimport future.keywords.in\nallow { some k, v in input.context.limits; k == \"eu\"; v < 5 }", true},
+		{"package p\nallow { count(input.context.tags) == 2; startswith(input.subject, \"al\") }", true},
+		{"package p\nallow { level >= 3 }\nlevel := input.user_properties.level", false},
+		{"package p\nallow := true\n", true},
+		{"package p\nallow { {\"a\": input.subject} == {\"a\": \"alice\"}; [1, 2] != [2, 1] }", true},
+		{"package p\nallow { input.context.missing == 1 }", false},
+		{"package p\nallow { not input.context.missing }", true},
+		{"package p\nallow { false } else = true { true }", true},
+		{"package p\nallow { sprintf(\"%s:%d\", [input.subject, 2]) == \"alice:2\" }", true},
+		{"package p\nallow { object.get(input.context, \"missing\", 3) == 3 }", true},
+		{"package p\nallow { count([t | t := input.context.tags[_]; t != \"new\"]) == 1 }", true},
+		{"package p\nallow { input.context.amount / 0 > 0 }", false},
+		{"package p\nallow { time.now_ns() < time.parse_rfc3339_ns(\"2000-01-01T00:00:00Z\") }", false},
+	} {
+		result, err := registry.Evaluate(RegoLanguage, tc.policy, Input{Subject: "alice",
+			UserProperties: map[string]interface{}{"level": 2},
+			Context:        map[string]interface{}{"amount": 6, "tags": []interface{}{"new", "vip"}, "limits": map[string]interface{}{"eu": 3, "us": 9}}})
+		assert.Nil(t, err, tc.policy)
+		assert.Equal(t, tc.want, result, tc.policy)
+	}
+
+	// policies which do not compile point into the policy
+	for _, tc := range []struct {
+		policy string
+		err    *SyntaxError
+	}{
+		{"allow { true }", &SyntaxError{Line: 1, Column: 1, Message: "package expected"}},
+		{"", &SyntaxError{Line: 0, Column: 0, Message: "empty module"}},
+		{"package p\n\nallow {\n  input.subject ==\n}", &SyntaxError{Line: 5, Column: 1, Message: "unexpected } token"}},
+		{"package p\nallow { x == 1 }", &SyntaxError{Line: 2, Column: 9, Message: "var x is unsafe"}},
+		{"package p\nallow { is_admin(input.subject) }", &SyntaxError{Line: 2, Column: 9, Message: "undefined function is_admin"}},
+		{"package p\nallow { a }\na { allow }", &SyntaxError{Line: 2, Column: 1, Message: "rule data.p.allow is recursive: data.p.allow -> data.p.a -> data.p.allow"}},
+		{"package p\nallow { \"a\" + \"b\" == \"ab\" }", &SyntaxError{Line: 2, Column: 9, Message: "plus: invalid argument(s)"}},
+		{"package p\ndeny { true }", &SyntaxError{Message: "rego policy must define an allow rule"}},
+	} {
+		_, err := registry.Compile(RegoLanguage, tc.policy)
+		assert.Equal(t, tc.err, err, tc.policy)
+	}
+
+	// policies which only differ in layout and comments hash alike
+	first, err := registry.Compile(RegoLanguage, "package p\nallow {\n  input.subject == \"alice\"\n  input.action == \"read\"\n}")
+	assert.Nil(t, err)
+	second, err := registry.Compile(RegoLanguage, "package p\n\n# alice reads\nallow { input.subject == \"alice\"; input.action == \"read\" }\n")
+	assert.Nil(t, err)
+	assert.Equal(t, first.Hash(), second.Hash())
+	third, err := registry.Compile(RegoLanguage, "package p\nallow { input.subject == \"bob\"; input.action == \"read\" }")
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Hash(), third.Hash())
+
+	// complete rules which conflict fail the evaluation
+	_, err = registry.Evaluate(RegoLanguage, "package p\nallow := true { input.subject == \"alice\" }\nallow := false { input.subject == \"alice\" }", Input{Subject: "alice"})
+	assert.ErrorContains(t, err, "complete rules must not produce multiple outputs")
+}
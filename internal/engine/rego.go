@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// RegoLanguage is the policy language of Open Policy Agent. A Rego policy is a module which decides
+// through its allow rule, over an input of subject, action, resource, user_properties and context.
+const RegoLanguage Language = "rego"
+
+// Rego returns the engine of Rego policies, which evaluates them with Open Policy Agent.
+func Rego() PolicyEngine {
+
+	return regoEngine{}
+}
+
+type regoEngine struct{}
+
+type regoPolicy struct {
+	query     rego.PreparedEvalQuery
+	canonical string
+}
+
+func (regoEngine) Language() Language {
+
+	return RegoLanguage
+}
+
+// Compile parses the module and prepares the query of its allow rule, so the policy is only
+// compiled once and every evaluation reuses the prepared query.
+func (e regoEngine) Compile(policy string) (CompiledPolicy, error) {
+
+	module, err := ast.ParseModule("policy.rego", policy)
+	if err != nil {
+		return nil, regoError(err)
+	}
+	if module == nil {
+		return nil, &SyntaxError{Line: 1, Column: 1, Message: "rego policy must start with a package"}
+	}
+	allow := false
+	for _, rule := range module.Rules {
+		allow = allow || rule.Head.Ref().String() == "allow"
+	}
+	if !allow {
+		return nil, &SyntaxError{Message: "rego policy must define an allow rule"}
+	}
+	query, err := rego.New(
+		rego.Query(module.Package.Path.String()+".allow"),
+		rego.ParsedModule(module),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, regoError(err)
+	}
+	return regoPolicy{query: query, canonical: module.String()}, nil
+}
+
+func (e regoEngine) Validate(policy string) error {
+
+	_, err := e.Compile(policy)
+	return err
+}
+
+func (e regoEngine) Evaluate(policy string, input Input) (bool, error) {
+
+	compiled, err := e.Compile(policy)
+	if err != nil {
+		return false, err
+	}
+	return compiled.Evaluate(input)
+}
+
+// Evaluate decides through the allow rule. An allow rule which is undefined for the input does not pass.
+func (p regoPolicy) Evaluate(input Input) (bool, error) {
+
+	document := map[string]interface{}{
+		"subject":  input.Subject,
+		"action":   input.Action,
+		"resource": input.ResourceIdentifier,
+	}
+	for name, attributes := range map[string]map[string]interface{}{
+		"user_properties": input.UserProperties,
+		"context":         input.Context,
+	} {
+		attributes, err := jsonDocument(attributes)
+		if err != nil {
+			return false, err
+		}
+		document[name] = attributes
+	}
+	results, err := p.query.Eval(context.Background(), rego.EvalInput(document), rego.EvalTime(input.Time))
+	if err != nil {
+		return false, err
+	}
+	if len(results) == 0 || len(results[0].Expressions) == 0 {
+		return false, nil
+	}
+	allowed, ok := results[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("rego policy allow must be a boolean, got %v", results[0].Expressions[0].Value)
+	}
+	return allowed, nil
+}
+
+func (p regoPolicy) Hash() string {
+
+	return hash(RegoLanguage, p.canonical)
+}
+
+// regoError reports the first error Open Policy Agent found in a policy where it was found.
+func regoError(err error) error {
+
+	var errs ast.Errors
+	first := &ast.Error{}
+	if errors.As(err, &errs) && len(errs) > 0 {
+		errs.Sort()
+		first = errs[0]
+	} else if !errors.As(err, &first) {
+		return err
+	}
+	syntaxErr := &SyntaxError{Message: first.Message}
+	if first.Location != nil {
+		syntaxErr.Line, syntaxErr.Column = first.Location.Row, first.Location.Col
+	}
+	return syntaxErr
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"encoding/json"
+
+	"github.com/shashimalcse/tunnel_go"
+)
+
+// Tunnel returns the engine of policy tunnel policies, which are evaluated against the user properties
+// merged with the request context.
+func Tunnel() PolicyEngine {
+
+	return tunnelEngine{}
+}
+
+type tunnelEngine struct{}
+
+type tunnelPolicy struct {
+	policy string
+	paths  tunnel_go.Paths
+}
+
+func (tunnelEngine) Language() Language {
+
+	return TunnelLanguage
+}
+
+func (e tunnelEngine) Compile(policy string) (CompiledPolicy, error) {
+
+	var paths tunnel_go.Paths
+	if err := json.Unmarshal([]byte(policy), &paths); err != nil {
+		// The offsets of JSON errors count the bytes read, up to and including the byte in error.
+		switch jsonErr := err.(type) {
+		case *json.SyntaxError:
+			return nil, syntaxErrorAt(policy, int(jsonErr.Offset)-1, jsonErr.Error())
+		case *json.UnmarshalTypeError:
+			return nil, syntaxErrorAt(policy, int(jsonErr.Offset)-1, jsonErr.Error())
+		}
+		return nil, &SyntaxError{Message: err.Error()}
+	}
+	return tunnelPolicy{policy: policy, paths: paths}, nil
+}
+
+func (e tunnelEngine) Validate(policy string) error {
+
+	_, err := e.Compile(policy)
+	return err
+}
+
+func (e tunnelEngine) Evaluate(policy string, input Input) (bool, error) {
+
+	compiled, err := e.Compile(policy)
+	if err != nil {
+		return false, err
+	}
+	return compiled.Evaluate(input)
+}
+
+func (p tunnelPolicy) Evaluate(input Input) (bool, error) {
+
+	properties, err := json.Marshal(input.Attributes())
+	if err != nil {
+		return false, err
+	}
+	return tunnel_go.ValidateTunnelPolicy(p.policy, string(properties)), nil
+}
+
+func (p tunnelPolicy) Hash() string {
+
+	canonical, err := json.Marshal(p.paths)
+	if err != nil {
+		return hash(TunnelLanguage, p.policy)
+	}
+	return hash(TunnelLanguage, string(canonical))
+}
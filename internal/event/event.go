@@ -0,0 +1,56 @@
+package event
+
+import "sync"
+
+type Entity string
+
+const (
+	OrganizationEntity Entity = "organization"
+	UserEntity         Entity = "user"
+	RoleEntity         Entity = "role"
+	GroupEntity        Entity = "group"
+	PolicyEntity       Entity = "policy"
+	ResourceEntity     Entity = "resource"
+	RelationEntity     Entity = "relation"
+)
+
+// Event describes a change to the access data of an organization.
+// An event without an organization id stands for a change to any organization.
+type Event struct {
+	OrganizationID string
+	Entity         Entity
+	ID             string
+}
+
+type Listener func(Event)
+
+// Bus delivers events to every subscribed listener in the same process.
+type Bus struct {
+	mu        sync.RWMutex
+	listeners []Listener
+}
+
+func NewBus() *Bus {
+
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(listener Listener) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+// Publish is a no-op on a nil bus, so services can be built without one.
+func (b *Bus) Publish(e Event) {
+
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, listener := range b.listeners {
+		listener(e)
+	}
+}
@@ -0,0 +1,214 @@
+package event
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"time"
+
+	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Error code returned by MongoDB when change streams are used on a standalone server.
+const changeStreamNotSupported = 40573
+
+// Watcher publishes events for changes made to the organizations collection by any process,
+// so that caches of several instances stay in sync.
+type Watcher struct {
+	coll         *mongo.Collection
+	bus          *Bus
+	logger       *zap.Logger
+	pollInterval time.Duration
+}
+
+func NewWatcher(mongodb *db.MongoDB, bus *Bus, logger *zap.Logger, pollInterval time.Duration) *Watcher {
+
+	coll := mongodb.MongoClient.Database(mongodb.MongoConfig.DBName).Collection(mongodb.MongoConfig.OrganizationCollectionName)
+	if pollInterval <= 0 {
+		pollInterval = 10 * time.Second
+	}
+	return &Watcher{coll: coll, bus: bus, logger: logger, pollInterval: pollInterval}
+}
+
+// Run watches the collection with a change stream, or polls it when change streams are not
+// supported by the deployment. It blocks until the context is done.
+func (w *Watcher) Run(ctx context.Context) {
+
+	err := w.watch(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	w.logger.Warn("Change streams are not supported, polling for organization changes.",
+		zap.Duration("poll_interval", w.pollInterval), zap.Error(err))
+	w.poll(ctx)
+}
+
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	UpdateDescription struct {
+		UpdatedFields bson.Raw `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// watch follows the change stream, reopening it after failures. It only returns when the
+// context is done or the deployment does not support change streams.
+func (w *Watcher) watch(ctx context.Context) error {
+
+	var resumeToken bson.Raw
+	for {
+		opts := options.ChangeStream()
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+		stream, err := w.coll.Watch(ctx, mongo.Pipeline{}, opts)
+		if err != nil {
+			var serverErr mongo.ServerError
+			if errors.As(err, &serverErr) && serverErr.HasErrorCode(changeStreamNotSupported) {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			w.logger.Error("Error while opening organization change stream.", zap.Error(err))
+			// The position in the stream is lost, so nothing cached can be trusted.
+			resumeToken = nil
+			w.bus.Publish(Event{})
+			if !sleep(ctx, w.pollInterval) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		for stream.Next(ctx) {
+			var change changeEvent
+			if err := stream.Decode(&change); err != nil {
+				w.logger.Error("Error while decoding organization change.", zap.Error(err))
+				continue
+			}
+			for _, entity := range changedEntities(change) {
+				w.bus.Publish(Event{OrganizationID: change.DocumentKey.ID.Hex(), Entity: entity})
+			}
+			resumeToken = stream.ResumeToken()
+		}
+		err = stream.Err()
+		stream.Close(context.Background())
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.logger.Error("Organization change stream closed, reopening.", zap.Error(err))
+	}
+}
+
+// changedEntities maps the fields touched by a change to the entities they hold.
+func changedEntities(change changeEvent) []Entity {
+
+	if change.OperationType != "update" {
+		return []Entity{OrganizationEntity}
+	}
+	fields := change.UpdateDescription.RemovedFields
+	elements, _ := change.UpdateDescription.UpdatedFields.Elements()
+	for _, element := range elements {
+		fields = append(fields, element.Key())
+	}
+
+	seen := make(map[Entity]bool)
+	var entities []Entity
+	for _, field := range fields {
+		entity, exists := fieldEntities[strings.SplitN(field, ".", 2)[0]]
+		if !exists {
+			entity = OrganizationEntity
+		}
+		if !seen[entity] {
+			seen[entity] = true
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+var fieldEntities = map[string]Entity{
+	"users":     UserEntity,
+	"roles":     RoleEntity,
+	"groups":    GroupEntity,
+	"policies":  PolicyEntity,
+	"resources": ResourceEntity,
+	"relations": RelationEntity,
+	// Written together with the relations.
+	"relations_revision": RelationEntity,
+	"relation_changes":   RelationEntity,
+}
+
+// poll compares a fingerprint of every organization on each interval and publishes an event
+// for those which changed or were deleted.
+func (w *Watcher) poll(ctx context.Context) {
+
+	var fingerprints map[primitive.ObjectID][sha256.Size]byte
+	for {
+		current, err := w.fingerprints(ctx)
+		if err != nil {
+			w.logger.Error("Error while polling organizations.", zap.Error(err))
+		} else {
+			if fingerprints != nil {
+				for id, fingerprint := range current {
+					if previous, exists := fingerprints[id]; exists && previous != fingerprint {
+						w.bus.Publish(Event{OrganizationID: id.Hex(), Entity: OrganizationEntity})
+					}
+				}
+				for id := range fingerprints {
+					if _, exists := current[id]; !exists {
+						w.bus.Publish(Event{OrganizationID: id.Hex(), Entity: OrganizationEntity})
+					}
+				}
+			}
+			fingerprints = current
+		}
+		if !sleep(ctx, w.pollInterval) {
+			return
+		}
+	}
+}
+
+func (w *Watcher) fingerprints(ctx context.Context) (map[primitive.ObjectID][sha256.Size]byte, error) {
+
+	// Every field a check depends on, along with the revision which every write moves.
+	projection := bson.M{"api_key": 1, "combining_algorithm": 1, "revision": 1,
+		"users": 1, "groups": 1, "roles": 1, "policies": 1, "resources": 1, "relations": 1}
+	cursor, err := w.coll.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	fingerprints := make(map[primitive.ObjectID][sha256.Size]byte)
+	for cursor.Next(ctx) {
+		id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+		if !ok {
+			continue
+		}
+		fingerprints[id] = sha256.Sum256(cursor.Current)
+	}
+	return fingerprints, cursor.Err()
+}
+
+// sleep waits for the given duration and reports false if the context was done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
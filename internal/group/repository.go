@@ -5,6 +5,7 @@ import (
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +16,7 @@ import (
 type Repository interface {
 	Get(ctx context.Context, org_id string, id string) (*GroupResponse, error)
 	Query(ctx context.Context, org_id string) (*[]mongo_entity.Group, error)
+	GetGroupGraph(ctx context.Context, org_id string) ([]mongo_entity.Group, error)
 	Create(ctx context.Context, org_id string, group mongo_entity.Group) error
 	Update(ctx context.Context, org_id string, id string, update_group UpdateGroup) error
 	Patch(ctx context.Context, org_id string, id string, patch_group PatchGroup) error
@@ -27,6 +29,7 @@ type Repository interface {
 	CheckUserAlreadyAssignToGroupById(ctx context.Context, org_id string, group_id string, user_id string) (bool, error)
 	CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error)
 	CheckPolicyAlreadyAssignToGroupById(ctx context.Context, org_id string, group_id string, policy_id string) (bool, error)
+	GetOrganizationRevision(ctx context.Context, org_id string) (int64, error)
 }
 
 type repository struct {
@@ -87,6 +90,36 @@ func (r repository) Get(ctx context.Context, org_id string, id string) (*GroupRe
 		Roles:       assignedRoles,
 		Policies:    assignedPolicies,
 	}
+
+	// Resolve member groups and the users inherited through them.
+	if len(group.Groups) > 0 {
+		groups, err := r.GetGroupGraph(ctx, org_id)
+		if err != nil {
+			return nil, err
+		}
+		for _, memberId := range group.Groups {
+			for _, member := range groups {
+				if member.ID == memberId {
+					roleResponse.Groups = append(roleResponse.Groups, mongo_entity.AssignedGroup{ID: member.ID, Identifier: member.Identifier, DisplayName: member.DisplayName})
+				}
+			}
+		}
+		for _, descendant := range mongo_entity.DescendantGroups(groups, group.ID) {
+			if len(descendant.Users) == 0 {
+				continue
+			}
+			users, err := r.resolveAssignedUsers(ctx, orgId, descendant.Users)
+			if err != nil {
+				return nil, err
+			}
+			for _, user := range users {
+				roleResponse.InheritedUsers = append(roleResponse.InheritedUsers, InheritedUser{
+					AssignedUser: user,
+					Group:        mongo_entity.AssignedGroup{ID: descendant.ID, Identifier: descendant.Identifier, DisplayName: descendant.DisplayName},
+				})
+			}
+		}
+	}
 	return &roleResponse, nil
 }
 
@@ -100,7 +133,7 @@ func (r repository) Create(ctx context.Context, org_id string, group mongo_entit
 	// Update the APIResources array for the given organization
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$push": bson.M{"groups": group}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -111,7 +144,7 @@ func (r repository) Create(ctx context.Context, org_id string, group mongo_entit
 		for _, roleId := range group.Roles {
 			filter := bson.M{"_id": orgId, "roles._id": roleId}
 			update := bson.M{"$addToSet": bson.M{"roles.$.groups": group.ID}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -124,7 +157,7 @@ func (r repository) Create(ctx context.Context, org_id string, group mongo_entit
 		for _, userId := range group.Users {
 			filter := bson.M{"_id": orgId, "users._id": userId}
 			update := bson.M{"$addToSet": bson.M{"users.$.groups": group.ID}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -153,7 +186,7 @@ func (r repository) Update(ctx context.Context, org_id string, id string, update
 	if update_group.DisplayName != nil && *update_group.DisplayName != "" {
 		update["$set"].(bson.M)["groups.$.first_name"] = *update_group.DisplayName
 	}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -179,7 +212,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		update := bson.M{"$push": bson.M{"groups.$.roles": bson.M{
 			"$each": patch_group.AddedRoles,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -187,7 +220,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		for _, roleId := range patch_group.AddedRoles {
 			filter := bson.M{"_id": orgId, "roles._id": roleId}
 			update := bson.M{"$addToSet": bson.M{"roles.$.groups": groupId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -200,7 +233,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 
 		filter := bson.M{"_id": orgId, "groups._id": groupId}
 		update := bson.M{"$pull": bson.M{"groups.$.roles": bson.M{"$in": patch_group.RemovedRoles}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -208,7 +241,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		for _, roleId := range patch_group.RemovedRoles {
 			filter := bson.M{"_id": orgId, "roles._id": roleId}
 			update := bson.M{"$pull": bson.M{"roles.$.groups": groupId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -222,7 +255,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		update := bson.M{"$push": bson.M{"groups.$.users": bson.M{
 			"$each": patch_group.AddedUsers,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -230,7 +263,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		for _, userId := range patch_group.AddedUsers {
 			filter := bson.M{"_id": orgId, "users._id": userId}
 			update := bson.M{"$addToSet": bson.M{"users.$.groups": groupId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -243,7 +276,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 
 		filter := bson.M{"_id": orgId, "groups._id": groupId}
 		update := bson.M{"$pull": bson.M{"groups.$.users": bson.M{"$in": patch_group.RemovedUsers}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -251,7 +284,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		for _, userId := range patch_group.RemovedUsers {
 			filter := bson.M{"_id": orgId, "users._id": userId}
 			update := bson.M{"$pull": bson.M{"users.$.groups": groupId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -265,7 +298,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 		update := bson.M{"$push": bson.M{"groups.$.policies": bson.M{
 			"$each": patch_group.AddedPolicies,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -276,7 +309,31 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_g
 
 		filter := bson.M{"_id": orgId, "groups._id": groupId}
 		update := bson.M{"$pull": bson.M{"groups.$.policies": bson.M{"$in": patch_group.RemovedPolicies}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
+		if err != nil {
+			return err
+		}
+	}
+
+	// add member groups
+	if len(patch_group.AddedGroups) > 0 {
+
+		filter := bson.M{"_id": orgId, "groups._id": groupId}
+		update := bson.M{"$addToSet": bson.M{"groups.$.groups": bson.M{
+			"$each": patch_group.AddedGroups,
+		}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove member groups
+	if len(patch_group.RemovedGroups) > 0 {
+
+		filter := bson.M{"_id": orgId, "groups._id": groupId}
+		update := bson.M{"$pull": bson.M{"groups.$.groups": bson.M{"$in": patch_group.RemovedGroups}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -302,7 +359,7 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$pull": bson.M{"groups": bson.M{"_id": groupId}}}
 	// Find the group document in the "organizations" collection
-	result, err := r.mongoColl.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(false))
+	result, err := r.mongoColl.UpdateOne(context.Background(), filter, revision.Update(update), options.Update().SetUpsert(false))
 	if err != nil {
 		return err
 	}
@@ -314,14 +371,22 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 
 	filter = bson.M{"_id": orgId}
 	update = bson.M{"$pull": bson.M{"roles.$[].groups": groupId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
 
 	filter = bson.M{"_id": orgId}
 	update = bson.M{"$pull": bson.M{"users.$[].groups": groupId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+	if err != nil {
+		return err
+	}
+
+	// The deleted group is no longer a member of other groups.
+	filter = bson.M{"_id": orgId}
+	update = bson.M{"$pull": bson.M{"groups.$[].groups": groupId}}
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
@@ -358,6 +423,27 @@ func (r repository) Query(ctx context.Context, org_id string) (*[]mongo_entity.G
 	return &org.Groups, nil
 }
 
+// Get every group with its users and member groups, enough to walk nested groups.
+func (r repository) GetGroupGraph(ctx context.Context, org_id string) ([]mongo_entity.Group, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": orgId}
+	projection := bson.M{"groups._id": 1, "groups.identifier": 1, "groups.display_name": 1, "groups.users": 1, "groups.groups": 1}
+	var org mongo_entity.Organization
+	err = r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &util.NotFoundError{Path: "Organization"}
+		}
+		return nil, err
+	}
+	return org.Groups, nil
+}
+
 // Check if group exists by id.
 func (r repository) CheckGroupExistById(ctx context.Context, org_id string, id string) (bool, error) {
 
@@ -690,3 +776,9 @@ func (r repository) resolveAssignedPolicies(ctx context.Context, orgId primitive
 
 	return results[0].Policies, nil
 }
+
+// Get the revision the organization is at.
+func (r repository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, org_id)
+}
@@ -3,7 +3,9 @@ package group
 import (
 	"context"
 
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
@@ -31,6 +33,15 @@ type GroupResponse struct {
 	Users       []mongo_entity.AssignedUser   `json:"users,omitempty" bson:"users"`
 	Roles       []mongo_entity.AssignedRole   `json:"roles,omitempty" bson:"roles"`
 	Policies    []mongo_entity.AssignedPolicy `json:"policies,omitempty" bson:"policies"`
+	Groups      []mongo_entity.AssignedGroup  `json:"groups,omitempty" bson:"groups"`
+	// Users which are members through the nested groups of this group.
+	InheritedUsers []InheritedUser `json:"inherited_users,omitempty" bson:"inherited_users"`
+}
+
+// InheritedUser is a member of a nested group, along with the nested group it belongs to.
+type InheritedUser struct {
+	mongo_entity.AssignedUser `bson:",inline"`
+	Group                     mongo_entity.AssignedGroup `json:"group" bson:"group"`
 }
 
 type CreateGroupRequest struct {
@@ -39,6 +50,7 @@ type CreateGroupRequest struct {
 	Roles       []primitive.ObjectID `json:"roles,omitempty" bson:"roles"`
 	Users       []primitive.ObjectID `json:"users,omitempty" bson:"users"`
 	Policies    []primitive.ObjectID `json:"policies,omitempty" bson:"policies"`
+	Groups      []primitive.ObjectID `json:"groups,omitempty" bson:"groups"`
 }
 
 func (m CreateGroupRequest) Validate() error {
@@ -59,6 +71,8 @@ type PatchGroupRequest struct {
 	RemovedUsers    []primitive.ObjectID `json:"removed_users,omitempty" bson:"removed_users"`
 	AddedPolicies   []primitive.ObjectID `json:"added_policies,omitempty" bson:"added_policies"`
 	RemovedPolicies []primitive.ObjectID `json:"removed_policies,omitempty" bson:"removed_policies"`
+	AddedGroups     []primitive.ObjectID `json:"added_groups,omitempty" bson:"added_groups"`
+	RemovedGroups   []primitive.ObjectID `json:"removed_groups,omitempty" bson:"removed_groups"`
 }
 
 type UpdateGroup struct {
@@ -72,6 +86,8 @@ type PatchGroup struct {
 	RemovedUsers    []primitive.ObjectID `json:"removed_users,omitempty" bson:"removed_users"`
 	AddedPolicies   []primitive.ObjectID `json:"added_policies,omitempty" bson:"added_policies"`
 	RemovedPolicies []primitive.ObjectID `json:"removed_policies,omitempty" bson:"removed_policies"`
+	AddedGroups     []primitive.ObjectID `json:"added_groups,omitempty" bson:"added_groups"`
+	RemovedGroups   []primitive.ObjectID `json:"removed_groups,omitempty" bson:"removed_groups"`
 }
 
 func (m UpdateGroupRequest) Validate() error {
@@ -81,11 +97,25 @@ func (m UpdateGroupRequest) Validate() error {
 type service struct {
 	repo   Repository
 	logger *zap.Logger
+	events *event.Bus
+}
+
+func NewService(repo Repository, logger *zap.Logger, events *event.Bus) Service {
+
+	return service{repo: repo, logger: logger, events: events}
 }
 
-func NewService(repo Repository, logger *zap.Logger) Service {
+// changed publishes a write to a group, and tracks the revision the write moved the organization to.
+func (s service) changed(ctx context.Context, org_id string, id string) error {
 
-	return service{repo: repo, logger: logger}
+	s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.GroupEntity, ID: id})
+	rev, err := s.repo.GetOrganizationRevision(ctx, org_id)
+	if err != nil {
+		s.logger.Error("Error while reading organization revision.", zap.String("organization_id", org_id), zap.Error(err))
+		return err
+	}
+	revision.Track(ctx, rev)
+	return nil
 }
 
 // Get group by id.
@@ -142,6 +172,13 @@ func (s service) Create(ctx context.Context, org_id string, req CreateGroupReque
 		}
 	}
 
+	for _, memberId := range req.Groups {
+		exists, _ := s.repo.CheckGroupExistById(ctx, org_id, memberId.Hex())
+		if !exists {
+			return GroupResponse{}, &util.InvalidInputError{Path: "Invalid group id " + memberId.String()}
+		}
+	}
+
 	var roles []primitive.ObjectID
 	if req.Roles == nil {
 		roles = []primitive.ObjectID{}
@@ -163,6 +200,13 @@ func (s service) Create(ctx context.Context, org_id string, req CreateGroupReque
 		policies = req.Policies
 	}
 
+	var groups []primitive.ObjectID
+	if req.Groups == nil {
+		groups = []primitive.ObjectID{}
+	} else {
+		groups = req.Groups
+	}
+
 	err := s.repo.Create(ctx, org_id, mongo_entity.Group{
 		ID:          groupId,
 		DisplayName: req.DisplayName,
@@ -170,6 +214,7 @@ func (s service) Create(ctx context.Context, org_id string, req CreateGroupReque
 		Roles:       roles,
 		Users:       users,
 		Policies:    policies,
+		Groups:      groups,
 	})
 
 	if err != nil {
@@ -177,6 +222,9 @@ func (s service) Create(ctx context.Context, org_id string, req CreateGroupReque
 			zap.String("organization_id", org_id))
 		return GroupResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, groupId.Hex()); err != nil {
+		return GroupResponse{}, err
+	}
 	return s.Get(ctx, org_id, groupId.Hex())
 }
 
@@ -197,6 +245,9 @@ func (s service) Update(ctx context.Context, org_id string, id string, req Updat
 			zap.String("group_id", id))
 		return GroupResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return GroupResponse{}, err
+	}
 	return s.Get(ctx, org_id, id)
 }
 
@@ -295,6 +346,33 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchG
 		}
 	}
 
+	// member groups
+	for _, memberId := range req.AddedGroups {
+		exists, _ := s.repo.CheckGroupExistById(ctx, org_id, memberId.Hex())
+		if !exists {
+			return GroupResponse{}, &util.InvalidInputError{Path: "Invalid group id " + memberId.String()}
+		}
+	}
+	for _, memberId := range req.RemovedGroups {
+		exists, _ := s.repo.CheckGroupExistById(ctx, org_id, memberId.Hex())
+		if !exists {
+			return GroupResponse{}, &util.InvalidInputError{Path: "Invalid group id " + memberId.String()}
+		}
+	}
+	if len(req.AddedGroups) > 0 {
+		groups, err := s.repo.GetGroupGraph(ctx, org_id)
+		if err != nil {
+			return GroupResponse{}, err
+		}
+		groupId, _ := primitive.ObjectIDFromHex(id)
+		if mongo_entity.CreatesGroupCycle(groups, groupId, req.AddedGroups) {
+			return GroupResponse{}, &util.InvalidInputError{Path: "Member groups of group : " + id + " create a cycle."}
+		}
+		if mongo_entity.ExceedsGroupDepth(groups, groupId, req.AddedGroups) {
+			return GroupResponse{}, &util.InvalidInputError{Path: "Member groups of group : " + id + " nest groups too deep."}
+		}
+	}
+
 	if err := s.repo.Patch(ctx, org_id, id, PatchGroup{
 		AddedRoles:      added_roles,
 		RemovedRoles:    removed_roles,
@@ -302,12 +380,17 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchG
 		RemovedUsers:    removed_users,
 		AddedPolicies:   added_policies,
 		RemovedPolicies: removed_policies,
+		AddedGroups:     req.AddedGroups,
+		RemovedGroups:   req.RemovedGroups,
 	}); err != nil {
 		s.logger.Error("Error while updating group.",
 			zap.String("organization_id", org_id),
 			zap.String("group_id", id))
 		return GroupResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return GroupResponse{}, err
+	}
 	return s.Get(ctx, org_id, id)
 }
 
@@ -326,7 +409,7 @@ func (s service) Delete(ctx context.Context, org_id string, id string) error {
 			zap.String("group_id", id))
 		return err
 	}
-	return nil
+	return s.changed(ctx, org_id, id)
 }
 
 // Pagination filter.
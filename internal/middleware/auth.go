@@ -14,6 +14,7 @@ import (
 	"github.com/shashimalcse/cronuseo/internal/check"
 	"github.com/shashimalcse/cronuseo/internal/config"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.uber.org/zap"
 )
 
@@ -93,6 +94,16 @@ func Auth(cfg *config.Config, logger *zap.Logger, requiredPermissions map[Method
 
 			jwtMiddleware := middleware.JWTWithConfig(middleware.JWTConfig{
 				KeyFunc: keyFunc,
+				SuccessHandler: func(c echo.Context) {
+					// Record who makes the request, for the services which keep track of it.
+					if token, ok := c.Get("user").(*jwt.Token); ok {
+						if claims, ok := token.Claims.(jwt.MapClaims); ok {
+							if sub, ok := claims["sub"].(string); ok {
+								c.SetRequest(c.Request().WithContext(util.WithActor(c.Request().Context(), sub)))
+							}
+						}
+					}
+				},
 				ErrorHandlerWithContext: func(err error, c echo.Context) error {
 					logger.Debug("error while validating token", zap.Error(err))
 					if httpErr, ok := err.(*echo.HTTPError); ok {
@@ -114,6 +125,25 @@ func Auth(cfg *config.Config, logger *zap.Logger, requiredPermissions map[Method
 	}
 }
 
+// RequiredPermissions maps every method and path of the API endpoints to the permissions they require.
+func RequiredPermissions(endpoints []config.APIEndpoint) map[MethodPath][]string {
+
+	requiredPermissions := make(map[MethodPath][]string)
+
+	for _, endpoint := range endpoints {
+		for _, method := range endpoint.Methods {
+			key := MethodPath{
+				Method:   method.Method,
+				Path:     endpoint.Path,
+				Resource: endpoint.Resource,
+			}
+			requiredPermissions[key] = method.RequiredPermissions
+		}
+	}
+
+	return requiredPermissions
+}
+
 // getScopesForMethodPath returns the scopes for a given method and path based on wildcard patterns.
 func getPermissionsForMethodPath(methodPath MethodPath, requiredPermissions map[MethodPath][]string) ([]mongo_entity.Permission, error) {
 	for pattern, permissions := range requiredPermissions {
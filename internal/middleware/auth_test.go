@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/shashimalcse/cronuseo/internal/check"
+	"github.com/shashimalcse/cronuseo/internal/config"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/test"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_adminPermissions(t *testing.T) {
+	logger := test.InitLogger()
+
+	for _, file := range []string{"../../config/local.yml", "../../config/local-debug.yml", "../../config/run-test.yml"} {
+		// The database of some configurations is only given by the environment, so they are not validated here.
+		raw, err := os.ReadFile(file)
+		if !assert.Nil(t, err, file) {
+			continue
+		}
+		cfg := &config.Config{}
+		if !assert.Nil(t, yaml.Unmarshal(raw, cfg), file) {
+			continue
+		}
+		repo := newAdminRepository(cfg)
+		checkService := check.NewService(repo, logger)
+		requiredPermissions := RequiredPermissions(cfg.APIEndpoints)
+
+		// the admin holds every permission of every endpoint
+		for methodPath, permissions := range requiredPermissions {
+			required := []mongo_entity.Permission{}
+			for _, permission := range permissions {
+				required = append(required, mongo_entity.Permission{Resource: methodPath.Resource, Action: permission})
+			}
+			assert.True(t, checkPermissions(cfg.RootOrganization.AdminIdentifier, required, cfg, checkService),
+				"%s: %s %s", file, methodPath.Method, methodPath.Path)
+		}
+
+		// relation endpoints are guarded by the relations system resource
+		for _, methodPath := range []MethodPath{
+			{Method: "GET", Path: "/api/v1/o/super/relations"},
+			{Method: "POST", Path: "/api/v1/o/super/relations"},
+			{Method: "DELETE", Path: "/api/v1/o/super/relations"},
+			{Method: "POST", Path: "/api/v1/o/super/relations/write"},
+			{Method: "GET", Path: "/api/v1/o/super/relations/watch"},
+		} {
+			required, err := getPermissionsForMethodPath(methodPath, requiredPermissions)
+			if assert.Nil(t, err, "%s: %s %s", file, methodPath.Method, methodPath.Path) && assert.NotEmpty(t, required) {
+				assert.Equal(t, "relations", required[0].Resource)
+				assert.True(t, checkPermissions(cfg.RootOrganization.AdminIdentifier, required, cfg, checkService))
+				assert.False(t, checkPermissions("someone", required, cfg, checkService))
+			}
+		}
+	}
+}
+
+// adminRepository holds the root organization as initialized from a configuration: the admin holds
+// the admin role, which is granted every action of every system resource.
+type adminRepository struct {
+	admin       string
+	role        primitive.ObjectID
+	permissions []mongo_entity.Permission
+}
+
+func newAdminRepository(cfg *config.Config) *adminRepository {
+	repo := &adminRepository{admin: cfg.RootOrganization.AdminIdentifier, role: primitive.NewObjectID()}
+	for _, systemResource := range cfg.SystemResourceList() {
+		for _, action := range systemResource.Actions {
+			repo.permissions = append(repo.permissions, mongo_entity.Permission{Resource: systemResource.Identifier, Action: action})
+		}
+	}
+	return repo
+}
+
+func (r *adminRepository) ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error) {
+	return false, nil
+}
+
+func (r *adminRepository) GetRoles(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) ([]mongo_entity.Role, error) {
+	return []mongo_entity.Role{{ID: r.role, Identifier: "admin", Permissions: r.permissions}}, nil
+}
+
+func (r *adminRepository) GetRolePermissions(ctx context.Context, org_identifier string, role_ids []primitive.ObjectID) (*[]mongo_entity.Permission, error) {
+	return &r.permissions, nil
+}
+
+func (r *adminRepository) GetCheckDetails(ctx context.Context, org_identifier string, identifier string) (check.CheckDetails, error) {
+	if identifier != r.admin {
+		return check.CheckDetails{}, nil
+	}
+	return check.CheckDetails{Roles: []primitive.ObjectID{r.role}, RoleGrants: []check.RoleGrant{{RoleID: r.role}}}, nil
+}
+
+func (r *adminRepository) GetActivePolicyVersionContents(ctx context.Context, org_identifier string, policy_ids []primitive.ObjectID) ([]check.ActivePolicyContent, error) {
+	return nil, nil
+}
+
+func (r *adminRepository) GetOrganization(ctx context.Context, org_identifier string) (*mongo_entity.Organization, error) {
+	return nil, &util.NotFoundError{Path: "Organization"}
+}
+
+func (r *adminRepository) GetResourcePolicies(ctx context.Context, org_identifier string, resource string) ([]primitive.ObjectID, error) {
+	return nil, nil
+}
+
+func (r *adminRepository) GetCombiningAlgorithm(ctx context.Context, org_identifier string) (mongo_entity.CombiningAlgorithm, error) {
+	return mongo_entity.RBACAndABAC, nil
+}
+
+func (r *adminRepository) EnsureRevision(ctx context.Context, org_identifier string, revision int64) error {
+	return nil
+}
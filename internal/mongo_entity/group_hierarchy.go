@@ -0,0 +1,120 @@
+package mongo_entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MaxGroupDepth limits how many levels of nested groups are followed when resolving membership.
+// Group patches which would nest groups deeper are rejected.
+const MaxGroupDepth = 10
+
+// AncestorGroups returns every group which holds the given group as a member, directly or
+// through other groups, nearest first. Each group is returned once and at most MaxGroupDepth
+// levels are followed, so cycles in stored data can't loop forever.
+func AncestorGroups(groups []Group, id primitive.ObjectID) []Group {
+
+	parents := make(map[primitive.ObjectID][]Group)
+	for _, group := range groups {
+		for _, memberID := range group.Groups {
+			parents[memberID] = append(parents[memberID], group)
+		}
+	}
+	return walkGroups(id, func(id primitive.ObjectID) []Group { return parents[id] })
+}
+
+// DescendantGroups returns every group which is a member of the given group, directly or
+// through other groups, nearest first, with the same limits as AncestorGroups.
+func DescendantGroups(groups []Group, id primitive.ObjectID) []Group {
+
+	byID := make(map[primitive.ObjectID]Group)
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+	return walkGroups(id, func(id primitive.ObjectID) []Group {
+		var members []Group
+		for _, memberID := range byID[id].Groups {
+			if member, exists := byID[memberID]; exists {
+				members = append(members, member)
+			}
+		}
+		return members
+	})
+}
+
+// CreatesGroupCycle reports whether adding the given groups as members of the group with the
+// given id would make the group a member of itself.
+func CreatesGroupCycle(groups []Group, id primitive.ObjectID, memberIDs []primitive.ObjectID) bool {
+
+	for _, memberID := range memberIDs {
+		if memberID == id {
+			return true
+		}
+		for _, descendant := range DescendantGroups(groups, memberID) {
+			if descendant.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExceedsGroupDepth reports whether adding the given groups as members of the group with the
+// given id would nest groups deeper than MaxGroupDepth, beyond which membership isn't resolved.
+func ExceedsGroupDepth(groups []Group, id primitive.ObjectID, memberIDs []primitive.ObjectID) bool {
+
+	parents := make(map[primitive.ObjectID][]primitive.ObjectID)
+	members := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, group := range groups {
+		members[group.ID] = group.Groups
+		for _, memberID := range group.Groups {
+			parents[memberID] = append(parents[memberID], group.ID)
+		}
+	}
+	above := nestingDepth(id, parents, map[primitive.ObjectID]bool{})
+	for _, memberID := range memberIDs {
+		if above+1+nestingDepth(memberID, members, map[primitive.ObjectID]bool{}) > MaxGroupDepth {
+			return true
+		}
+	}
+	return false
+}
+
+// nestingDepth returns the length of the longest chain of groups reachable from the given group.
+func nestingDepth(id primitive.ObjectID, next map[primitive.ObjectID][]primitive.ObjectID, path map[primitive.ObjectID]bool) int {
+
+	path[id] = true
+	defer delete(path, id)
+	depth := 0
+	for _, nextID := range next[id] {
+		if path[nextID] {
+			continue
+		}
+		if d := 1 + nestingDepth(nextID, next, path); d > depth {
+			depth = d
+		}
+		if depth > MaxGroupDepth {
+			break
+		}
+	}
+	return depth
+}
+
+func walkGroups(id primitive.ObjectID, next func(primitive.ObjectID) []Group) []Group {
+
+	visited := map[primitive.ObjectID]bool{id: true}
+	level := []primitive.ObjectID{id}
+	var result []Group
+	for depth := 0; depth < MaxGroupDepth && len(level) > 0; depth++ {
+		var nextLevel []primitive.ObjectID
+		for _, groupID := range level {
+			for _, group := range next(groupID) {
+				if visited[group.ID] {
+					continue
+				}
+				visited[group.ID] = true
+				result = append(result, group)
+				nextLevel = append(nextLevel, group.ID)
+			}
+		}
+		level = nextLevel
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package mongo_entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_ExceedsGroupDepth(t *testing.T) {
+	// a chain of MaxGroupDepth groups, each one the only member of the group before it
+	groups := make([]Group, MaxGroupDepth)
+	for i := range groups {
+		groups[i].ID = primitive.NewObjectID()
+	}
+	for i := 0; i < len(groups)-1; i++ {
+		groups[i].Groups = []primitive.ObjectID{groups[i+1].ID}
+	}
+	top, bottom := groups[0].ID, groups[len(groups)-1].ID
+	other := Group{ID: primitive.NewObjectID()}
+	below := Group{ID: primitive.NewObjectID(), Groups: []primitive.ObjectID{other.ID}}
+	groups = append(groups, other, below)
+
+	// the chain is MaxGroupDepth-1 levels deep, so one more level still resolves
+	assert.False(t, ExceedsGroupDepth(groups, bottom, []primitive.ObjectID{other.ID}))
+	// nesting a group which has members of its own below the bottom goes too deep
+	assert.True(t, ExceedsGroupDepth(groups, bottom, []primitive.ObjectID{below.ID}))
+	// so does nesting the top of the chain into another group's member
+	assert.True(t, ExceedsGroupDepth(groups, other.ID, []primitive.ObjectID{top}))
+	// the longest chain counts, even when a shorter one reaches the same group
+	groups[0].Groups = append(groups[0].Groups, bottom)
+	assert.True(t, ExceedsGroupDepth(groups, bottom, []primitive.ObjectID{below.ID}))
+	assert.False(t, ExceedsGroupDepth(groups, below.ID, []primitive.ObjectID{top}))
+}
@@ -1,6 +1,10 @@
 package mongo_entity
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type ResourceType string
 
@@ -9,16 +13,54 @@ const (
 	BusinessResource ResourceType = "business"
 )
 
+// CombiningAlgorithm decides how the RBAC decision of a check is combined with the policies of the subject.
+// Whatever the algorithm, a deny of the roles wins, and the policies of the subject only grant a check which
+// a permission of the subject or a policy of its resource applies to.
+type CombiningAlgorithm string
+
+const (
+	// DenyOverrides denies when RBAC or any policy denies, and allows when RBAC or a policy allows.
+	DenyOverrides CombiningAlgorithm = "deny-overrides"
+	// PermitOverrides allows when RBAC or any policy allows.
+	PermitOverrides CombiningAlgorithm = "permit-overrides"
+	// FirstApplicable takes the RBAC decision when a permission matches, or else the first policy.
+	FirstApplicable CombiningAlgorithm = "first-applicable"
+	// RBACAndABAC allows when RBAC allows and every policy passes. It is the default.
+	RBACAndABAC CombiningAlgorithm = "rbac-and-abac"
+	// RBACOrABAC allows when RBAC allows, or when the subject has policies and all of them pass.
+	RBACOrABAC CombiningAlgorithm = "rbac-or-abac"
+)
+
+// CombiningAlgorithms lists every combining algorithm an organization can choose.
+var CombiningAlgorithms = []CombiningAlgorithm{DenyOverrides, PermitOverrides, FirstApplicable, RBACAndABAC, RBACOrABAC}
+
 type Organization struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Identifier  string             `json:"identifier" bson:"identifier"`
 	DisplayName string             `json:"display_name" bson:"display_name"`
 	API_KEY     string             `json:"api_key" bson:"api_key"`
-	Resources   []Resource         `json:"resources,omitempty" bson:"resources"`
-	Users       []User             `json:"users,omitempty" bson:"users"`
-	Roles       []Role             `json:"roles,omitempty" bson:"roles"`
-	Groups      []Group            `json:"groups,omitempty" bson:"groups"`
-	Polices     []Policy           `json:"policies,omitempty" bson:"policies"`
+	// Revision is increased after every write to the access data of the organization.
+	Revision int64 `json:"revision,omitempty" bson:"revision,omitempty"`
+	// CombiningAlgorithm of the checks of the organization. Empty is RBACAndABAC.
+	CombiningAlgorithm CombiningAlgorithm `json:"combining_algorithm,omitempty" bson:"combining_algorithm,omitempty"`
+	Resources          []Resource         `json:"resources,omitempty" bson:"resources"`
+	Users              []User             `json:"users,omitempty" bson:"users"`
+	Roles              []Role             `json:"roles,omitempty" bson:"roles"`
+	Groups             []Group            `json:"groups,omitempty" bson:"groups"`
+	Polices            []Policy           `json:"policies,omitempty" bson:"policies"`
+	Relations          []RelationTuple    `json:"relations,omitempty" bson:"relations"`
+	// RelationsRevision is increased on every change to the relations, which are kept in RelationChanges.
+	RelationsRevision int64            `json:"relations_revision,omitempty" bson:"relations_revision,omitempty"`
+	RelationChanges   []RelationChange `json:"-" bson:"relation_changes,omitempty"`
+}
+
+// Algorithm returns the combining algorithm of the organization, which is RBACAndABAC unless it chose another.
+func (o Organization) Algorithm() CombiningAlgorithm {
+
+	if o.CombiningAlgorithm == "" {
+		return RBACAndABAC
+	}
+	return o.CombiningAlgorithm
 }
 
 type Resource struct {
@@ -27,6 +69,10 @@ type Resource struct {
 	DisplayName string             `json:"display_name" bson:"display_name"`
 	Type        ResourceType       `json:"type" bson:"type"`
 	Actions     []Action           `json:"actions,omitempty" bson:"actions"`
+	// Relations define the namespace of the resource for relation tuples about its instances.
+	Relations []RelationDefinition `json:"relations,omitempty" bson:"relations,omitempty"`
+	// Policies must pass for every check on the resource, whoever the subject is.
+	Policies []primitive.ObjectID `json:"policies,omitempty" bson:"policies,omitempty"`
 }
 
 type Action struct {
@@ -43,6 +89,8 @@ type User struct {
 	Roles          []primitive.ObjectID   `json:"roles,omitempty" bson:"roles"`
 	Groups         []primitive.ObjectID   `json:"groups,omitempty" bson:"groups"`
 	Policies       []primitive.ObjectID   `json:"policies,omitempty" bson:"policies"`
+	// TimedRoles bounds when some of the roles of the user are held.
+	TimedRoles []TimedRole `json:"timed_roles,omitempty" bson:"timed_roles,omitempty"`
 }
 
 type AssignedUser struct {
@@ -58,6 +106,9 @@ type Role struct {
 	Users       []primitive.ObjectID `json:"users,omitempty" bson:"users"`
 	Groups      []primitive.ObjectID `json:"groups,omitempty" bson:"groups"`
 	Permissions []Permission         `json:"permissions,omitempty" bson:"permissions"`
+	ParentRoles []primitive.ObjectID `json:"parent_roles,omitempty" bson:"parent_roles"`
+	// Policies condition every permission the role holds, including the inherited ones.
+	Policies []primitive.ObjectID `json:"policies,omitempty" bson:"policies,omitempty"`
 }
 
 type AssignedRole struct {
@@ -66,6 +117,8 @@ type AssignedRole struct {
 	DisplayName string             `json:"display_name" bson:"display_name"`
 }
 
+// Group holds users, roles and policies. Groups listed in Groups are members of the group,
+// so their users get everything the group holds.
 type Group struct {
 	ID          primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
 	Identifier  string               `json:"identifier" bson:"identifier"`
@@ -73,6 +126,9 @@ type Group struct {
 	Users       []primitive.ObjectID `json:"users,omitempty" bson:"users"`
 	Roles       []primitive.ObjectID `json:"roles,omitempty" bson:"roles"`
 	Policies    []primitive.ObjectID `json:"policies,omitempty" bson:"policies"`
+	Groups      []primitive.ObjectID `json:"groups,omitempty" bson:"groups"`
+	// TimedRoles bounds when some of the roles of the group are held.
+	TimedRoles []TimedRole `json:"timed_roles,omitempty" bson:"timed_roles,omitempty"`
 }
 
 type AssignedGroup struct {
@@ -81,17 +137,97 @@ type AssignedGroup struct {
 	DisplayName string             `json:"display_name" bson:"display_name"`
 }
 
+type Effect string
+
+const (
+	AllowEffect Effect = "allow"
+	DenyEffect  Effect = "deny"
+)
+
+// Permission grants an action on a resource, or denies it when the effect is deny.
+// An empty effect is an allow. A permission with policies only takes part in a check
+// when all of its policies pass.
 type Permission struct {
-	Action   string `json:"action" bson:"action"`
-	Resource string `json:"resource" bson:"resource"`
+	Action   string               `json:"action" bson:"action"`
+	Resource string               `json:"resource" bson:"resource"`
+	Effect   Effect               `json:"effect,omitempty" bson:"effect,omitempty"`
+	Policies []primitive.ObjectID `json:"policies,omitempty" bson:"policies,omitempty"`
+}
+
+// PermissionKey identifies a permission by its resource and action.
+type PermissionKey struct {
+	Resource string
+	Action   string
+}
+
+func (p Permission) Key() PermissionKey {
+
+	return PermissionKey{Resource: p.Resource, Action: p.Action}
+}
+
+func (p Permission) Denies() bool {
+
+	return p.Effect == DenyEffect
+}
+
+// ConditionedPermissions returns the permissions of the role, each conditioned by the policies of the role.
+func (r Role) ConditionedPermissions() []Permission {
+
+	if len(r.Policies) == 0 {
+		return r.Permissions
+	}
+	permissions := make([]Permission, 0, len(r.Permissions))
+	for _, permission := range r.Permissions {
+		permission.Policies = append(append([]primitive.ObjectID{}, permission.Policies...), r.Policies...)
+		permissions = append(permissions, permission)
+	}
+	return permissions
 }
 
 type Policy struct {
-	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Identifier     string             `json:"identifier" bson:"identifier"`
-	DisplayName    string             `json:"display_name" bson:"display_name"`
-	ActiveVersion  string             `json:"active_version" bson:"active_version"`
-	PolicyContents []PolicyContent    `json:"policy_contents" bson:"policy_contents"`
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Identifier    string             `json:"identifier" bson:"identifier"`
+	DisplayName   string             `json:"display_name" bson:"display_name"`
+	ActiveVersion string             `json:"active_version" bson:"active_version"`
+	// ShadowVersion is evaluated alongside the active version in checks, only to report where they disagree.
+	ShadowVersion string `json:"shadow_version,omitempty" bson:"shadow_version,omitempty"`
+	// Language is the policy language of the versions which do not name their own.
+	Language       string          `json:"language,omitempty" bson:"language,omitempty"`
+	PolicyContents []PolicyContent `json:"policy_contents" bson:"policy_contents"`
+	// TestCases must all pass for a version before it becomes the active version.
+	TestCases []PolicyTestCase `json:"test_cases,omitempty" bson:"test_cases,omitempty"`
+	// Audit records the rollbacks of the policy and the edits of its versions, oldest first.
+	Audit []PolicyAuditEntry `json:"audit,omitempty" bson:"audit,omitempty"`
+}
+
+// PolicyAuditAction names what an audit entry of a policy records.
+type PolicyAuditAction string
+
+const (
+	PolicyRollback PolicyAuditAction = "rollback"
+	PolicyEdit     PolicyAuditAction = "edit"
+)
+
+// PolicyAuditEntry records who moved the active version of a policy, when and from which version,
+// or who edited a version of the policy in place.
+type PolicyAuditEntry struct {
+	Action          PolicyAuditAction `json:"action" bson:"action"`
+	Version         string            `json:"version" bson:"version"`
+	PreviousVersion string            `json:"previous_version,omitempty" bson:"previous_version,omitempty"`
+	Actor           string            `json:"actor,omitempty" bson:"actor,omitempty"`
+	At              time.Time         `json:"at" bson:"at"`
+	Comment         string            `json:"comment,omitempty" bson:"comment,omitempty"`
+}
+
+// PolicyTestCase is a named sample input of a policy, along with the result the policy must give for it.
+type PolicyTestCase struct {
+	Name           string                 `json:"name" bson:"name"`
+	Subject        string                 `json:"subject,omitempty" bson:"subject,omitempty"`
+	Action         string                 `json:"action,omitempty" bson:"action,omitempty"`
+	Resource       string                 `json:"resource,omitempty" bson:"resource,omitempty"`
+	UserProperties map[string]interface{} `json:"user_properties,omitempty" bson:"user_properties,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty" bson:"context,omitempty"`
+	Expected       bool                   `json:"expected" bson:"expected"`
 }
 
 type AssignedPolicy struct {
@@ -102,7 +238,45 @@ type AssignedPolicy struct {
 }
 
 type PolicyContent struct {
-	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Version string             `json:"version" bson:"version"`
-	Policy  string             `json:"policy" bson:"policy"`
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Version  string             `json:"version" bson:"version"`
+	Language string             `json:"language,omitempty" bson:"language,omitempty"`
+	Policy   string             `json:"policy" bson:"policy"`
+	// Hash identifies the compiled form of the policy, as compiled when the version was written.
+	Hash string `json:"hash,omitempty" bson:"hash,omitempty"`
+	// CreatedAt and CreatedBy are when and by whom the version was added, unknown for older versions.
+	CreatedAt *time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	Comment   string     `json:"comment,omitempty" bson:"comment,omitempty"`
+}
+
+// Content returns the given version of the policy.
+func (p Policy) Content(version string) (PolicyContent, bool) {
+
+	for _, content := range p.PolicyContents {
+		if content.Version == version {
+			return content, true
+		}
+	}
+	return PolicyContent{}, false
+}
+
+// HasTestCase reports whether the policy has a test case of the name.
+func (p Policy) HasTestCase(name string) bool {
+
+	for _, testCase := range p.TestCases {
+		if testCase.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentLanguage returns the policy language of a version of the policy.
+func (p Policy) ContentLanguage(content PolicyContent) string {
+
+	if content.Language != "" {
+		return content.Language
+	}
+	return p.Language
 }
@@ -0,0 +1,63 @@
+package mongo_entity
+
+import "strings"
+
+// Wildcard matches exactly one non-empty segment of a resource or action, and DeepWildcard
+// matches one or more of them, so "doc:*" matches "doc:a" while "doc:**" also matches "doc:a:b".
+const (
+	Wildcard     = "*"
+	DeepWildcard = "**"
+)
+
+// Segment separators of resources ("projects/*/documents") and actions ("invoices:*").
+const (
+	ResourceSeparator = "/"
+	ActionSeparator   = ":"
+)
+
+// Matches reports whether the permission applies to the given resource and action, whatever its effect.
+func (p Permission) Matches(resource string, action string) bool {
+
+	return MatchResource(p.Resource, resource) && MatchAction(p.Action, action)
+}
+
+func MatchResource(pattern string, resource string) bool {
+
+	return matchPattern(pattern, resource, ResourceSeparator)
+}
+
+func MatchAction(pattern string, action string) bool {
+
+	return matchPattern(pattern, action, ActionSeparator)
+}
+
+func matchPattern(pattern string, value string, separator string) bool {
+
+	if pattern == value {
+		return true
+	}
+	return matchSegments(strings.Split(pattern, separator), strings.Split(value, separator))
+}
+
+func matchSegments(pattern []string, value []string) bool {
+
+	if len(pattern) == 0 {
+		return len(value) == 0
+	}
+	if len(value) == 0 || value[0] == "" {
+		return false
+	}
+	switch pattern[0] {
+	case DeepWildcard:
+		for i := 1; i <= len(value) && value[i-1] != ""; i++ {
+			if matchSegments(pattern[1:], value[i:]) {
+				return true
+			}
+		}
+		return false
+	case Wildcard:
+		return matchSegments(pattern[1:], value[1:])
+	default:
+		return pattern[0] == value[0] && matchSegments(pattern[1:], value[1:])
+	}
+}
@@ -0,0 +1,46 @@
+package mongo_entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MatchAction(t *testing.T) {
+	tests := []struct {
+		pattern string
+		action  string
+		want    bool
+	}{
+		{"doc:read", "doc:read", true},
+		{"doc:*", "doc:read", true},
+		// a wildcard matches one segment, never an empty one or several of them
+		{"doc:*", "doc:", false},
+		{"doc:*", "doc", false},
+		{"doc:*", "doc:a:b:c", false},
+		{"*", "read", true},
+		{"*", "doc:read", false},
+		{"*", "", false},
+		{"doc:*:read", "doc:a:read", true},
+		{"doc:*:read", "doc::read", false},
+		// a deep wildcard matches one or more non-empty segments
+		{"doc:**", "doc:a", true},
+		{"doc:**", "doc:a:b:c", true},
+		{"doc:**", "doc:", false},
+		{"doc:**", "doc", false},
+		{"doc:**", "doc:a::c", false},
+		{"**", "doc:a:b", true},
+		{"doc:**:read", "doc:a:b:read", true},
+		{"doc:**:read", "doc:read", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchAction(tt.pattern, tt.action), "%q matching %q", tt.pattern, tt.action)
+	}
+}
+
+func Test_MatchResource(t *testing.T) {
+	assert.True(t, MatchResource("projects/*/documents", "projects/apollo/documents"))
+	assert.False(t, MatchResource("projects/*/documents", "projects//documents"))
+	assert.False(t, MatchResource("projects/*", "projects/apollo/documents"))
+	assert.True(t, MatchResource("projects/**", "projects/apollo/documents"))
+}
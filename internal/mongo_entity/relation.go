@@ -0,0 +1,172 @@
+package mongo_entity
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// UserSubjectType is the type of subjects which are users of the organization, by identifier.
+	UserSubjectType = "user"
+	// GroupObjectType is the built-in namespace of the groups of the organization, by identifier.
+	GroupObjectType = "group"
+	// MemberRelation of a group holds its users, directly or through nested groups.
+	MemberRelation = "member"
+	// MaxRelationDepth limits how many relations are followed while resolving a check.
+	MaxRelationDepth = 25
+	// MaxRelationChanges is how many of the latest relation changes are kept for watchers.
+	MaxRelationChanges = 1000
+)
+
+type RelationOperation string
+
+const (
+	WriteRelation  RelationOperation = "write"
+	DeleteRelation RelationOperation = "delete"
+)
+
+// RelationTuple states that a subject has a relation to an object, written object#relation@subject,
+// e.g. document:42#viewer@user:alice. A subject with a relation is a subject set, e.g.
+// document:42#viewer@group:engineering#member gives every member of the group the relation.
+type RelationTuple struct {
+	ObjectType      string `json:"object_type" bson:"object_type"`
+	ObjectID        string `json:"object_id" bson:"object_id"`
+	Relation        string `json:"relation" bson:"relation"`
+	SubjectType     string `json:"subject_type" bson:"subject_type"`
+	SubjectID       string `json:"subject_id" bson:"subject_id"`
+	SubjectRelation string `json:"subject_relation,omitempty" bson:"subject_relation,omitempty"`
+}
+
+// RelationChange records a relation tuple written or deleted at a revision of the organization's relations.
+type RelationChange struct {
+	Revision  int64             `json:"revision" bson:"revision"`
+	Operation RelationOperation `json:"operation" bson:"operation"`
+	Tuple     RelationTuple     `json:"tuple" bson:"tuple"`
+}
+
+// RelationDefinition is a relation of a resource type. Without a rewrite the relation only
+// holds the subjects of its own tuples.
+type RelationDefinition struct {
+	Name    string    `json:"name" bson:"name"`
+	Rewrite []Userset `json:"rewrite,omitempty" bson:"rewrite,omitempty"`
+}
+
+// Userset is one operand of the union forming a relation. Exactly one field is set.
+type Userset struct {
+	// This includes the subjects of the relation's own tuples.
+	This bool `json:"this,omitempty" bson:"this,omitempty"`
+	// ComputedUserset includes the subjects of another relation of the same object.
+	ComputedUserset string `json:"computed_userset,omitempty" bson:"computed_userset,omitempty"`
+	// TupleToUserset includes the subjects of a relation of the objects the tupleset points to.
+	TupleToUserset *TupleToUserset `json:"tuple_to_userset,omitempty" bson:"tuple_to_userset,omitempty"`
+}
+
+// TupleToUserset follows the tuples of the tupleset relation, e.g. document#parent@folder:1,
+// and includes the subjects of the computed relation of each object found, e.g. folder:1#viewer.
+type TupleToUserset struct {
+	Tupleset        string `json:"tupleset" bson:"tupleset"`
+	ComputedUserset string `json:"computed_userset" bson:"computed_userset"`
+}
+
+func (t RelationTuple) String() string {
+
+	tuple := t.ObjectType + ":" + t.ObjectID + "#" + t.Relation + "@" + t.SubjectType + ":" + t.SubjectID
+	if t.SubjectRelation != "" {
+		tuple += "#" + t.SubjectRelation
+	}
+	return tuple
+}
+
+// ParseRelationTuple parses a tuple written as object_type:object_id#relation@subject_type:subject_id[#subject_relation].
+func ParseRelationTuple(tuple string) (RelationTuple, error) {
+
+	object, subject, found := strings.Cut(tuple, "@")
+	if !found {
+		return RelationTuple{}, errors.New("missing subject in relation tuple " + tuple)
+	}
+	object, relation, found := strings.Cut(object, "#")
+	if !found {
+		return RelationTuple{}, errors.New("missing relation in relation tuple " + tuple)
+	}
+	objectType, objectID, found := strings.Cut(object, ":")
+	if !found {
+		return RelationTuple{}, errors.New("missing object id in relation tuple " + tuple)
+	}
+	subject, subjectRelation, _ := strings.Cut(subject, "#")
+	subjectType, subjectID, found := strings.Cut(subject, ":")
+	if !found {
+		return RelationTuple{}, errors.New("missing subject id in relation tuple " + tuple)
+	}
+	parsed := RelationTuple{
+		ObjectType:      objectType,
+		ObjectID:        objectID,
+		Relation:        relation,
+		SubjectType:     subjectType,
+		SubjectID:       subjectID,
+		SubjectRelation: subjectRelation,
+	}
+	return parsed, parsed.Validate()
+}
+
+// Validate checks that every part of the tuple is given.
+func (t RelationTuple) Validate() error {
+
+	if t.ObjectType == "" || t.ObjectID == "" || t.Relation == "" || t.SubjectType == "" || t.SubjectID == "" {
+		return errors.New("incomplete relation tuple " + t.String())
+	}
+	return nil
+}
+
+// FindRelation returns the definition of the relation with the given name.
+func FindRelation(definitions []RelationDefinition, name string) (RelationDefinition, bool) {
+
+	for _, definition := range definitions {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+	return RelationDefinition{}, false
+}
+
+// ValidateRelationDefinitions checks that relation names are unique and that rewrites only
+// refer to relations of the same resource type.
+func ValidateRelationDefinitions(definitions []RelationDefinition) error {
+
+	names := make(map[string]bool)
+	for _, definition := range definitions {
+		if definition.Name == "" {
+			return errors.New("relation name is required")
+		}
+		if names[definition.Name] {
+			return errors.New("duplicate relation " + definition.Name)
+		}
+		names[definition.Name] = true
+	}
+	for _, definition := range definitions {
+		for _, userset := range definition.Rewrite {
+			set := 0
+			if userset.This {
+				set++
+			}
+			if userset.ComputedUserset != "" {
+				set++
+				if !names[userset.ComputedUserset] {
+					return errors.New("relation " + definition.Name + " refers to unknown relation " + userset.ComputedUserset)
+				}
+			}
+			if userset.TupleToUserset != nil {
+				set++
+				if !names[userset.TupleToUserset.Tupleset] {
+					return errors.New("relation " + definition.Name + " refers to unknown relation " + userset.TupleToUserset.Tupleset)
+				}
+				if userset.TupleToUserset.ComputedUserset == "" {
+					return errors.New("relation " + definition.Name + " is missing the computed userset of its tuple to userset")
+				}
+			}
+			if set != 1 {
+				return errors.New("each userset of relation " + definition.Name + " must set exactly one of this, computed_userset or tuple_to_userset")
+			}
+		}
+	}
+	return nil
+}
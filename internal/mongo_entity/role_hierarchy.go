@@ -0,0 +1,48 @@
+package mongo_entity
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AncestorRoles returns every role the given role inherits from, nearest parents first.
+// Each role is returned once, so cycles in stored data can't loop forever.
+func AncestorRoles(roles []Role, id primitive.ObjectID) []Role {
+
+	byID := make(map[primitive.ObjectID]Role)
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+	visited := map[primitive.ObjectID]bool{id: true}
+	queue := append([]primitive.ObjectID{}, byID[id].ParentRoles...)
+	var ancestors []Role
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		if visited[parentID] {
+			continue
+		}
+		visited[parentID] = true
+		parent, exists := byID[parentID]
+		if !exists {
+			continue
+		}
+		ancestors = append(ancestors, parent)
+		queue = append(queue, parent.ParentRoles...)
+	}
+	return ancestors
+}
+
+// CreatesRoleCycle reports whether making the given roles parents of the role with the given id
+// would let the role inherit from itself.
+func CreatesRoleCycle(roles []Role, id primitive.ObjectID, parentIDs []primitive.ObjectID) bool {
+
+	for _, parentID := range parentIDs {
+		if parentID == id {
+			return true
+		}
+		for _, ancestor := range AncestorRoles(roles, parentID) {
+			if ancestor.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package mongo_entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimedRole bounds when a role assigned to a user or a group is held. A bound which is left out does
+// not limit the assignment.
+type TimedRole struct {
+	Role      primitive.ObjectID `json:"role" bson:"role"`
+	NotBefore *time.Time         `json:"not_before,omitempty" bson:"not_before,omitempty"`
+	ExpiresAt *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+// Bounded reports whether the assignment is limited in time at all.
+func (t TimedRole) Bounded() bool {
+
+	return t.NotBefore != nil || t.ExpiresAt != nil
+}
+
+// Held reports whether the role is held at the time.
+func (t TimedRole) Held(now time.Time) bool {
+
+	return (t.NotBefore == nil || !now.Before(*t.NotBefore)) && !t.Expired(now)
+}
+
+// Valid reports whether the bounds can ever hold the role after the time: an assignment must expire after
+// it starts, and in the future.
+func (t TimedRole) Valid(now time.Time) bool {
+
+	if t.ExpiresAt == nil {
+		return true
+	}
+	return t.ExpiresAt.After(now) && (t.NotBefore == nil || t.ExpiresAt.After(*t.NotBefore))
+}
+
+// Expired reports whether the assignment has ended at the time, never to be held again.
+func (t TimedRole) Expired(now time.Time) bool {
+
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// HeldRoles returns the assigned roles which are held at the time, leaving out the roles whose
+// assignment has not started yet or has expired.
+func HeldRoles(roles []primitive.ObjectID, timed []TimedRole, now time.Time) []primitive.ObjectID {
+
+	if len(timed) == 0 {
+		return roles
+	}
+	bounds := make(map[primitive.ObjectID]TimedRole, len(timed))
+	for _, t := range timed {
+		bounds[t.Role] = t
+	}
+	held := make([]primitive.ObjectID, 0, len(roles))
+	for _, role := range roles {
+		if t, bounded := bounds[role]; !bounded || t.Held(now) {
+			held = append(held, role)
+		}
+	}
+	return held
+}
+
+// NextRoleTransition returns the first time after now when a role assigned to one of the users or
+// groups starts or stops being held. It is zero when no such time is ahead.
+func NextRoleTransition(users []User, groups []Group, now time.Time) time.Time {
+
+	var next time.Time
+	consider := func(timed []TimedRole) {
+		for _, t := range timed {
+			for _, bound := range []*time.Time{t.NotBefore, t.ExpiresAt} {
+				if bound != nil && bound.After(now) && (next.IsZero() || bound.Before(next)) {
+					next = *bound
+				}
+			}
+		}
+	}
+	for _, user := range users {
+		consider(user.TimedRoles)
+	}
+	for _, group := range groups {
+		consider(group.TimedRoles)
+	}
+	return next
+}
+
+// TimedRoleUpdates are the updates which replace the bounds of the given roles of a user or group, matched
+// with the positional operator in the holders array ("users" or "groups"). The old bounds of every role are
+// pulled, whether the new assignment is bounded or not, and only bounded assignments are pushed back. They
+// are separate updates, as one update can not both pull from and push to an array.
+func TimedRoleUpdates(holders string, timed []TimedRole) []bson.M {
+
+	roles := make([]primitive.ObjectID, 0, len(timed))
+	bounded := []TimedRole{}
+	for _, t := range timed {
+		roles = append(roles, t.Role)
+		if t.Bounded() {
+			bounded = append(bounded, t)
+		}
+	}
+	updates := []bson.M{{"$pull": bson.M{holders + ".$.timed_roles": bson.M{"role": bson.M{"$in": roles}}}}}
+	if len(bounded) > 0 {
+		updates = append(updates, bson.M{"$push": bson.M{holders + ".$.timed_roles": bson.M{"$each": bounded}}})
+	}
+	return updates
+}
@@ -0,0 +1,29 @@
+package mongo_entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_TimedRoleUpdates(t *testing.T) {
+	role, other := primitive.NewObjectID(), primitive.NewObjectID()
+	expiresAt := time.Now().Add(time.Hour)
+
+	// granting a role for good drops the bounds it had before, so a sweep does not remove it later
+	updates := TimedRoleUpdates("users", []TimedRole{{Role: role}})
+	assert.Equal(t, []bson.M{
+		{"$pull": bson.M{"users.$.timed_roles": bson.M{"role": bson.M{"$in": []primitive.ObjectID{role}}}}},
+	}, updates)
+
+	// bounded roles are pushed back after the old bounds of every role are pulled
+	bounded := TimedRole{Role: role, ExpiresAt: &expiresAt}
+	updates = TimedRoleUpdates("groups", []TimedRole{bounded, {Role: other}})
+	assert.Equal(t, []bson.M{
+		{"$pull": bson.M{"groups.$.timed_roles": bson.M{"role": bson.M{"$in": []primitive.ObjectID{role, other}}}}},
+		{"$push": bson.M{"groups.$.timed_roles": bson.M{"$each": []TimedRole{bounded}}}},
+	}, updates)
+}
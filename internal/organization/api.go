@@ -13,6 +13,7 @@ func RegisterHandlers(r *echo.Group, service Service) {
 	router.GET("", res.query)
 	router.GET("/:id", res.get)
 	router.POST("", res.create)
+	router.PUT("/:id", res.update)
 	router.DELETE("/:id", res.delete)
 	router.POST("/:id/regenerate-key", res.regenerateAPIKey)
 }
@@ -73,6 +74,28 @@ func (r resource) create(c echo.Context) error {
 	return c.JSON(http.StatusCreated, organization)
 }
 
+// @Description Update organization.
+// @Tags        Organization
+// @Accept      json
+// @Param id path string true "Organization ID"
+// @Param request body UpdateOrganizationRequest true "body"
+// @Produce     json
+// @Success     201 {object}  Organization
+// @failure     400,403,404,500
+// @Router      /organization/{id} [put]
+func (r resource) update(c echo.Context) error {
+
+	var req UpdateOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+	organization, err := r.service.Update(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusCreated, organization)
+}
+
 // @Description Delete organization.
 // @Tags        Organization
 // @Param id path string true "Organization ID"
@@ -16,7 +16,7 @@ func TestAPI(t *testing.T) {
 	repo := &mockRepository{orgs: []mongo_entity.Organization{
 		{ID: primitive.NewObjectID(), Identifier: "test", DisplayName: "test"},
 	}}
-	RegisterHandlers(router.Group(""), NewService(repo, logger))
+	RegisterHandlers(router.Group(""), NewService(repo, logger, nil))
 	header := middleware.MockAuthHeader()
 
 	tests := []test.APITestCase{
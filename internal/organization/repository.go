@@ -6,6 +6,7 @@ import (
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,9 +19,11 @@ Get(ctx context.Context, id string) (*mongo_entity.Organization, error)
 	Query(ctx context.Context) ([]mongo_entity.Organization, error)
 	Create(ctx context.Context, organization mongo_entity.Organization) (string, error)
 	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, id string, update_organization UpdateOrganization) error
 	RefreshAPIKey(ctx context.Context, apiKey string, id string) error
 	CheckOrgExistById(ctx context.Context, id string) (bool, error)
 	CheckOrgExistByIdentifier(ctx context.Context, identifier string) (bool, error)
+	GetOrganizationRevision(ctx context.Context, id string) (int64, error)
 }
 
 type repository struct {
@@ -223,3 +226,33 @@ func (r repository) CheckOrgExistByIdentifier(ctx context.Context, identifier st
 		return false, result.Err()
 	}
 }
+
+// Update organization.
+func (r repository) Update(ctx context.Context, id string, update_organization UpdateOrganization) error {
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{}
+	if update_organization.DisplayName != nil {
+		set["display_name"] = *update_organization.DisplayName
+	}
+	if update_organization.CombiningAlgorithm != nil {
+		set["combining_algorithm"] = *update_organization.CombiningAlgorithm
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	filter := bson.M{"_id": objID}
+	update := bson.M{"$set": set}
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
+	return err
+}
+
+func (r repository) GetOrganizationRevision(ctx context.Context, id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, id)
+}
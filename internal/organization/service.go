@@ -5,7 +5,9 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.uber.org/zap"
 
@@ -17,6 +19,7 @@ type Service interface {
 	GetIdByIdentifier(ctx context.Context, identifier string) (string, error)
 	Query(ctx context.Context) ([]Organization, error)
 	Create(ctx context.Context, req OrganizationCreationRequest) (Organization, error)
+	Update(ctx context.Context, id string, req UpdateOrganizationRequest) (Organization, error)
 	RegenerateAPIKey(ctx context.Context, id string) (Organization, error)
 	Delete(ctx context.Context, id string) (Organization, error)
 	CheckOrgExistByIdentifier(ctx context.Context, identifier string) (bool, error)
@@ -44,13 +47,49 @@ func (m OrganizationCreationRequest) Validate() error {
 	)
 }
 
+type UpdateOrganizationRequest struct {
+	DisplayName        *string                          `json:"display_name" bson:"display_name"`
+	CombiningAlgorithm *mongo_entity.CombiningAlgorithm `json:"combining_algorithm" bson:"combining_algorithm"`
+}
+
+func (m UpdateOrganizationRequest) Validate() error {
+
+	algorithms := make([]interface{}, 0, len(mongo_entity.CombiningAlgorithms))
+	for _, algorithm := range mongo_entity.CombiningAlgorithms {
+		algorithms = append(algorithms, algorithm)
+	}
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.DisplayName, validation.NilOrNotEmpty),
+		validation.Field(&m.CombiningAlgorithm, validation.NilOrNotEmpty, validation.In(algorithms...)),
+	)
+}
+
+type UpdateOrganization struct {
+	DisplayName        *string                          `json:"display_name" bson:"display_name"`
+	CombiningAlgorithm *mongo_entity.CombiningAlgorithm `json:"combining_algorithm" bson:"combining_algorithm"`
+}
+
 type service struct {
 	repo   Repository
 	logger *zap.Logger
+	events *event.Bus
+}
+
+func NewService(repo Repository, logger *zap.Logger, events *event.Bus) Service {
+	return service{repo: repo, logger: logger, events: events}
 }
 
-func NewService(repo Repository, logger *zap.Logger) Service {
-	return service{repo: repo, logger: logger}
+// changed publishes a write to its settings, and tracks the revision the write moved the organization to.
+func (s service) changed(ctx context.Context, id string) error {
+
+	s.events.Publish(event.Event{OrganizationID: id, Entity: event.OrganizationEntity, ID: id})
+	rev, err := s.repo.GetOrganizationRevision(ctx, id)
+	if err != nil {
+		s.logger.Error("Error while reading organization revision.", zap.String("organization_id", id), zap.Error(err))
+		return err
+	}
+	revision.Track(ctx, rev)
+	return nil
 }
 
 // Get organization by id.
@@ -143,6 +182,7 @@ func (s service) Create(ctx context.Context, req OrganizationCreationRequest) (O
 		Roles:       roles,
 		Resources:   resources,
 		Polices:     policies,
+		Relations:   []mongo_entity.RelationTuple{},
 	})
 	if err != nil {
 		s.logger.Error("Error while creating organization.")
@@ -151,6 +191,33 @@ func (s service) Create(ctx context.Context, req OrganizationCreationRequest) (O
 	return s.Get(ctx, id)
 }
 
+// Update the display name or the combining algorithm of the organization.
+func (s service) Update(ctx context.Context, id string, req UpdateOrganizationRequest) (Organization, error) {
+
+	if err := req.Validate(); err != nil {
+		s.logger.Error("Error while validating organization update request.")
+		return Organization{}, &util.InvalidInputError{Path: "Invalid input for organization."}
+	}
+
+	exists, _ := s.repo.CheckOrgExistById(ctx, id)
+	if !exists {
+		s.logger.Debug("Organization not exists.", zap.String("organization_id", id))
+		return Organization{}, &util.NotFoundError{Path: "Organization " + id + " not exists."}
+	}
+
+	if err := s.repo.Update(ctx, id, UpdateOrganization{
+		DisplayName:        req.DisplayName,
+		CombiningAlgorithm: req.CombiningAlgorithm,
+	}); err != nil {
+		s.logger.Error("Error while updating organization.", zap.String("organization_id", id))
+		return Organization{}, err
+	}
+	if err := s.changed(ctx, id); err != nil {
+		return Organization{}, err
+	}
+	return s.Get(ctx, id)
+}
+
 // Delete organization by id.
 func (s service) Delete(ctx context.Context, id string) (Organization, error) {
 
@@ -187,6 +254,8 @@ func (s service) RegenerateAPIKey(ctx context.Context, id string) (Organization,
 		s.logger.Error("Error while updating organization.", zap.String("organization_id", id))
 		return Organization{}, err
 	}
+	// Checks validate API keys against their cached copy of the organization, which the old key must leave.
+	s.events.Publish(event.Event{OrganizationID: id, Entity: event.OrganizationEntity, ID: id})
 	organization, err := s.Get(ctx, id)
 	return organization, err
 }
@@ -13,7 +13,7 @@ import (
 
 func Test_service(t *testing.T) {
 	logger := test.InitLogger()
-	s := NewService(&mockRepository{}, logger)
+	s := NewService(&mockRepository{}, logger, nil)
 
 	ctx := context.Background()
 
@@ -33,6 +33,17 @@ func Test_service(t *testing.T) {
 	})
 	assert.NotNil(t, err)
 
+	// choose a combining algorithm
+	algorithm := mongo_entity.PermitOverrides
+	org, err = s.Update(ctx, org.ID.Hex(), UpdateOrganizationRequest{CombiningAlgorithm: &algorithm})
+	assert.Nil(t, err)
+	assert.Equal(t, mongo_entity.PermitOverrides, org.Algorithm())
+
+	// unknown combining algorithm
+	unknown := mongo_entity.CombiningAlgorithm("majority")
+	_, err = s.Update(ctx, org.ID.Hex(), UpdateOrganizationRequest{CombiningAlgorithm: &unknown})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
 }
 
 type mockRepository struct {
@@ -82,6 +93,29 @@ func (m *mockRepository) RefreshAPIKey(ctx context.Context, apiKey string, id st
 	}
 	return &util.NotFoundError{Path: "Organization"}
 }
+func (m *mockRepository) Update(ctx context.Context, id string, update_organization UpdateOrganization) error {
+	for i, org := range m.orgs {
+		if org.ID.Hex() == id {
+			if update_organization.DisplayName != nil {
+				m.orgs[i].DisplayName = *update_organization.DisplayName
+			}
+			if update_organization.CombiningAlgorithm != nil {
+				m.orgs[i].CombiningAlgorithm = *update_organization.CombiningAlgorithm
+			}
+			m.orgs[i].Revision++
+			return nil
+		}
+	}
+	return &util.NotFoundError{Path: "Organization"}
+}
+func (m *mockRepository) GetOrganizationRevision(ctx context.Context, id string) (int64, error) {
+	for _, org := range m.orgs {
+		if org.ID.Hex() == id {
+			return org.Revision, nil
+		}
+	}
+	return 0, &util.NotFoundError{Path: "Organization"}
+}
 func (m mockRepository) CheckOrgExistById(ctx context.Context, id string) (bool, error) {
 	for _, org := range m.orgs {
 		if org.ID.Hex() == id {
@@ -16,6 +16,11 @@ func RegisterHandlers(r *echo.Group, service Service) {
 	router.DELETE("/:id", res.delete)
 	router.PUT("/:id", res.update)
 	router.PATCH("/:id", res.patch)
+	router.POST("/evaluate", res.evaluate)
+	router.POST("/:id/evaluate", res.evaluate)
+	router.POST("/:id/test", res.test)
+	router.GET("/:id/diff", res.diff)
+	router.POST("/:id/rollback", res.rollback)
 }
 
 type resource struct {
@@ -135,6 +140,97 @@ func (r resource) patch(c echo.Context) error {
 	return c.JSON(http.StatusCreated, user)
 }
 
+// @Description Evaluate a policy against sample inputs. Without a policy ID, the policy content is given in the request.
+// @Tags        Policy
+// @Accept      json
+// @Param org_id path string true "Organization ID"
+// @Param id path string false "Policy ID"
+// @Param request body EvaluatePolicyRequest true "body"
+// @Produce     json
+// @Success     200 {object}  EvaluatePolicyResponse
+// @failure     400,403,404,500
+// @Router      /{org_id}/polcies/{id}/evaluate [post]
+func (r resource) evaluate(c echo.Context) error {
+
+	var input EvaluatePolicyRequest
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+
+	result, err := r.service.Evaluate(c.Request().Context(), c.Param("org_id"), c.Param("id"), input)
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Description Run the test cases of a policy against a version of it.
+// @Tags        Policy
+// @Accept      json
+// @Param org_id path string true "Organization ID"
+// @Param id path string true "Policy ID"
+// @Param request body TestPolicyRequest true "body"
+// @Produce     json
+// @Success     200 {object}  TestPolicyResponse
+// @failure     400,403,404,500
+// @Router      /{org_id}/polcies/{id}/test [post]
+func (r resource) test(c echo.Context) error {
+
+	var input TestPolicyRequest
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+
+	result, err := r.service.Test(c.Request().Context(), c.Param("org_id"), c.Param("id"), input)
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Description Diff two versions of a policy.
+// @Tags        Policy
+// @Param org_id path string true "Organization ID"
+// @Param id path string true "Policy ID"
+// @Param from query string true "Version to diff from"
+// @Param to query string false "Version to diff to, the active version by default"
+// @Produce     json
+// @Success     200 {object}  PolicyDiff
+// @failure     400,403,404,500
+// @Router      /{org_id}/polcies/{id}/diff [get]
+func (r resource) diff(c echo.Context) error {
+
+	diff, err := r.service.Diff(c.Request().Context(), c.Param("org_id"), c.Param("id"), c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+// @Description Roll a policy back to a previous version.
+// @Tags        Policy
+// @Accept      json
+// @Param org_id path string true "Organization ID"
+// @Param id path string true "Policy ID"
+// @Param request body RollbackPolicyRequest true "body"
+// @Produce     json
+// @Success     201 {object}  Policy
+// @failure     400,403,404,412,500
+// @Router      /{org_id}/polcies/{id}/rollback [post]
+func (r resource) rollback(c echo.Context) error {
+
+	var input RollbackPolicyRequest
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+
+	policy, err := r.service.Rollback(c.Request().Context(), c.Param("org_id"), c.Param("id"), input)
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusCreated, policy)
+}
+
 // // @Description Delete policy.
 // // @Tags        Policy
 // // @Param org_id path string true "Organization ID"
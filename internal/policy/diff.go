@@ -0,0 +1,74 @@
+package policy
+
+import "strings"
+
+// DiffOperation tells whether a line of a diff is kept, added or removed.
+type DiffOperation string
+
+const (
+	DiffEqual   DiffOperation = "="
+	DiffAdded   DiffOperation = "+"
+	DiffRemoved DiffOperation = "-"
+)
+
+type DiffLine struct {
+	Operation DiffOperation `json:"op"`
+	Text      string        `json:"text"`
+}
+
+// PolicyDiff is the difference between two versions of a policy.
+type PolicyDiff struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	FromLanguage string `json:"from_language"`
+	ToLanguage   string `json:"to_language"`
+	// Changed is false when both versions compile to the same policy, however their lines differ.
+	Changed bool       `json:"changed"`
+	Lines   []DiffLine `json:"lines"`
+}
+
+// diffLines is the line diff from one policy to another, along the longest common subsequence of their lines.
+func diffLines(from string, to string) []DiffLine {
+
+	a, b := strings.Split(from, "\n"), strings.Split(to, "\n")
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	lines := []DiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Operation: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, DiffLine{Operation: DiffRemoved, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Operation: DiffAdded, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Operation: DiffRemoved, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Operation: DiffAdded, Text: b[j]})
+	}
+	return lines
+}
@@ -0,0 +1,195 @@
+package policy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shashimalcse/cronuseo/internal/engine"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.uber.org/zap"
+)
+
+// EvaluatePolicyRequest evaluates a policy against sample inputs, without a check. The policy is either given
+// inline, or a stored version of a policy, the active version when no version is given.
+type EvaluatePolicyRequest struct {
+	Version string `json:"version,omitempty"`
+	Policy  string `json:"policy,omitempty"`
+	// Language of the inline policy, the language of the stored policy when empty.
+	Language string `json:"language,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	// Action and Resource are what a check would ask for.
+	Action         string                 `json:"action,omitempty"`
+	Resource       string                 `json:"resource,omitempty"`
+	UserProperties map[string]interface{} `json:"user_properties,omitempty"`
+	Context        map[string]interface{} `json:"context,omitempty"`
+}
+
+type EvaluatePolicyResponse struct {
+	Version  string `json:"version,omitempty"`
+	Language string `json:"language"`
+	Result   bool   `json:"result"`
+	// Error is why the policy could not be evaluated, in which case the result is false.
+	Error string `json:"error,omitempty"`
+}
+
+// TestPolicyRequest runs the test cases of a policy against a version of it. The version is either given
+// inline, or a stored version, the active version when no version is given.
+type TestPolicyRequest struct {
+	Version  string `json:"version,omitempty"`
+	Policy   string `json:"policy,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+type TestPolicyResponse struct {
+	Version string             `json:"version,omitempty"`
+	Passed  bool               `json:"passed"`
+	Results []PolicyTestResult `json:"results"`
+}
+
+type PolicyTestResult struct {
+	Name     string `json:"name"`
+	Expected bool   `json:"expected"`
+	Result   bool   `json:"result"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Failed lists the names of the test cases which did not pass.
+func (r TestPolicyResponse) Failed() []string {
+
+	failed := []string{}
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed = append(failed, result.Name)
+		}
+	}
+	return failed
+}
+
+// content resolves the policy content a dry run is about. Inline content wins over a stored version.
+func (s service) content(ctx context.Context, org_id string, id string, version string, policy string, language string) (mongo_entity.Policy, mongo_entity.PolicyContent, error) {
+
+	stored := mongo_entity.Policy{}
+	if id != "" {
+		found, err := s.Get(ctx, org_id, id)
+		if err != nil {
+			s.logger.Debug("Policy not exists.", zap.String("policy_id", id))
+			return stored, mongo_entity.PolicyContent{}, &util.NotFoundError{Path: "Policy " + id + " not exists."}
+		}
+		stored = found.Policy
+	}
+
+	if policy != "" {
+		if language == "" {
+			language = stored.Language
+		}
+		if err := s.checkLanguage(language); err != nil {
+			return stored, mongo_entity.PolicyContent{}, err
+		}
+		return stored, mongo_entity.PolicyContent{Version: version, Language: language, Policy: policy}, nil
+	}
+	if id == "" {
+		return stored, mongo_entity.PolicyContent{}, &util.InvalidInputError{Path: "Invalid input for policy."}
+	}
+
+	if version == "" {
+		version = stored.ActiveVersion
+	}
+	content, ok := stored.Content(version)
+	if !ok {
+		return stored, mongo_entity.PolicyContent{}, &util.InvalidInputError{Path: "Invalid policy version " + version}
+	}
+	content.Language = stored.ContentLanguage(content)
+	return stored, content, nil
+}
+
+// Evaluate a policy against sample inputs. The id is empty when only inline policy content is evaluated.
+func (s service) Evaluate(ctx context.Context, org_id string, id string, req EvaluatePolicyRequest) (EvaluatePolicyResponse, error) {
+
+	_, content, err := s.content(ctx, org_id, id, req.Version, req.Policy, req.Language)
+	if err != nil {
+		return EvaluatePolicyResponse{}, err
+	}
+
+	response := EvaluatePolicyResponse{Version: content.Version, Language: string(engine.LanguageOf(content.Language))}
+	input := engine.NewInput(req.Subject, req.UserProperties, req.Context)
+	input.Action, input.ResourceIdentifier = req.Action, req.Resource
+	if response.Result, err = s.engines.Evaluate(engine.Language(content.Language), content.Policy, input); err != nil {
+		response.Result = false
+		response.Error = err.Error()
+	}
+	return response, nil
+}
+
+// Test runs the stored test cases of a policy against a version of it.
+func (s service) Test(ctx context.Context, org_id string, id string, req TestPolicyRequest) (TestPolicyResponse, error) {
+
+	policy, content, err := s.content(ctx, org_id, id, req.Version, req.Policy, req.Language)
+	if err != nil {
+		return TestPolicyResponse{}, err
+	}
+	return s.runTests(policy.TestCases, content), nil
+}
+
+// testInput is what a policy is evaluated against for a test case.
+func testInput(testCase mongo_entity.PolicyTestCase) engine.Input {
+
+	input := engine.NewInput(testCase.Subject, testCase.UserProperties, testCase.Context)
+	input.Action, input.ResourceIdentifier = testCase.Action, testCase.Resource
+	return input
+}
+
+// runTests evaluates the test cases against the policy content. A test case which can not be evaluated fails.
+func (s service) runTests(cases []mongo_entity.PolicyTestCase, content mongo_entity.PolicyContent) TestPolicyResponse {
+
+	response := TestPolicyResponse{Version: content.Version, Passed: true, Results: []PolicyTestResult{}}
+	compiled, compileErr := s.engines.Compile(engine.Language(content.Language), content.Policy)
+	for _, testCase := range cases {
+		result := PolicyTestResult{Name: testCase.Name, Expected: testCase.Expected}
+		if compileErr != nil {
+			result.Error = compileErr.Error()
+		} else if value, err := compiled.Evaluate(testInput(testCase)); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Result = value
+			result.Passed = value == testCase.Expected
+		}
+		if !result.Passed {
+			response.Passed = false
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response
+}
+
+// checkActivation makes sure every test case of the policy passes for the content, before it becomes active.
+func (s service) checkActivation(cases []mongo_entity.PolicyTestCase, content mongo_entity.PolicyContent) error {
+
+	response := s.runTests(cases, content)
+	if !response.Passed {
+		failed := response.Failed()
+		s.logger.Debug("Policy test cases failed.", zap.String("version", content.Version), zap.Strings("test_cases", failed))
+		return &util.PreconditionFailedError{Path: "policy test cases failed for version " + content.Version + ": " + strings.Join(failed, ", ")}
+	}
+	return nil
+}
+
+// checkTestCases validates the test cases added to a policy which already has the given test cases.
+func checkTestCases(existing []mongo_entity.PolicyTestCase, added []mongo_entity.PolicyTestCase) error {
+
+	names := map[string]bool{}
+	for _, testCase := range existing {
+		names[testCase.Name] = true
+	}
+	for _, testCase := range added {
+		if testCase.Name == "" {
+			return &util.InvalidInputError{Path: "Invalid input for policy test case."}
+		}
+		if names[testCase.Name] {
+			return &util.AlreadyExistsError{Path: "Policy test case : " + testCase.Name}
+		}
+		names[testCase.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"context"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/engine"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.uber.org/zap"
+)
+
+// Diff two versions of a policy. The version diffed to is the active version when it is not given.
+func (s service) Diff(ctx context.Context, org_id string, id string, from string, to string) (PolicyDiff, error) {
+
+	policy, err := s.Get(ctx, org_id, id)
+	if err != nil {
+		s.logger.Debug("Policy not exists.", zap.String("policy_id", id))
+		return PolicyDiff{}, &util.NotFoundError{Path: "Policy " + id + " not exists."}
+	}
+	if to == "" {
+		to = policy.ActiveVersion
+	}
+	fromContent, ok := policy.Content(from)
+	if !ok {
+		return PolicyDiff{}, &util.InvalidInputError{Path: "Invalid policy version " + from}
+	}
+	toContent, ok := policy.Content(to)
+	if !ok {
+		return PolicyDiff{}, &util.InvalidInputError{Path: "Invalid policy version " + to}
+	}
+
+	fromLanguage := string(engine.LanguageOf(policy.ContentLanguage(fromContent)))
+	toLanguage := string(engine.LanguageOf(policy.ContentLanguage(toContent)))
+	return PolicyDiff{
+		From:         from,
+		To:           to,
+		FromLanguage: fromLanguage,
+		ToLanguage:   toLanguage,
+		Changed:      s.contentHash(fromLanguage, fromContent) != s.contentHash(toLanguage, toContent),
+		Lines:        diffLines(fromContent.Policy, toContent.Policy),
+	}, nil
+}
+
+// contentHash identifies the compiled form of a version. Versions stored before their hash was kept, or
+// which do not compile, are identified by their language and content.
+func (s service) contentHash(language string, content mongo_entity.PolicyContent) string {
+
+	if content.Hash != "" {
+		return content.Hash
+	}
+	if compiled, err := s.engines.Compile(engine.Language(language), content.Policy); err == nil {
+		return compiled.Hash()
+	}
+	return language + "\x00" + content.Policy
+}
+
+// Rollback activates a previous version of a policy, and records who did so in the audit of the policy.
+func (s service) Rollback(ctx context.Context, org_id string, id string, req RollbackPolicyRequest) (Policy, error) {
+
+	policy, err := s.Get(ctx, org_id, id)
+	if err != nil {
+		s.logger.Debug("Policy not exists.", zap.String("policy_id", id))
+		return Policy{}, &util.NotFoundError{Path: "Policy " + id + " not exists."}
+	}
+	content, ok := policy.Content(req.Version)
+	if !ok {
+		return Policy{}, &util.InvalidInputError{Path: "Invalid policy version " + req.Version}
+	}
+	if req.Version == policy.ActiveVersion {
+		return Policy{}, &util.PreconditionFailedError{Path: "policy version " + req.Version + " is already active"}
+	}
+	content.Language = policy.ContentLanguage(content)
+	if err := s.checkActivation(policy.TestCases, content); err != nil {
+		return Policy{}, err
+	}
+
+	entry := mongo_entity.PolicyAuditEntry{
+		Action:          mongo_entity.PolicyRollback,
+		Version:         req.Version,
+		PreviousVersion: policy.ActiveVersion,
+		Actor:           util.Actor(ctx),
+		At:              time.Now(),
+		Comment:         req.Comment,
+	}
+	if err := s.repo.Rollback(ctx, org_id, id, entry); err != nil {
+		s.logger.Error("Error while rolling back policy.",
+			zap.String("organization_id", org_id),
+			zap.String("policy_id", id))
+		return Policy{}, err
+	}
+	if policy.ShadowVersion == req.Version {
+		// The shadow version stops once it is active.
+		stopped := ""
+		if err := s.repo.Update(ctx, org_id, id, UpdatePolicy{ShadowVersion: &stopped}); err != nil {
+			s.logger.Error("Error while stopping shadow policy version.",
+				zap.String("organization_id", org_id),
+				zap.String("policy_id", id))
+			return Policy{}, err
+		}
+	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return Policy{}, err
+	}
+	return s.Get(ctx, org_id, id)
+}
@@ -5,6 +5,7 @@ import (
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,10 +19,12 @@ type Repository interface {
 	Query(ctx context.Context, org_id string) (*[]mongo_entity.Policy, error)
 	Update(ctx context.Context, org_id string, id string, update_user UpdatePolicy) error
 	Patch(ctx context.Context, org_id string, id string, patch_user PatchPolicy) error
+	Rollback(ctx context.Context, org_id string, id string, entry mongo_entity.PolicyAuditEntry) error
 	Delete(ctx context.Context, org_id string, id string) error
 	CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error)
 	CheckPolicyExistsByIdentifier(ctx context.Context, org_id string, identifier string) (bool, error)
 	CheckPolicyContentExistsByVersion(ctx context.Context, org_id string, version string) (bool, error)
+	GetOrganizationRevision(ctx context.Context, org_id string) (int64, error)
 }
 
 type repository struct {
@@ -80,7 +83,7 @@ func (r repository) Create(ctx context.Context, org_id string, policy mongo_enti
 	// Update the APIResources array for the given organization
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$push": bson.M{"policies": policy}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -110,6 +113,9 @@ func (r repository) Update(ctx context.Context, org_id string, id string, update
 	if update_policy.ActiveVersion != nil && *update_policy.ActiveVersion != "" {
 		update["$set"].(bson.M)["policies.$.active_version"] = *update_policy.ActiveVersion
 	}
+	if update_policy.ShadowVersion != nil {
+		update["$set"].(bson.M)["policies.$.shadow_version"] = *update_policy.ShadowVersion
+	}
 
 	if update_policy.PolicyContent != nil && update_policy.PolicyContent.Version != nil && *update_policy.PolicyContent.Version != "" {
 		// Update the specific policy content
@@ -119,19 +125,37 @@ func (r repository) Update(ctx context.Context, org_id string, id string, update
 			"policies.policy_contents.version": *update_policy.PolicyContent.Version,
 		}
 		update["$set"].(bson.M)["policies.$.policy_contents.$[elem].policy"] = *update_policy.PolicyContent.Policy
+		if update_policy.PolicyContent.Language != nil {
+			update["$set"].(bson.M)["policies.$.policy_contents.$[elem].language"] = *update_policy.PolicyContent.Language
+		}
+		if update_policy.PolicyContent.Hash != nil {
+			update["$set"].(bson.M)["policies.$.policy_contents.$[elem].hash"] = *update_policy.PolicyContent.Hash
+		}
+		if update_policy.PolicyContent.CreatedAt != nil {
+			comment := ""
+			if update_policy.PolicyContent.Comment != nil {
+				comment = *update_policy.PolicyContent.Comment
+			}
+			update["$set"].(bson.M)["policies.$.policy_contents.$[elem].created_at"] = *update_policy.PolicyContent.CreatedAt
+			update["$set"].(bson.M)["policies.$.policy_contents.$[elem].created_by"] = update_policy.PolicyContent.CreatedBy
+			update["$set"].(bson.M)["policies.$.policy_contents.$[elem].comment"] = comment
+		}
+		if update_policy.Audit != nil {
+			update["$push"] = bson.M{"policies.$.audit": *update_policy.Audit}
+		}
 
 		arrayFilters := options.ArrayFilters{
 			Filters: []interface{}{bson.M{"elem.version": *update_policy.PolicyContent.Version}},
 		}
 		opts := options.Update().SetArrayFilters(arrayFilters).SetUpsert(true)
 
-		_, err = r.mongoColl.UpdateOne(ctx, contentFilter, update, opts)
+		_, err = r.mongoColl.UpdateOne(ctx, contentFilter, revision.Update(update), opts)
 		if err != nil {
 			return err
 		}
 	} else {
 		// If no policy content update is needed, just update the policy
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 		if err != nil {
 			return err
 		}
@@ -158,7 +182,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		update := bson.M{"$push": bson.M{"policies.$.policy_contents": bson.M{
 			"$each": patch_user.AddedPolicies,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -175,7 +199,37 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 				},
 			},
 		}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
+		if err != nil {
+			return err
+		}
+	}
+
+	// add test cases
+	if len(patch_user.AddedTestCases) > 0 {
+
+		filter := bson.M{"_id": orgId, "policies._id": policyId}
+		update := bson.M{"$push": bson.M{"policies.$.test_cases": bson.M{
+			"$each": patch_user.AddedTestCases,
+		}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove test cases
+	if len(patch_user.RemovedTestCases) > 0 {
+
+		filter := bson.M{"_id": orgId, "policies._id": policyId}
+		update := bson.M{
+			"$pull": bson.M{
+				"policies.$.test_cases": bson.M{
+					"name": bson.M{"$in": patch_user.RemovedTestCases},
+				},
+			},
+		}
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -183,6 +237,28 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 	return nil
 }
 
+// Rollback activates the version of the audit entry, and appends the entry to the audit of the policy.
+func (r repository) Rollback(ctx context.Context, org_id string, id string, entry mongo_entity.PolicyAuditEntry) error {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return err
+	}
+
+	policyId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": orgId, "policies._id": policyId}
+	update := bson.M{
+		"$set":  bson.M{"policies.$.active_version": entry.Version},
+		"$push": bson.M{"policies.$.audit": entry},
+	}
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+	return err
+}
+
 // Delete existing policy.
 func (r repository) Delete(ctx context.Context, org_id string, id string) error {
 
@@ -200,7 +276,7 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$pull": bson.M{"policies": bson.M{"_id": policyId}}}
 	// Find the policy document in the "organizations" collection
-	result, err := r.mongoColl.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(false))
+	result, err := r.mongoColl.UpdateOne(context.Background(), filter, revision.Update(update), options.Update().SetUpsert(false))
 	if err != nil {
 		return err
 	}
@@ -212,10 +288,24 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 
 	filter = bson.M{"_id": orgId}
 	update = bson.M{"$pull": bson.M{"users.$[].policies": policyId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
+
+	// Remove the policy from the groups, roles, resources and permissions it scopes.
+	for path, arrayFilters := range map[string][]interface{}{
+		"groups.$[group].policies":                         {bson.M{"group.policies": policyId}},
+		"roles.$[role].policies":                           {bson.M{"role.policies": policyId}},
+		"resources.$[resource].policies":                   {bson.M{"resource.policies": policyId}},
+		"roles.$[role].permissions.$[permission].policies": {bson.M{"role.permissions.policies": policyId}, bson.M{"permission.policies": policyId}},
+	} {
+		update = bson.M{"$pull": bson.M{path: policyId}}
+		opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+		if _, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), opts); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -449,3 +539,9 @@ func (r repository) CheckPolicyContentExistsByVersion(ctx context.Context, org_i
 // 	// Group ID not found in the user's Groups field
 // 	return false, nil
 // }
+
+// Get the revision the organization is at.
+func (r repository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, org_id)
+}
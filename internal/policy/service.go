@@ -2,8 +2,12 @@ package policy
 
 import (
 	"context"
+	"time"
 
+	"github.com/shashimalcse/cronuseo/internal/engine"
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
@@ -18,6 +22,10 @@ type Service interface {
 	Update(ctx context.Context, org_id string, id string, input UpdatePolicyRequest) (Policy, error)
 	Patch(ctx context.Context, org_id string, id string, input PatchPolicyRequest) (Policy, error)
 	Delete(ctx context.Context, org_id string, id string) error
+	Evaluate(ctx context.Context, org_id string, id string, input EvaluatePolicyRequest) (EvaluatePolicyResponse, error)
+	Test(ctx context.Context, org_id string, id string, input TestPolicyRequest) (TestPolicyResponse, error)
+	Diff(ctx context.Context, org_id string, id string, from string, to string) (PolicyDiff, error)
+	Rollback(ctx context.Context, org_id string, id string, input RollbackPolicyRequest) (Policy, error)
 	// Patch(ctx context.Context, org_id string, id string, req UserPatchRequest) (User, error)
 }
 
@@ -29,34 +37,62 @@ type CreatePolicyRequest struct {
 	Identifier  string `json:"identifier" bson:"identifier"`
 	DisplayName string `json:"display_name" bson:"display_name"`
 	Version     string `json:"version" bson:"version"`
-	Policy      string `json:"policy" bson:"policy"`
+	// Language of the policy, tunnel when empty.
+	Language string `json:"language,omitempty" bson:"language"`
+	Policy   string `json:"policy" bson:"policy"`
+	// Comment describes the version.
+	Comment string `json:"comment,omitempty" bson:"comment"`
+	// TestCases must pass for the version, which becomes the active version.
+	TestCases []mongo_entity.PolicyTestCase `json:"test_cases,omitempty" bson:"test_cases"`
+}
+
+// RollbackPolicyRequest activates a previous version of a policy.
+type RollbackPolicyRequest struct {
+	Version string `json:"version"`
+	Comment string `json:"comment,omitempty"`
 }
 
 type UpdatePolicyRequest struct {
-	DisplayName   *string              `json:"display_name,omitempty" bson:"display_name"`
-	ActiveVersion *string              `json:"active_version,omitempty" bson:"active_version"`
+	DisplayName   *string `json:"display_name,omitempty" bson:"display_name"`
+	ActiveVersion *string `json:"active_version,omitempty" bson:"active_version"`
+	// ShadowVersion is evaluated alongside the active version in checks, and an empty version stops it.
+	ShadowVersion *string              `json:"shadow_version,omitempty" bson:"shadow_version"`
 	PolicyContent *UpdatePolicyContent `json:"policy_content,omitempty" bson:"policy_content"`
 }
 
 type PatchPolicyRequest struct {
-	AddedPolicies   []mongo_entity.PolicyContent `json:"added_policies,omitempty" bson:"added_policies"`
-	RemovedPolicies []string                     `json:"removed_policies,omitempty" bson:"removed_policies"`
+	AddedPolicies    []mongo_entity.PolicyContent  `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies  []string                      `json:"removed_policies,omitempty" bson:"removed_policies"`
+	AddedTestCases   []mongo_entity.PolicyTestCase `json:"added_test_cases,omitempty" bson:"added_test_cases"`
+	RemovedTestCases []string                      `json:"removed_test_cases,omitempty" bson:"removed_test_cases"`
 }
 
 type UpdatePolicy struct {
 	DisplayName   *string              `json:"display_name,omitempty" bson:"display_name"`
 	ActiveVersion *string              `json:"active_version,omitempty" bson:"active_version"`
+	ShadowVersion *string              `json:"shadow_version,omitempty" bson:"shadow_version"`
 	PolicyContent *UpdatePolicyContent `json:"policy_content,omitempty" bson:"policy_content"`
+	// Audit records an edit of a version, appended to the audit of the policy.
+	Audit *mongo_entity.PolicyAuditEntry `json:"-" bson:"audit"`
 }
 
 type UpdatePolicyContent struct {
-	Version *string `json:"version" bson:"version"`
-	Policy  *string `json:"policy" bson:"policy"`
+	Version  *string `json:"version" bson:"version"`
+	Language *string `json:"language,omitempty" bson:"language"`
+	Policy   *string `json:"policy" bson:"policy"`
+	Comment  *string `json:"comment,omitempty" bson:"comment"`
+	// Hash of the compiled policy, set when the policy compiles.
+	Hash *string `json:"-" bson:"hash"`
+	// CreatedAt and CreatedBy are when and by whom the version was edited, set by the service.
+	CreatedAt *time.Time `json:"-" bson:"created_at"`
+	CreatedBy string     `json:"-" bson:"created_by"`
 }
 
 type PatchPolicy struct {
-	AddedPolicies   []mongo_entity.PolicyContent `json:"added_policies,omitempty" bson:"added_policies"`
-	RemovedPolicies []string                     `json:"removed_policies,omitempty" bson:"removed_policies"`
+	AddedPolicies    []mongo_entity.PolicyContent  `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies  []string                      `json:"removed_policies,omitempty" bson:"removed_policies"`
+	AddedTestCases   []mongo_entity.PolicyTestCase `json:"added_test_cases,omitempty" bson:"added_test_cases"`
+	RemovedTestCases []string                      `json:"removed_test_cases,omitempty" bson:"removed_test_cases"`
 }
 
 func (m CreatePolicyRequest) Validate() error {
@@ -69,13 +105,56 @@ func (m CreatePolicyRequest) Validate() error {
 }
 
 type service struct {
-	repo   Repository
-	logger *zap.Logger
+	repo    Repository
+	logger  *zap.Logger
+	events  *event.Bus
+	engines *engine.Registry
+}
+
+func NewService(repo Repository, logger *zap.Logger, events *event.Bus) Service {
+
+	return service{repo: repo, logger: logger, events: events, engines: engine.Default()}
+}
+
+// checkLanguage makes sure a policy engine is registered for the policy language.
+func (s service) checkLanguage(language string) error {
+
+	if _, err := s.engines.Get(engine.Language(language)); err != nil {
+		s.logger.Debug("Unsupported policy language.", zap.String("language", language))
+		return &util.InvalidInputError{Path: "policy language " + language}
+	}
+	return nil
 }
 
-func NewService(repo Repository, logger *zap.Logger) Service {
+// compile makes sure a version of a policy compiles, and returns the hash of its compiled form.
+func (s service) compile(version string, language string, policy string) (string, error) {
 
-	return service{repo: repo, logger: logger}
+	if err := s.checkLanguage(language); err != nil {
+		return "", err
+	}
+	compiled, err := s.engines.Compile(engine.Language(language), policy)
+	if err != nil {
+		s.logger.Debug("Invalid policy.", zap.String("version", version), zap.Error(err))
+		invalid := &util.InvalidContentError{Path: "policy version " + version, Message: err.Error()}
+		if syntaxErr, ok := err.(*engine.SyntaxError); ok {
+			invalid.Line, invalid.Column, invalid.Message = syntaxErr.Line, syntaxErr.Column, syntaxErr.Message
+		}
+		return "", invalid
+	}
+	return compiled.Hash(), nil
+}
+
+// changed publishes a write to a policy, and tracks the revision the write moved the organization to.
+func (s service) changed(ctx context.Context, org_id string, id string) error {
+
+	s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.PolicyEntity, ID: id})
+	rev, err := s.repo.GetOrganizationRevision(ctx, org_id)
+	if err != nil {
+		s.logger.Error("Error while reading organization revision.", zap.String("organization_id", org_id), zap.Error(err))
+		return err
+	}
+	revision.Track(ctx, rev)
+	return nil
 }
 
 // Get policy by id.
@@ -99,6 +178,10 @@ func (s service) Create(ctx context.Context, org_id string, req CreatePolicyRequ
 		s.logger.Error("Error while validating policy create request.")
 		return Policy{}, &util.InvalidInputError{Path: "Invalid input for policy."}
 	}
+	hash, err := s.compile(req.Version, req.Language, req.Policy)
+	if err != nil {
+		return Policy{}, err
+	}
 
 	// Check policy already exists.
 	exists, _ := s.repo.CheckPolicyExistsByIdentifier(ctx, org_id, req.Identifier)
@@ -107,21 +190,34 @@ func (s service) Create(ctx context.Context, org_id string, req CreatePolicyRequ
 		return Policy{}, &util.AlreadyExistsError{Path: "User : " + req.Identifier}
 
 	}
+	if err := checkTestCases(nil, req.TestCases); err != nil {
+		return Policy{}, err
+	}
+	if err := s.checkActivation(req.TestCases, mongo_entity.PolicyContent{Version: req.Version, Language: req.Language, Policy: req.Policy}); err != nil {
+		return Policy{}, err
+	}
 
 	// Generate policy id.
 	policyId := primitive.NewObjectID()
 	policContentId := primitive.NewObjectID()
+	now := time.Now()
 	policyContent := mongo_entity.PolicyContent{
-		ID:      policContentId,
-		Version: req.Version,
-		Policy:  req.Policy,
+		ID:        policContentId,
+		Version:   req.Version,
+		Policy:    req.Policy,
+		Hash:      hash,
+		CreatedAt: &now,
+		CreatedBy: util.Actor(ctx),
+		Comment:   req.Comment,
 	}
-	err := s.repo.Create(ctx, org_id, mongo_entity.Policy{
+	err = s.repo.Create(ctx, org_id, mongo_entity.Policy{
 		ID:             policyId,
 		DisplayName:    req.DisplayName,
 		Identifier:     req.Identifier,
 		ActiveVersion:  req.Version,
+		Language:       req.Language,
 		PolicyContents: []mongo_entity.PolicyContent{policyContent},
+		TestCases:      req.TestCases,
 	})
 
 	if err != nil {
@@ -129,18 +225,22 @@ func (s service) Create(ctx context.Context, org_id string, req CreatePolicyRequ
 			zap.String("organization_id", org_id))
 		return Policy{}, err
 	}
+	if err := s.changed(ctx, org_id, policyId.Hex()); err != nil {
+		return Policy{}, err
+	}
 	return s.Get(ctx, org_id, policyId.Hex())
 }
 
 // // Update policy.
 func (s service) Update(ctx context.Context, org_id string, id string, req UpdatePolicyRequest) (Policy, error) {
 
-	_, err := s.Get(ctx, org_id, id)
+	policy, err := s.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("Policy not exists.", zap.String("policy_id", id))
 		return Policy{}, &util.NotFoundError{Path: "Policy " + id + " not exists."}
 	}
 
+	var audit *mongo_entity.PolicyAuditEntry
 	if req.PolicyContent != nil {
 		if req.PolicyContent.Version == nil || *req.PolicyContent.Version == "" {
 			return Policy{}, &util.InvalidInputError{Path: "Invalid input for policy."}
@@ -152,18 +252,84 @@ func (s service) Update(ctx context.Context, org_id string, id string, req Updat
 		if req.PolicyContent.Policy == nil || *req.PolicyContent.Policy == "" {
 			return Policy{}, &util.InvalidInputError{Path: "Invalid input for policy."}
 		}
+		language := policy.Language
+		if content, ok := policy.Content(*req.PolicyContent.Version); ok {
+			language = policy.ContentLanguage(content)
+		}
+		if req.PolicyContent.Language != nil {
+			language = *req.PolicyContent.Language
+		}
+		hash, err := s.compile(*req.PolicyContent.Version, language, *req.PolicyContent.Policy)
+		if err != nil {
+			return Policy{}, err
+		}
+		// An edit replaces the version, so the version records when, by whom and why it was edited,
+		// and the audit of the policy keeps the edit.
+		now := time.Now()
+		content := *req.PolicyContent
+		content.Hash = &hash
+		content.CreatedAt, content.CreatedBy = &now, util.Actor(ctx)
+		req.PolicyContent = &content
+		audit = &mongo_entity.PolicyAuditEntry{
+			Action:  mongo_entity.PolicyEdit,
+			Version: *content.Version,
+			Actor:   content.CreatedBy,
+			At:      now,
+		}
+		if content.Comment != nil {
+			audit.Comment = *content.Comment
+		}
+	}
+
+	// The test cases of the policy must pass for the version which is active after the update.
+	active := policy.ActiveVersion
+	if req.ActiveVersion != nil && *req.ActiveVersion != "" {
+		active = *req.ActiveVersion
+	}
+	if active != policy.ActiveVersion || (req.PolicyContent != nil && *req.PolicyContent.Version == active) {
+		content, ok := policy.Content(active)
+		if !ok {
+			return Policy{}, &util.InvalidInputError{Path: "Invalid policy version " + active}
+		}
+		content.Language = policy.ContentLanguage(content)
+		if req.PolicyContent != nil && *req.PolicyContent.Version == active {
+			content.Policy = *req.PolicyContent.Policy
+			if req.PolicyContent.Language != nil {
+				content.Language = *req.PolicyContent.Language
+			}
+		}
+		if err := s.checkActivation(policy.TestCases, content); err != nil {
+			return Policy{}, err
+		}
+	}
+
+	// The shadow version is a version other than the active one, and stops when it becomes active.
+	shadow := req.ShadowVersion
+	if shadow != nil && *shadow != "" {
+		if _, ok := policy.Content(*shadow); !ok || *shadow == active {
+			return Policy{}, &util.InvalidInputError{Path: "Invalid shadow policy version " + *shadow}
+		}
+	}
+	if shadow == nil && policy.ShadowVersion != "" && policy.ShadowVersion == active {
+		stopped := ""
+		shadow = &stopped
 	}
 
 	if err := s.repo.Update(ctx, org_id, id, UpdatePolicy{
 		DisplayName:   req.DisplayName,
 		ActiveVersion: req.ActiveVersion,
+		ShadowVersion: shadow,
 		PolicyContent: req.PolicyContent,
+		Audit:         audit,
 	}); err != nil {
 		s.logger.Error("Error while updating user.",
 			zap.String("organization_id", org_id),
 			zap.String("user_id", id))
 		return Policy{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return Policy{}, err
+	}
 	updatedPolicy, err := s.repo.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("User not exists.", zap.String("user_id", id))
@@ -174,44 +340,74 @@ func (s service) Update(ctx context.Context, org_id string, id string, req Updat
 
 func (s service) Patch(ctx context.Context, org_id string, id string, req PatchPolicyRequest) (Policy, error) {
 
-	_, err := s.Get(ctx, org_id, id)
+	policy, err := s.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("User not exists.", zap.String("user_id", id))
 		return Policy{}, &util.NotFoundError{Path: "User " + id + " not exists."}
 	}
 
 	// roles
-	for _, policy := range req.AddedPolicies {
-		exists, _ := s.repo.CheckPolicyContentExistsByVersion(ctx, org_id, policy.Version)
+	now := time.Now()
+	added_policies := []mongo_entity.PolicyContent{}
+	for _, policyContent := range req.AddedPolicies {
+		exists, _ := s.repo.CheckPolicyContentExistsByVersion(ctx, org_id, policyContent.Version)
 		if exists {
-			return Policy{}, &util.InvalidInputError{Path: "Invalid policy version " + policy.Version}
+			return Policy{}, &util.InvalidInputError{Path: "Invalid policy version " + policyContent.Version}
+		}
+		hash, err := s.compile(policyContent.Version, policy.ContentLanguage(policyContent), policyContent.Policy)
+		if err != nil {
+			return Policy{}, err
 		}
+		added_policies = append(added_policies, mongo_entity.PolicyContent{
+			ID:        primitive.NewObjectID(),
+			Version:   policyContent.Version,
+			Language:  policyContent.Language,
+			Policy:    policyContent.Policy,
+			Hash:      hash,
+			CreatedAt: &now,
+			CreatedBy: util.Actor(ctx),
+			Comment:   policyContent.Comment,
+		})
 	}
 	for _, version := range req.RemovedPolicies {
 		exists, _ := s.repo.CheckPolicyContentExistsByVersion(ctx, org_id, version)
 		if !exists {
 			return Policy{}, &util.InvalidInputError{Path: "Invalid policy version " + version}
 		}
+		if version == policy.ActiveVersion {
+			s.logger.Debug("Active policy version can not be removed.", zap.String("policy_id", id), zap.String("version", version))
+			return Policy{}, &util.PreconditionFailedError{Path: "active policy version " + version + " can not be removed"}
+		}
+		if version == policy.ShadowVersion {
+			s.logger.Debug("Shadow policy version can not be removed.", zap.String("policy_id", id), zap.String("version", version))
+			return Policy{}, &util.PreconditionFailedError{Path: "shadow policy version " + version + " can not be removed"}
+		}
 	}
 
-	added_policies := []mongo_entity.PolicyContent{}
-	for _, policyContent := range req.AddedPolicies {
-		policyContentId := primitive.NewObjectID()
-		added_policies = append(added_policies, mongo_entity.PolicyContent{
-			ID:      policyContentId,
-			Version: policyContent.Version,
-			Policy:  policyContent.Policy,
-		})
+	// test cases
+	if err := checkTestCases(policy.TestCases, req.AddedTestCases); err != nil {
+		return Policy{}, err
+	}
+	for _, name := range req.RemovedTestCases {
+		if !policy.HasTestCase(name) {
+			return Policy{}, &util.InvalidInputError{Path: "Invalid policy test case " + name}
+		}
 	}
+
 	if err := s.repo.Patch(ctx, org_id, id, PatchPolicy{
-		AddedPolicies:   added_policies,
-		RemovedPolicies: req.RemovedPolicies,
+		AddedPolicies:    added_policies,
+		RemovedPolicies:  req.RemovedPolicies,
+		AddedTestCases:   req.AddedTestCases,
+		RemovedTestCases: req.RemovedTestCases,
 	}); err != nil {
 		s.logger.Error("Error while updating user.",
 			zap.String("organization_id", org_id),
 			zap.String("user_id", id))
 		return Policy{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return Policy{}, err
+	}
 	updatedUser, err := s.repo.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("User not exists.", zap.String("user_id", id))
@@ -235,7 +431,7 @@ func (s service) Delete(ctx context.Context, org_id string, id string) error {
 			zap.String("user_id", id))
 		return err
 	}
-	return nil
+	return s.changed(ctx, org_id, id)
 }
 
 // Pagination filter.
@@ -0,0 +1,369 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/test"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_service_TestCases(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(&mockRepository{}, logger, nil)
+
+	ctx := context.Background()
+	org_id := "64a1b2c3d4e5f60718293a4b"
+	small := mongo_entity.PolicyTestCase{Name: "small", Context: map[string]interface{}{"amount": 10}, Expected: true}
+	large := mongo_entity.PolicyTestCase{Name: "large", Context: map[string]interface{}{"amount": 5000}, Expected: false}
+
+	// test cases must pass for the created version
+	_, err := s.Create(ctx, org_id, CreatePolicyRequest{Identifier: "limit", Version: "v1", Language: "cel", Policy: "context.amount < 5",
+		TestCases: []mongo_entity.PolicyTestCase{small}})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+
+	policy, err := s.Create(ctx, org_id, CreatePolicyRequest{Identifier: "limit", Version: "v1", Language: "cel", Policy: "context.amount < 1000",
+		TestCases: []mongo_entity.PolicyTestCase{small}})
+	assert.Nil(t, err)
+	id := policy.ID.Hex()
+
+	// dry run of inline policy content
+	result, err := s.Evaluate(ctx, org_id, "", EvaluatePolicyRequest{Language: "cel", Policy: "context.amount < 5", Context: map[string]interface{}{"amount": 10}})
+	assert.Nil(t, err)
+	assert.False(t, result.Result)
+	assert.Empty(t, result.Error)
+
+	// dry run of the active version
+	result, err = s.Evaluate(ctx, org_id, id, EvaluatePolicyRequest{Context: map[string]interface{}{"amount": 10}})
+	assert.Nil(t, err)
+	assert.Equal(t, EvaluatePolicyResponse{Version: "v1", Language: "cel", Result: true}, result)
+
+	// policies which can not be evaluated report why
+	result, err = s.Evaluate(ctx, org_id, id, EvaluatePolicyRequest{Policy: "context.amount <"})
+	assert.Nil(t, err)
+	assert.False(t, result.Result)
+	assert.NotEmpty(t, result.Error)
+
+	_, err = s.Evaluate(ctx, org_id, id, EvaluatePolicyRequest{Version: "v9"})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// add a version along with a test case
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{
+		AddedPolicies:  []mongo_entity.PolicyContent{{Version: "v2", Policy: "context.amount < 5"}, {Version: "v3", Policy: "context.amount < 100"}},
+		AddedTestCases: []mongo_entity.PolicyTestCase{large},
+	})
+	assert.Nil(t, err)
+
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{AddedTestCases: []mongo_entity.PolicyTestCase{small}})
+	assert.IsType(t, &util.AlreadyExistsError{}, err)
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{RemovedTestCases: []string{"medium"}})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// run the test cases against a stored version
+	tested, err := s.Test(ctx, org_id, id, TestPolicyRequest{Version: "v2"})
+	assert.Nil(t, err)
+	assert.False(t, tested.Passed)
+	assert.Equal(t, []string{"small"}, tested.Failed())
+
+	// versions with failing test cases can not be activated
+	v2 := "v2"
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ActiveVersion: &v2})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+
+	v3 := "v3"
+	policy, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ActiveVersion: &v3})
+	assert.Nil(t, err)
+	assert.Equal(t, "v3", policy.ActiveVersion)
+
+	// nor can the active version be changed to fail them
+	broken := "context.amount > 100"
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{PolicyContent: &UpdatePolicyContent{Version: &v3, Policy: &broken}})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+
+	// unless the failing test case is removed
+	policy, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{RemovedTestCases: []string{"small"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []mongo_entity.PolicyTestCase{large}, policy.TestCases)
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ActiveVersion: &v2})
+	assert.Nil(t, err)
+
+	v9 := "v9"
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ActiveVersion: &v9})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+}
+
+func Test_service_Compile(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(&mockRepository{}, logger, nil)
+
+	ctx := context.Background()
+	org_id := "64a1b2c3d4e5f60718293a4b"
+
+	// broken policies are rejected with where they break
+	_, err := s.Create(ctx, org_id, CreatePolicyRequest{Identifier: "level", Version: "v1", Language: "cel", Policy: "subject == \"alice\" &&\n  user_properties.level >"})
+	assert.IsType(t, &util.InvalidContentError{}, err)
+	assert.Equal(t, 2, err.(*util.InvalidContentError).Line)
+
+	policy, err := s.Create(ctx, org_id, CreatePolicyRequest{Identifier: "level", Version: "v1", Language: "cel", Policy: "user_properties.level > 2"})
+	assert.Nil(t, err)
+	hash := policy.PolicyContents[0].Hash
+	assert.NotEmpty(t, hash)
+	id := policy.ID.Hex()
+
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{AddedPolicies: []mongo_entity.PolicyContent{{Version: "v2", Language: "tunnel", Policy: `[[{"attribute":`}}})
+	assert.Equal(t, &util.InvalidContentError{Path: "policy version v2", Line: 1, Column: 15, Message: "unexpected end of JSON input"}, err)
+
+	v1 := "v1"
+	broken := "user_properties.level >"
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{PolicyContent: &UpdatePolicyContent{Version: &v1, Policy: &broken}})
+	assert.IsType(t, &util.InvalidContentError{}, err)
+
+	// the hash follows the compiled policy, not its layout
+	spaced := "user_properties.level  >  2"
+	updated, err := s.Update(ctx, org_id, id, UpdatePolicyRequest{PolicyContent: &UpdatePolicyContent{Version: &v1, Policy: &spaced}})
+	assert.Nil(t, err)
+	assert.Equal(t, hash, updated.PolicyContents[0].Hash)
+	changed := "user_properties.level > 3"
+	updated, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{PolicyContent: &UpdatePolicyContent{Version: &v1, Policy: &changed}})
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, updated.PolicyContents[0].Hash)
+
+	// rego versions are compiled on write too, and decide through their allow rule
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{AddedPolicies: []mongo_entity.PolicyContent{{Version: "v3", Language: "rego", Policy: "package cronuseo\n\nallow {\n  input.action ==\n}"}}})
+	assert.Equal(t, &util.InvalidContentError{Path: "policy version v3", Line: 5, Column: 1, Message: "unexpected } token"}, err)
+	rego := "package cronuseo\n\nimport rego.v1\n\ndefault allow := false\n\nallow if {\n  input.action == \"read\"\n  input.user_properties.level > 2\n}"
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{AddedPolicies: []mongo_entity.PolicyContent{{Version: "v3", Language: "rego", Policy: rego}}})
+	assert.Nil(t, err)
+	result, err := s.Evaluate(ctx, org_id, id, EvaluatePolicyRequest{Version: "v3", Action: "read", UserProperties: map[string]interface{}{"level": 3}})
+	assert.Nil(t, err)
+	assert.Equal(t, EvaluatePolicyResponse{Version: "v3", Language: "rego", Result: true}, result)
+	result, err = s.Evaluate(ctx, org_id, id, EvaluatePolicyRequest{Version: "v3", Action: "delete", UserProperties: map[string]interface{}{"level": 3}})
+	assert.Nil(t, err)
+	assert.False(t, result.Result)
+}
+
+func Test_service_History(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(&mockRepository{}, logger, nil)
+
+	ctx := util.WithActor(context.Background(), "alice")
+	org_id := "64a1b2c3d4e5f60718293a4b"
+
+	// versions record when, by whom and why they were added
+	policy, err := s.Create(ctx, org_id, CreatePolicyRequest{Identifier: "hours", Version: "v1", Language: "cel", Policy: "context.hour >= 9 &&\ncontext.hour < 17", Comment: "office hours"})
+	assert.Nil(t, err)
+	assert.NotNil(t, policy.PolicyContents[0].CreatedAt)
+	assert.Equal(t, "alice", policy.PolicyContents[0].CreatedBy)
+	assert.Equal(t, "office hours", policy.PolicyContents[0].Comment)
+	id := policy.ID.Hex()
+
+	policy, err = s.Patch(util.WithActor(ctx, "bob"), org_id, id, PatchPolicyRequest{AddedPolicies: []mongo_entity.PolicyContent{
+		{Version: "v2", Policy: "context.hour >= 8 &&\ncontext.hour < 17", Comment: "early start"},
+		{Version: "v3", Policy: "context.hour >= 9  &&  context.hour < 17"},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", policy.PolicyContents[1].CreatedBy)
+
+	// diff to the active version
+	diff, err := s.Diff(ctx, org_id, id, "v2", "")
+	assert.Nil(t, err)
+	assert.True(t, diff.Changed)
+	assert.Equal(t, "v1", diff.To)
+	assert.Equal(t, []DiffLine{
+		{Operation: DiffRemoved, Text: "context.hour >= 8 &&"},
+		{Operation: DiffAdded, Text: "context.hour >= 9 &&"},
+		{Operation: DiffEqual, Text: "context.hour < 17"},
+	}, diff.Lines)
+
+	// versions which only differ in layout are not changed
+	diff, err = s.Diff(ctx, org_id, id, "v1", "v3")
+	assert.Nil(t, err)
+	assert.False(t, diff.Changed)
+
+	_, err = s.Diff(ctx, org_id, id, "v9", "")
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// roll forward and back, recording each rollback
+	v2 := "v2"
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ActiveVersion: &v2})
+	assert.Nil(t, err)
+	policy, err = s.Rollback(ctx, org_id, id, RollbackPolicyRequest{Version: "v1", Comment: "too early"})
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", policy.ActiveVersion)
+	assert.Len(t, policy.Audit, 1)
+	assert.Equal(t, mongo_entity.PolicyRollback, policy.Audit[0].Action)
+	assert.Equal(t, "v2", policy.Audit[0].PreviousVersion)
+	assert.Equal(t, "alice", policy.Audit[0].Actor)
+	assert.Equal(t, "too early", policy.Audit[0].Comment)
+
+	_, err = s.Rollback(ctx, org_id, id, RollbackPolicyRequest{Version: "v1"})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+	_, err = s.Rollback(ctx, org_id, id, RollbackPolicyRequest{Version: "v9"})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// editing a version in place records the edit on the version and in the audit
+	v3, edited, comment := "v3", "context.hour >= 10 && context.hour < 17", "late start"
+	policy, err = s.Update(util.WithActor(ctx, "carol"), org_id, id, UpdatePolicyRequest{PolicyContent: &UpdatePolicyContent{Version: &v3, Policy: &edited, Comment: &comment}})
+	assert.Nil(t, err)
+	assert.Equal(t, "carol", policy.PolicyContents[2].CreatedBy)
+	assert.Equal(t, "late start", policy.PolicyContents[2].Comment)
+	assert.Len(t, policy.Audit, 2)
+	assert.Equal(t, mongo_entity.PolicyAuditEntry{Action: mongo_entity.PolicyEdit, Version: "v3", Actor: "carol", At: policy.Audit[1].At, Comment: "late start"}, policy.Audit[1])
+
+	// the active version can not be removed
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{RemovedPolicies: []string{"v1"}})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+	policy, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{RemovedPolicies: []string{"v2"}})
+	assert.Nil(t, err)
+	assert.Len(t, policy.PolicyContents, 2)
+}
+
+func Test_service_ShadowVersion(t *testing.T) {
+	logger := test.InitLogger()
+	s := NewService(&mockRepository{}, logger, nil)
+
+	ctx := context.Background()
+	org_id := "64a1b2c3d4e5f60718293a4b"
+
+	policy, err := s.Create(ctx, org_id, CreatePolicyRequest{Identifier: "limit", Version: "v1", Language: "cel", Policy: "context.amount < 1000"})
+	assert.Nil(t, err)
+	id := policy.ID.Hex()
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{AddedPolicies: []mongo_entity.PolicyContent{{Version: "v2", Policy: "context.amount < 500"}}})
+	assert.Nil(t, err)
+
+	// the shadow version is another stored version
+	v1, v2, v9 := "v1", "v2", "v9"
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ShadowVersion: &v9})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ShadowVersion: &v1})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+	policy, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ShadowVersion: &v2})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", policy.ShadowVersion)
+
+	_, err = s.Patch(ctx, org_id, id, PatchPolicyRequest{RemovedPolicies: []string{"v2"}})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+
+	// activating the shadow version stops it
+	policy, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ActiveVersion: &v2})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", policy.ActiveVersion)
+	assert.Empty(t, policy.ShadowVersion)
+
+	// and so does rolling back to it
+	_, err = s.Update(ctx, org_id, id, UpdatePolicyRequest{ShadowVersion: &v1})
+	assert.Nil(t, err)
+	policy, err = s.Rollback(ctx, org_id, id, RollbackPolicyRequest{Version: "v1"})
+	assert.Nil(t, err)
+	assert.Empty(t, policy.ShadowVersion)
+}
+
+type mockRepository struct {
+	policies []mongo_entity.Policy
+}
+
+func (m *mockRepository) find(id string) *mongo_entity.Policy {
+	for i := range m.policies {
+		if m.policies[i].ID.Hex() == id {
+			return &m.policies[i]
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) Get(ctx context.Context, org_id string, id string) (*mongo_entity.Policy, error) {
+	if policy := m.find(id); policy != nil {
+		found := *policy
+		return &found, nil
+	}
+	return nil, &util.NotFoundError{Path: "Policy"}
+}
+func (m *mockRepository) Create(ctx context.Context, org_id string, policy mongo_entity.Policy) error {
+	m.policies = append(m.policies, policy)
+	return nil
+}
+func (m *mockRepository) Query(ctx context.Context, org_id string) (*[]mongo_entity.Policy, error) {
+	return &m.policies, nil
+}
+func (m *mockRepository) Update(ctx context.Context, org_id string, id string, update_policy UpdatePolicy) error {
+	policy := m.find(id)
+	if update_policy.ActiveVersion != nil && *update_policy.ActiveVersion != "" {
+		policy.ActiveVersion = *update_policy.ActiveVersion
+	}
+	if update_policy.ShadowVersion != nil {
+		policy.ShadowVersion = *update_policy.ShadowVersion
+	}
+	if update_policy.PolicyContent != nil {
+		for i, content := range policy.PolicyContents {
+			if content.Version == *update_policy.PolicyContent.Version {
+				policy.PolicyContents[i].Policy = *update_policy.PolicyContent.Policy
+				policy.PolicyContents[i].Hash = *update_policy.PolicyContent.Hash
+				policy.PolicyContents[i].CreatedAt = update_policy.PolicyContent.CreatedAt
+				policy.PolicyContents[i].CreatedBy = update_policy.PolicyContent.CreatedBy
+				policy.PolicyContents[i].Comment = ""
+				if update_policy.PolicyContent.Comment != nil {
+					policy.PolicyContents[i].Comment = *update_policy.PolicyContent.Comment
+				}
+			}
+		}
+	}
+	if update_policy.Audit != nil {
+		policy.Audit = append(policy.Audit, *update_policy.Audit)
+	}
+	return nil
+}
+func (m *mockRepository) Patch(ctx context.Context, org_id string, id string, patch_policy PatchPolicy) error {
+	policy := m.find(id)
+	policy.PolicyContents = append(policy.PolicyContents, patch_policy.AddedPolicies...)
+	policy.TestCases = append(policy.TestCases, patch_policy.AddedTestCases...)
+	for _, version := range patch_policy.RemovedPolicies {
+		for i, content := range policy.PolicyContents {
+			if content.Version == version {
+				policy.PolicyContents = append(policy.PolicyContents[:i], policy.PolicyContents[i+1:]...)
+				break
+			}
+		}
+	}
+	for _, name := range patch_policy.RemovedTestCases {
+		for i, testCase := range policy.TestCases {
+			if testCase.Name == name {
+				policy.TestCases = append(policy.TestCases[:i], policy.TestCases[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+func (m *mockRepository) Rollback(ctx context.Context, org_id string, id string, entry mongo_entity.PolicyAuditEntry) error {
+	policy := m.find(id)
+	policy.ActiveVersion = entry.Version
+	policy.Audit = append(policy.Audit, entry)
+	return nil
+}
+func (m *mockRepository) Delete(ctx context.Context, org_id string, id string) error {
+	return nil
+}
+func (m *mockRepository) CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error) {
+	return m.find(id) != nil, nil
+}
+func (m *mockRepository) CheckPolicyExistsByIdentifier(ctx context.Context, org_id string, identifier string) (bool, error) {
+	for _, policy := range m.policies {
+		if policy.Identifier == identifier {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *mockRepository) CheckPolicyContentExistsByVersion(ctx context.Context, org_id string, version string) (bool, error) {
+	for _, policy := range m.policies {
+		if _, ok := policy.Content(version); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *mockRepository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+	return 1, nil
+}
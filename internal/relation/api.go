@@ -0,0 +1,151 @@
+package relation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shashimalcse/cronuseo/internal/util"
+)
+
+func RegisterHandlers(r *echo.Group, service Service) {
+	res := resource{service}
+	router := r.Group("/o/:org_id/relations")
+	router.GET("", res.query)
+	router.POST("", res.create)
+	router.DELETE("", res.delete)
+	router.POST("/write", res.write)
+	router.GET("/watch", res.watch)
+}
+
+type resource struct {
+	service Service
+}
+
+// @Description Get relation tuples matching the filter.
+// @Tags        Relation
+// @Param org_id path string true "Organization ID"
+// @Param object_type query string false "Object type"
+// @Param object_id query string false "Object ID"
+// @Param relation query string false "Relation"
+// @Param subject_type query string false "Subject type"
+// @Param subject_id query string false "Subject ID"
+// @Param subject_relation query string false "Subject relation"
+// @Produce     json
+// @Success     200 {object}  RelationsResponse
+// @failure     400,500
+// @Router      /{org_id}/relations [get]
+func (r resource) query(c echo.Context) error {
+
+	var filter Filter
+	if err := c.Bind(&filter); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+	relations, err := r.service.Query(c.Request().Context(), c.Param("org_id"), filter)
+	if err != nil {
+		return util.HandleError(err)
+	}
+
+	return c.JSON(http.StatusOK, relations)
+}
+
+// @Description Create relation tuple.
+// @Tags        Relation
+// @Accept      json
+// @Param org_id path string true "Organization ID"
+// @Param request body RelationRequest true "body"
+// @Produce     json
+// @Success     201 {object}  Relation
+// @failure     400,403,500
+// @Router      /{org_id}/relations [post]
+func (r resource) create(c echo.Context) error {
+
+	var input RelationRequest
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+	relation, err := r.service.Create(c.Request().Context(), c.Param("org_id"), input)
+	if err != nil {
+		return util.HandleError(err)
+	}
+
+	return c.JSON(http.StatusCreated, relation)
+}
+
+// @Description Delete relation tuple.
+// @Tags        Relation
+// @Accept      json
+// @Param org_id path string true "Organization ID"
+// @Param request body RelationRequest true "body"
+// @Produce     json
+// @Success     204
+// @failure     400,404,500
+// @Router      /{org_id}/relations [delete]
+func (r resource) delete(c echo.Context) error {
+
+	var input RelationRequest
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+	err := r.service.Delete(c.Request().Context(), c.Param("org_id"), input)
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusNoContent, "")
+}
+
+// @Description Write and delete relation tuples in a batch, if every precondition holds.
+// @Tags        Relation
+// @Accept      json
+// @Param org_id path string true "Organization ID"
+// @Param request body WriteRelationsRequest true "body"
+// @Produce     json
+// @Success     200 {object}  WriteRelationsResponse
+// @failure     400,412,500
+// @Router      /{org_id}/relations/write [post]
+func (r resource) write(c echo.Context) error {
+
+	var input WriteRelationsRequest
+	if err := c.Bind(&input); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs")
+	}
+	response, err := r.service.Write(c.Request().Context(), c.Param("org_id"), input)
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Description Stream relation changes after the revision as newline delimited JSON.
+// @Tags        Relation
+// @Param org_id path string true "Organization ID"
+// @Param revision query string false "Revision to watch from, now when empty"
+// @Produce     json
+// @Success     200 {object}  RelationChange
+// @failure     400,404,412,500
+// @Router      /{org_id}/relations/watch [get]
+func (r resource) watch(c echo.Context) error {
+
+	// Changes may be far apart, so the stream starts right away once the revision can be watched.
+	// Errors after that are sent in the stream.
+	if err := r.service.CheckWatch(c.Request().Context(), c.Param("org_id"), c.QueryParam("revision")); err != nil {
+		return util.HandleError(err)
+	}
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	encoder := json.NewEncoder(response)
+	err := r.service.Watch(c.Request().Context(), c.Param("org_id"), c.QueryParam("revision"), func(change RelationChange) error {
+		if err := encoder.Encode(change); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	})
+	if err != nil {
+		encoder.Encode(map[string]interface{}{"error": util.HandleError(err).Message})
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package relation
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"github.com/shashimalcse/cronuseo/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// Organizations reach the gRPC relation service by identifier with their API key, like the check service.
+type Organizations interface {
+	GetIdByIdentifier(ctx context.Context, identifier string) (string, error)
+}
+
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error)
+}
+
+func NewGrpcService(service Service, organizations Organizations, validator APIKeyValidator, logger *zap.Logger) proto.RelationServer {
+
+	return grpcService{service: service, organizations: organizations, validator: validator, logger: logger}
+}
+
+type grpcService struct {
+	service       Service
+	organizations Organizations
+	validator     APIKeyValidator
+	logger        *zap.Logger
+}
+
+func (s grpcService) Write(ctx context.Context, req *proto.GrpcWriteRelationsRequest) (*proto.GrpcWriteRelationsResponse, error) {
+
+	s.logger.Info("GRPC method : Write", zap.String("method", "Write"))
+	org_id, err := s.authorize(ctx, req.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	input := WriteRelationsRequest{
+		Writes:  fromGrpcTuples(req.Writes),
+		Deletes: fromGrpcTuples(req.Deletes),
+	}
+	for _, precondition := range req.Preconditions {
+		input.Preconditions = append(input.Preconditions, Precondition{
+			Operation: PreconditionOperation(precondition.Operation),
+			Filter:    Filter(fromGrpcTuple(precondition.Filter)),
+		})
+	}
+	// The write tracks the revision it moves the organization to, as the revision header does over REST.
+	ctx = revision.Tracking(ctx)
+	result, err := s.service.Write(ctx, org_id, input)
+	if err != nil {
+		return nil, util.HandleError(err)
+	}
+	response := &proto.GrpcWriteRelationsResponse{Revision: result.Revision}
+	if rev := revision.Tracked(ctx); rev > 0 {
+		response.OrganizationRevision = revision.Format(rev)
+	}
+	return response, nil
+}
+
+func (s grpcService) Read(ctx context.Context, req *proto.GrpcReadRelationsRequest) (*proto.GrpcReadRelationsResponse, error) {
+
+	s.logger.Info("GRPC method : Read", zap.String("method", "Read"))
+	org_id, err := s.authorize(ctx, req.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.Query(ctx, org_id, Filter(fromGrpcTuple(req.Filter)))
+	if err != nil {
+		return nil, util.HandleError(err)
+	}
+	response := &proto.GrpcReadRelationsResponse{Revision: result.Revision}
+	for _, relation := range result.Relations {
+		response.Relations = append(response.Relations, toGrpcTuple(relation.RelationTuple))
+	}
+	return response, nil
+}
+
+func (s grpcService) Watch(req *proto.GrpcWatchRelationsRequest, stream proto.Relation_WatchServer) error {
+
+	s.logger.Info("GRPC method : Watch", zap.String("method", "Watch"))
+	org_id, err := s.authorize(stream.Context(), req.Organization)
+	if err != nil {
+		return err
+	}
+
+	err = s.service.Watch(stream.Context(), org_id, req.Revision, func(change RelationChange) error {
+		return stream.Send(&proto.GrpcRelationChange{
+			Revision:  change.Revision,
+			Operation: string(change.Operation),
+			Tuple:     toGrpcTuple(change.Relation.RelationTuple),
+		})
+	})
+	if err != nil {
+		return util.HandleError(err)
+	}
+	return nil
+}
+
+// authorize validates the API key of the organization and returns the organization id.
+func (s grpcService) authorize(ctx context.Context, org_identifier string) (string, error) {
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", errors.New("missing metadata from request")
+	}
+	apiKeys := md.Get("API_KEY")
+	if len(apiKeys) == 0 {
+		return "", errors.New("missing API_KEY from request metadata")
+	}
+	validated, _ := s.validator.ValidateAPIKey(ctx, org_identifier, apiKeys[0])
+	if !validated {
+		return "", util.HandleError(&util.UnauthorizedError{})
+	}
+	org_id, err := s.organizations.GetIdByIdentifier(ctx, org_identifier)
+	if err != nil {
+		return "", util.HandleError(err)
+	}
+	return org_id, nil
+}
+
+func fromGrpcTuple(tuple *proto.GrpcRelationTuple) mongo_entity.RelationTuple {
+
+	if tuple == nil {
+		return mongo_entity.RelationTuple{}
+	}
+	return mongo_entity.RelationTuple{
+		ObjectType:      tuple.ObjectType,
+		ObjectID:        tuple.ObjectId,
+		Relation:        tuple.Relation,
+		SubjectType:     tuple.SubjectType,
+		SubjectID:       tuple.SubjectId,
+		SubjectRelation: tuple.SubjectRelation,
+	}
+}
+
+func fromGrpcTuples(tuples []*proto.GrpcRelationTuple) []RelationRequest {
+
+	requests := make([]RelationRequest, 0, len(tuples))
+	for _, tuple := range tuples {
+		requests = append(requests, RelationRequest{RelationTuple: fromGrpcTuple(tuple)})
+	}
+	return requests
+}
+
+func toGrpcTuple(tuple mongo_entity.RelationTuple) *proto.GrpcRelationTuple {
+
+	return &proto.GrpcRelationTuple{
+		ObjectType:      tuple.ObjectType,
+		ObjectId:        tuple.ObjectID,
+		Relation:        tuple.Relation,
+		SubjectType:     tuple.SubjectType,
+		SubjectId:       tuple.SubjectID,
+		SubjectRelation: tuple.SubjectRelation,
+	}
+}
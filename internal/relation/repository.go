@@ -0,0 +1,123 @@
+package relation
+
+import (
+	"context"
+
+	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository interface {
+	GetRelations(ctx context.Context, org_id string) ([]mongo_entity.RelationTuple, int64, error)
+	GetChanges(ctx context.Context, org_id string) ([]mongo_entity.RelationChange, int64, error)
+	GetRevision(ctx context.Context, org_id string) (int64, error)
+	Write(ctx context.Context, org_id string, revision int64, relations []mongo_entity.RelationTuple, changes []mongo_entity.RelationChange) (bool, error)
+	GetSchema(ctx context.Context, org_id string) (*mongo_entity.Organization, error)
+	GetOrganizationRevision(ctx context.Context, org_id string) (int64, error)
+}
+
+type repository struct {
+	mongoClient *mongo.Client
+	mongoColl   *mongo.Collection
+}
+
+func NewRepository(mongodb *db.MongoDB) Repository {
+
+	orgCollection := mongodb.MongoClient.Database(mongodb.MongoConfig.DBName).Collection(mongodb.MongoConfig.OrganizationCollectionName)
+
+	return repository{mongoClient: mongodb.MongoClient, mongoColl: orgCollection}
+}
+
+func (r repository) findOrganization(ctx context.Context, org_id string, projection bson.M) (*mongo_entity.Organization, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": orgId}
+	var org mongo_entity.Organization
+	if err := r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &util.NotFoundError{Path: "Organization"}
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Get all relation tuples with the revision they were read at.
+func (r repository) GetRelations(ctx context.Context, org_id string) ([]mongo_entity.RelationTuple, int64, error) {
+
+	org, err := r.findOrganization(ctx, org_id, bson.M{"relations": 1, "relations_revision": 1})
+	if err != nil {
+		return nil, 0, err
+	}
+	return org.Relations, org.RelationsRevision, nil
+}
+
+// Get the retained relation changes with the current revision.
+func (r repository) GetChanges(ctx context.Context, org_id string) ([]mongo_entity.RelationChange, int64, error) {
+
+	org, err := r.findOrganization(ctx, org_id, bson.M{"relation_changes": 1, "relations_revision": 1})
+	if err != nil {
+		return nil, 0, err
+	}
+	return org.RelationChanges, org.RelationsRevision, nil
+}
+
+// Get the current revision of the relations.
+func (r repository) GetRevision(ctx context.Context, org_id string) (int64, error) {
+
+	org, err := r.findOrganization(ctx, org_id, bson.M{"relations_revision": 1})
+	if err != nil {
+		return 0, err
+	}
+	return org.RelationsRevision, nil
+}
+
+// Write replaces the relation tuples and records the changes, only if the relations are still at the
+// given revision. It reports false when another write got there first.
+func (r repository) Write(ctx context.Context, org_id string, relations_revision int64, relations []mongo_entity.RelationTuple, changes []mongo_entity.RelationChange) (bool, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": orgId, "relations_revision": relations_revision}
+	if relations_revision == 0 {
+		// Organizations which never had relations written have no revision yet.
+		filter["relations_revision"] = bson.M{"$in": bson.A{int64(0), nil}}
+	}
+	update := bson.M{
+		"$set": bson.M{"relations": relations, "relations_revision": relations_revision + 1},
+		"$push": bson.M{"relation_changes": bson.M{
+			"$each":  changes,
+			"$slice": -mongo_entity.MaxRelationChanges,
+		}},
+	}
+	result, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Get the resources, users and groups a relation tuple may refer to.
+func (r repository) GetSchema(ctx context.Context, org_id string) (*mongo_entity.Organization, error) {
+
+	return r.findOrganization(ctx, org_id, bson.M{"resources.identifier": 1, "resources.relations": 1, "users.identifier": 1, "groups.identifier": 1})
+}
+
+// Get the revision the organization is at.
+func (r repository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, org_id)
+}
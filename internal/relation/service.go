@@ -0,0 +1,493 @@
+package relation
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/event"
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.uber.org/zap"
+)
+
+type Service interface {
+	Query(ctx context.Context, org_id string, filter Filter) (RelationsResponse, error)
+	Create(ctx context.Context, org_id string, input RelationRequest) (Relation, error)
+	Delete(ctx context.Context, org_id string, input RelationRequest) error
+	Write(ctx context.Context, org_id string, input WriteRelationsRequest) (WriteRelationsResponse, error)
+	Watch(ctx context.Context, org_id string, revision string, send func(RelationChange) error) error
+	CheckWatch(ctx context.Context, org_id string, revision string) error
+}
+
+type Relation struct {
+	mongo_entity.RelationTuple `bson:",inline"`
+	Tuple                      string `json:"tuple" bson:"-"`
+}
+
+type RelationsResponse struct {
+	Relations []Relation `json:"relations"`
+	// Revision the relations were read at.
+	Revision string `json:"revision"`
+}
+
+// RelationRequest holds a relation tuple, either in its parts or written as a single tuple
+// such as document:42#viewer@user:alice.
+type RelationRequest struct {
+	mongo_entity.RelationTuple `bson:",inline"`
+	Tuple                      string `json:"tuple,omitempty" bson:"-"`
+}
+
+// ToTuple returns the tuple of the request.
+func (m RelationRequest) ToTuple() (mongo_entity.RelationTuple, error) {
+
+	if m.Tuple != "" {
+		return mongo_entity.ParseRelationTuple(m.Tuple)
+	}
+	return m.RelationTuple, m.RelationTuple.Validate()
+}
+
+// Filter matches relation tuples by any of their parts. Empty parts match everything.
+type Filter struct {
+	ObjectType      string `json:"object_type,omitempty" query:"object_type"`
+	ObjectID        string `json:"object_id,omitempty" query:"object_id"`
+	Relation        string `json:"relation,omitempty" query:"relation"`
+	SubjectType     string `json:"subject_type,omitempty" query:"subject_type"`
+	SubjectID       string `json:"subject_id,omitempty" query:"subject_id"`
+	SubjectRelation string `json:"subject_relation,omitempty" query:"subject_relation"`
+}
+
+func (f Filter) Matches(tuple mongo_entity.RelationTuple) bool {
+
+	return matches(f.ObjectType, tuple.ObjectType) &&
+		matches(f.ObjectID, tuple.ObjectID) &&
+		matches(f.Relation, tuple.Relation) &&
+		matches(f.SubjectType, tuple.SubjectType) &&
+		matches(f.SubjectID, tuple.SubjectID) &&
+		matches(f.SubjectRelation, tuple.SubjectRelation)
+}
+
+func matches(expected string, value string) bool {
+
+	return expected == "" || expected == value
+}
+
+type PreconditionOperation string
+
+const (
+	MustExist    PreconditionOperation = "must_exist"
+	MustNotExist PreconditionOperation = "must_not_exist"
+)
+
+// Precondition requires some tuple matching the filter to exist, or no tuple to match it,
+// before a write is applied.
+type Precondition struct {
+	Operation PreconditionOperation `json:"operation"`
+	Filter    Filter                `json:"filter"`
+}
+
+// WriteRelationsRequest writes and deletes relation tuples in a single revision, only if every
+// precondition holds. Writing a tuple which exists, or deleting one which doesn't, changes nothing.
+type WriteRelationsRequest struct {
+	Writes        []RelationRequest `json:"writes,omitempty"`
+	Deletes       []RelationRequest `json:"deletes,omitempty"`
+	Preconditions []Precondition    `json:"preconditions,omitempty"`
+}
+
+type WriteRelationsResponse struct {
+	// Revision of the relations after the write, to watch from. The consistency token for checks
+	// is the organization revision, returned in the revision header.
+	Revision string `json:"revision"`
+}
+
+// RelationChange is a change streamed to watchers, with the revision token to resume after it.
+type RelationChange struct {
+	Revision  string                         `json:"revision"`
+	Operation mongo_entity.RelationOperation `json:"operation"`
+	Relation  Relation                       `json:"relation"`
+}
+
+// Writes which lose a race with another write are retried this many times.
+const maxWriteAttempts = 5
+
+type service struct {
+	repo          Repository
+	logger        *zap.Logger
+	events        *event.Bus
+	watchInterval time.Duration
+}
+
+func NewService(repo Repository, logger *zap.Logger, events *event.Bus) Service {
+
+	return service{repo: repo, logger: logger, events: events, watchInterval: time.Second}
+}
+
+func newRelation(tuple mongo_entity.RelationTuple) Relation {
+
+	return Relation{RelationTuple: tuple, Tuple: tuple.String()}
+}
+
+func formatRevision(revision int64) string {
+
+	return strconv.FormatInt(revision, 10)
+}
+
+func parseRevision(revision string) (int64, error) {
+
+	parsed, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, &util.InvalidInputError{Path: "revision " + revision}
+	}
+	return parsed, nil
+}
+
+// Get the relation tuples matching the filter.
+func (s service) Query(ctx context.Context, org_id string, filter Filter) (RelationsResponse, error) {
+
+	tuples, revision, err := s.repo.GetRelations(ctx, org_id)
+	if err != nil {
+		s.logger.Error("Error while retrieving relations.",
+			zap.String("organization_id", org_id))
+		return RelationsResponse{}, err
+	}
+	response := RelationsResponse{Relations: []Relation{}, Revision: formatRevision(revision)}
+	for _, tuple := range tuples {
+		if filter.Matches(tuple) {
+			response.Relations = append(response.Relations, newRelation(tuple))
+		}
+	}
+	return response, nil
+}
+
+// Create new relation tuple.
+func (s service) Create(ctx context.Context, org_id string, req RelationRequest) (Relation, error) {
+
+	tuple, err := req.ToTuple()
+	if err != nil {
+		s.logger.Error("Error while validating relation create request.")
+		return Relation{}, &util.InvalidInputError{Path: "Invalid input for relation : " + err.Error()}
+	}
+
+	_, err = s.Write(ctx, org_id, WriteRelationsRequest{
+		Writes:        []RelationRequest{{RelationTuple: tuple}},
+		Preconditions: []Precondition{{Operation: MustNotExist, Filter: exactFilter(tuple)}},
+	})
+	if _, failed := err.(*util.PreconditionFailedError); failed {
+		s.logger.Debug("Relation already exists.")
+		return Relation{}, &util.AlreadyExistsError{Path: "Relation : " + tuple.String()}
+	}
+	if err != nil {
+		return Relation{}, err
+	}
+	return newRelation(tuple), nil
+}
+
+// Delete relation tuple.
+func (s service) Delete(ctx context.Context, org_id string, req RelationRequest) error {
+
+	tuple, err := req.ToTuple()
+	if err != nil {
+		s.logger.Error("Error while validating relation delete request.")
+		return &util.InvalidInputError{Path: "Invalid input for relation : " + err.Error()}
+	}
+
+	_, err = s.Write(ctx, org_id, WriteRelationsRequest{
+		Deletes:       []RelationRequest{{RelationTuple: tuple}},
+		Preconditions: []Precondition{{Operation: MustExist, Filter: exactFilter(tuple)}},
+	})
+	if _, failed := err.(*util.PreconditionFailedError); failed {
+		s.logger.Debug("Relation not exists.", zap.String("tuple", tuple.String()))
+		return &util.NotFoundError{Path: "Relation " + tuple.String() + " not exists."}
+	}
+	return err
+}
+
+func exactFilter(tuple mongo_entity.RelationTuple) Filter {
+
+	return Filter(tuple)
+}
+
+// Write relation tuples in a batch.
+func (s service) Write(ctx context.Context, org_id string, req WriteRelationsRequest) (WriteRelationsResponse, error) {
+
+	writes, err := toTuples(req.Writes)
+	if err != nil {
+		s.logger.Error("Error while validating relation write request.")
+		return WriteRelationsResponse{}, err
+	}
+	deletes, err := toTuples(req.Deletes)
+	if err != nil {
+		s.logger.Error("Error while validating relation write request.")
+		return WriteRelationsResponse{}, err
+	}
+	for _, precondition := range req.Preconditions {
+		if precondition.Operation != MustExist && precondition.Operation != MustNotExist {
+			return WriteRelationsResponse{}, &util.InvalidInputError{Path: "precondition operation " + string(precondition.Operation)}
+		}
+	}
+
+	if len(writes) > 0 {
+		org, err := s.repo.GetSchema(ctx, org_id)
+		if err != nil {
+			s.logger.Error("Error while getting the relation schema.", zap.String("organization_id", org_id))
+			return WriteRelationsResponse{}, err
+		}
+		for _, tuple := range writes {
+			if err := validateTuple(org, tuple); err != nil {
+				return WriteRelationsResponse{}, err
+			}
+		}
+	}
+
+	// Apply the batch to the latest relations, and retry when another write changed them meanwhile.
+	for attempt := 0; attempt < maxWriteAttempts; attempt++ {
+		current, relationsRevision, err := s.repo.GetRelations(ctx, org_id)
+		if err != nil {
+			return WriteRelationsResponse{}, err
+		}
+		for _, precondition := range req.Preconditions {
+			if err := checkPrecondition(current, precondition); err != nil {
+				return WriteRelationsResponse{}, err
+			}
+		}
+		relations, changes := applyChanges(current, writes, deletes, relationsRevision+1)
+		if len(changes) == 0 {
+			return WriteRelationsResponse{Revision: formatRevision(relationsRevision)}, nil
+		}
+		written, err := s.repo.Write(ctx, org_id, relationsRevision, relations, changes)
+		if err != nil {
+			s.logger.Error("Error while writing relations.", zap.String("organization_id", org_id))
+			return WriteRelationsResponse{}, err
+		}
+		if written {
+			s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.RelationEntity})
+			rev, err := s.repo.GetOrganizationRevision(ctx, org_id)
+			if err != nil {
+				s.logger.Error("Error while reading organization revision.", zap.String("organization_id", org_id), zap.Error(err))
+				return WriteRelationsResponse{}, err
+			}
+			revision.Track(ctx, rev)
+			return WriteRelationsResponse{Revision: formatRevision(relationsRevision + 1)}, nil
+		}
+		s.logger.Debug("Relations changed while writing, retrying.", zap.String("organization_id", org_id))
+	}
+	return WriteRelationsResponse{}, &util.SystemError{Message: "Relations are changing too often to write, please retry."}
+}
+
+func toTuples(requests []RelationRequest) ([]mongo_entity.RelationTuple, error) {
+
+	tuples := make([]mongo_entity.RelationTuple, 0, len(requests))
+	for _, request := range requests {
+		tuple, err := request.ToTuple()
+		if err != nil {
+			return nil, &util.InvalidInputError{Path: "Invalid input for relation : " + err.Error()}
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, nil
+}
+
+func checkPrecondition(relations []mongo_entity.RelationTuple, precondition Precondition) error {
+
+	exists := false
+	for _, tuple := range relations {
+		if precondition.Filter.Matches(tuple) {
+			exists = true
+			break
+		}
+	}
+	if exists != (precondition.Operation == MustExist) {
+		return &util.PreconditionFailedError{Path: string(precondition.Operation)}
+	}
+	return nil
+}
+
+// applyChanges returns the relations after the deletes and writes, with the changes they made.
+func applyChanges(relations []mongo_entity.RelationTuple, writes []mongo_entity.RelationTuple, deletes []mongo_entity.RelationTuple, revision int64) ([]mongo_entity.RelationTuple, []mongo_entity.RelationChange) {
+
+	existing := make(map[mongo_entity.RelationTuple]bool)
+	for _, tuple := range relations {
+		existing[tuple] = true
+	}
+	changes := []mongo_entity.RelationChange{}
+	for _, tuple := range deletes {
+		if existing[tuple] {
+			delete(existing, tuple)
+			changes = append(changes, mongo_entity.RelationChange{Revision: revision, Operation: mongo_entity.DeleteRelation, Tuple: tuple})
+		}
+	}
+	result := []mongo_entity.RelationTuple{}
+	for _, tuple := range relations {
+		if existing[tuple] {
+			result = append(result, tuple)
+		}
+	}
+	for _, tuple := range writes {
+		if !existing[tuple] {
+			existing[tuple] = true
+			result = append(result, tuple)
+			changes = append(changes, mongo_entity.RelationChange{Revision: revision, Operation: mongo_entity.WriteRelation, Tuple: tuple})
+		}
+	}
+	return result, changes
+}
+
+// Watch sends every relation change after the given revision until the context is done.
+// Without a revision only changes from now on are sent.
+func (s service) Watch(ctx context.Context, org_id string, revision string, send func(RelationChange) error) error {
+
+	from, err := s.watchFrom(ctx, org_id, revision)
+	if err != nil {
+		return err
+	}
+
+	for {
+		changes, err := s.changesAfter(ctx, org_id, from)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if err := send(RelationChange{
+				Revision:  formatRevision(change.Revision),
+				Operation: change.Operation,
+				Relation:  newRelation(change.Tuple),
+			}); err != nil {
+				return err
+			}
+			from = change.Revision
+		}
+
+		timer := time.NewTimer(s.watchInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// CheckWatch makes sure the changes after the revision can be watched, so a watch can fail before it starts.
+func (s service) CheckWatch(ctx context.Context, org_id string, revision string) error {
+
+	from, err := s.watchFrom(ctx, org_id, revision)
+	if err != nil {
+		return err
+	}
+	_, err = s.changesAfter(ctx, org_id, from)
+	return err
+}
+
+// watchFrom resolves the revision a watch starts after, the current one when no revision is given.
+func (s service) watchFrom(ctx context.Context, org_id string, revision string) (int64, error) {
+
+	if revision == "" {
+		return s.repo.GetRevision(ctx, org_id)
+	}
+	return parseRevision(revision)
+}
+
+// changesAfter returns the kept changes after the revision. It fails when the revision is ahead of the
+// relations, or when changes after it are no longer kept.
+func (s service) changesAfter(ctx context.Context, org_id string, from int64) ([]mongo_entity.RelationChange, error) {
+
+	latest, err := s.repo.GetRevision(ctx, org_id)
+	if err != nil {
+		return nil, err
+	}
+	if latest < from {
+		return nil, &util.InvalidInputError{Path: "revision " + formatRevision(from) + ", it is ahead of the relations."}
+	}
+	if latest == from {
+		return nil, nil
+	}
+	changes, _, err := s.repo.GetChanges(ctx, org_id)
+	if err != nil {
+		return nil, err
+	}
+	// Older changes are dropped, so a watcher which fell too far behind has to read the relations again.
+	if len(changes) == 0 || from < oldestWatchable(changes) {
+		return nil, &util.PreconditionFailedError{Path: "revision " + formatRevision(from) + ", changes after it are no longer kept, read the relations again"}
+	}
+	after := []mongo_entity.RelationChange{}
+	for _, change := range changes {
+		if change.Revision > from {
+			after = append(after, change)
+		}
+	}
+	return after, nil
+}
+
+// oldestWatchable returns the oldest revision a watch can resume after, with every change after it kept.
+// Once the changes are trimmed to the latest ones, the trimming may have cut through the changes of the
+// oldest kept revision, so only the revisions after it are whole.
+func oldestWatchable(changes []mongo_entity.RelationChange) int64 {
+
+	if len(changes) >= mongo_entity.MaxRelationChanges {
+		return changes[0].Revision
+	}
+	return changes[0].Revision - 1
+}
+
+// validateTuple checks the tuple against the relation definitions of the organization's resources.
+// Objects of the built-in group namespace are the organization's groups, with the member relation.
+func validateTuple(org *mongo_entity.Organization, tuple mongo_entity.RelationTuple) error {
+
+	if !hasRelation(org, tuple.ObjectType, tuple.ObjectID, tuple.Relation) {
+		return &util.InvalidInputError{Path: "Relation " + tuple.Relation + " is not defined for " + tuple.ObjectType + ":" + tuple.ObjectID}
+	}
+
+	if tuple.SubjectRelation != "" {
+		if !hasRelation(org, tuple.SubjectType, tuple.SubjectID, tuple.SubjectRelation) {
+			return &util.InvalidInputError{Path: "Relation " + tuple.SubjectRelation + " is not defined for " + tuple.SubjectType + ":" + tuple.SubjectID}
+		}
+		return nil
+	}
+
+	switch tuple.SubjectType {
+	case mongo_entity.UserSubjectType:
+		for _, user := range org.Users {
+			if user.Identifier == tuple.SubjectID {
+				return nil
+			}
+		}
+		return &util.InvalidInputError{Path: "Invalid user " + tuple.SubjectID}
+	case mongo_entity.GroupObjectType:
+		if hasGroup(org, tuple.SubjectID) {
+			return nil
+		}
+		return &util.InvalidInputError{Path: "Invalid group " + tuple.SubjectID}
+	}
+	// Any other subject is an object, referred to by tuple to userset rewrites.
+	for _, resource := range org.Resources {
+		if resource.Identifier == tuple.SubjectType {
+			return nil
+		}
+	}
+	return &util.InvalidInputError{Path: "Invalid subject type " + tuple.SubjectType}
+}
+
+func hasRelation(org *mongo_entity.Organization, objectType string, objectID string, relation string) bool {
+
+	if objectType == mongo_entity.GroupObjectType {
+		return relation == mongo_entity.MemberRelation && hasGroup(org, objectID)
+	}
+	for _, resource := range org.Resources {
+		if resource.Identifier == objectType {
+			_, exists := mongo_entity.FindRelation(resource.Relations, relation)
+			return exists
+		}
+	}
+	return false
+}
+
+func hasGroup(org *mongo_entity.Organization, identifier string) bool {
+
+	for _, group := range org.Groups {
+		if group.Identifier == identifier {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package relation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/test"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"github.com/shashimalcse/cronuseo/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func Test_service_Write(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := NewService(repo, logger, nil)
+
+	ctx := context.Background()
+
+	// batch write in a single revision
+	result, err := s.Write(ctx, "org", WriteRelationsRequest{Writes: []RelationRequest{
+		{Tuple: "document:42#owner@user:alice"},
+		{Tuple: "document:42#viewer@group:finance#member"},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Revision)
+	assert.Len(t, repo.changes, 2)
+
+	// writing an existing tuple changes nothing
+	result, err = s.Write(ctx, "org", WriteRelationsRequest{Writes: []RelationRequest{{Tuple: "document:42#owner@user:alice"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Revision)
+
+	// undefined relation
+	_, err = s.Write(ctx, "org", WriteRelationsRequest{Writes: []RelationRequest{{Tuple: "document:42#admin@user:alice"}}})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// unknown user
+	_, err = s.Write(ctx, "org", WriteRelationsRequest{Writes: []RelationRequest{{Tuple: "document:42#owner@user:mallory"}}})
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// failed precondition leaves the relations untouched
+	_, err = s.Write(ctx, "org", WriteRelationsRequest{
+		Deletes:       []RelationRequest{{Tuple: "document:42#owner@user:alice"}},
+		Preconditions: []Precondition{{Operation: MustExist, Filter: Filter{ObjectType: "document", ObjectID: "7"}}},
+	})
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+	assert.Len(t, repo.relations, 2)
+
+	// delete and write in one batch
+	result, err = s.Write(ctx, "org", WriteRelationsRequest{
+		Deletes:       []RelationRequest{{Tuple: "document:42#owner@user:alice"}},
+		Writes:        []RelationRequest{{Tuple: "document:42#owner@user:bob"}},
+		Preconditions: []Precondition{{Operation: MustNotExist, Filter: Filter{SubjectID: "bob"}}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2", result.Revision)
+
+	// another write between reading and writing the relations is retried
+	repo.conflicts = 1
+	_, err = s.Create(ctx, "org", RelationRequest{Tuple: "document:7#owner@user:alice"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), repo.revision)
+
+	// single create and delete
+	_, err = s.Create(ctx, "org", RelationRequest{Tuple: "document:7#owner@user:alice"})
+	assert.IsType(t, &util.AlreadyExistsError{}, err)
+	err = s.Delete(ctx, "org", RelationRequest{Tuple: "document:7#owner@user:bob"})
+	assert.IsType(t, &util.NotFoundError{}, err)
+
+	// read by filter
+	read, err := s.Query(ctx, "org", Filter{ObjectType: "document", ObjectID: "42"})
+	assert.Nil(t, err)
+	assert.Equal(t, "3", read.Revision)
+	if assert.Len(t, read.Relations, 2) {
+		assert.Equal(t, "document:42#viewer@group:finance#member", read.Relations[0].Tuple)
+		assert.Equal(t, "document:42#owner@user:bob", read.Relations[1].Tuple)
+	}
+	read, err = s.Query(ctx, "org", Filter{SubjectType: "group", SubjectRelation: "member"})
+	assert.Nil(t, err)
+	assert.Len(t, read.Relations, 1)
+}
+
+func Test_grpcService_Write(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	repo.orgRevision = 7
+	s := NewGrpcService(NewService(repo, logger, nil), mockOrganizations{}, mockValidator{}, logger)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("API_KEY", "key"))
+
+	// the write returns the organization revision it reached, like the revision header over REST
+	response, err := s.Write(ctx, &proto.GrpcWriteRelationsRequest{Organization: "super", Writes: []*proto.GrpcRelationTuple{
+		{ObjectType: "document", ObjectId: "42", Relation: "owner", SubjectType: "user", SubjectId: "alice"},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", response.Revision)
+	assert.Equal(t, "8", response.OrganizationRevision)
+
+	// a write which changes nothing does not move the organization
+	response, err = s.Write(ctx, &proto.GrpcWriteRelationsRequest{Organization: "super", Writes: []*proto.GrpcRelationTuple{
+		{ObjectType: "document", ObjectId: "42", Relation: "owner", SubjectType: "user", SubjectId: "alice"},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", response.Revision)
+	assert.Equal(t, "", response.OrganizationRevision)
+}
+
+type mockOrganizations struct{}
+
+func (mockOrganizations) GetIdByIdentifier(ctx context.Context, identifier string) (string, error) {
+	return "org", nil
+}
+
+type mockValidator struct{}
+
+func (mockValidator) ValidateAPIKey(ctx context.Context, org_identifier string, apiKey string) (bool, error) {
+	return apiKey == "key", nil
+}
+
+func Test_service_Watch(t *testing.T) {
+	logger := test.InitLogger()
+	repo := newMockRepository()
+	s := service{repo: repo, logger: logger, watchInterval: time.Millisecond}
+
+	ctx := context.Background()
+	for _, tuple := range []string{"document:1#owner@user:alice", "document:2#owner@user:alice", "document:3#owner@user:bob"} {
+		_, err := s.Create(ctx, "org", RelationRequest{Tuple: tuple})
+		assert.Nil(t, err)
+	}
+
+	// changes after revision 1, until the watcher stops
+	watchCtx, cancel := context.WithCancel(ctx)
+	var changes []RelationChange
+	err := s.Watch(watchCtx, "org", "1", func(change RelationChange) error {
+		changes = append(changes, change)
+		if len(changes) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "2", changes[0].Revision)
+		assert.Equal(t, mongo_entity.WriteRelation, changes[0].Operation)
+		assert.Equal(t, "document:2#owner@user:alice", changes[0].Relation.Tuple)
+		assert.Equal(t, "3", changes[1].Revision)
+	}
+
+	// changes which are no longer kept
+	kept := repo.changes
+	repo.changes = kept[1:]
+	err = s.Watch(ctx, "org", "0", func(change RelationChange) error { return nil })
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+
+	// once trimmed, the oldest kept revision may have lost some of its changes
+	tuple, _ := mongo_entity.ParseRelationTuple("document:4#owner@user:bob")
+	trimmed := []mongo_entity.RelationChange{}
+	for len(trimmed) < mongo_entity.MaxRelationChanges-1 {
+		trimmed = append(trimmed, mongo_entity.RelationChange{Revision: 2, Operation: mongo_entity.WriteRelation, Tuple: tuple})
+	}
+	repo.changes = append(trimmed, kept[2])
+	err = s.Watch(ctx, "org", "1", func(change RelationChange) error { return nil })
+	assert.IsType(t, &util.PreconditionFailedError{}, err)
+	watchCtx, cancel = context.WithCancel(ctx)
+	changes = nil
+	err = s.Watch(watchCtx, "org", "2", func(change RelationChange) error {
+		changes = append(changes, change)
+		cancel()
+		return nil
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "3", changes[0].Revision)
+	}
+	repo.changes = kept
+
+	// revision ahead of the relations
+	err = s.Watch(ctx, "org", "10", func(change RelationChange) error { return nil })
+	assert.IsType(t, &util.InvalidInputError{}, err)
+
+	// the endpoint rejects a revision which can not be watched before the stream starts
+	router := test.MockRouter()
+	RegisterHandlers(router.Group(""), s)
+	for _, revision := range []string{"10", "x"} {
+		test.Endpoint(t, router, test.APITestCase{Name: "watch " + revision, Method: "GET", URL: "/o/org/relations/watch?revision=" + revision, WantStatus: http.StatusBadRequest})
+	}
+	repo.changes = kept[1:]
+	test.Endpoint(t, router, test.APITestCase{Name: "watch 0", Method: "GET", URL: "/o/org/relations/watch?revision=0", WantStatus: http.StatusPreconditionFailed})
+}
+
+type mockRepository struct {
+	relations []mongo_entity.RelationTuple
+	changes   []mongo_entity.RelationChange
+	revision  int64
+	// conflicts is how many of the next writes lose a race with another write.
+	conflicts   int
+	orgRevision int64
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{}
+}
+
+func (m *mockRepository) GetRelations(ctx context.Context, org_id string) ([]mongo_entity.RelationTuple, int64, error) {
+	return m.relations, m.revision, nil
+}
+
+func (m *mockRepository) GetChanges(ctx context.Context, org_id string) ([]mongo_entity.RelationChange, int64, error) {
+	return m.changes, m.revision, nil
+}
+
+func (m *mockRepository) GetRevision(ctx context.Context, org_id string) (int64, error) {
+	return m.revision, nil
+}
+
+func (m *mockRepository) Write(ctx context.Context, org_id string, revision int64, relations []mongo_entity.RelationTuple, changes []mongo_entity.RelationChange) (bool, error) {
+	if m.conflicts > 0 || revision != m.revision {
+		m.conflicts--
+		return false, nil
+	}
+	m.relations = relations
+	m.changes = append(m.changes, changes...)
+	m.revision = revision + 1
+	m.orgRevision++
+	return true, nil
+}
+
+func (m *mockRepository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+	return m.orgRevision, nil
+}
+
+func (m *mockRepository) GetSchema(ctx context.Context, org_id string) (*mongo_entity.Organization, error) {
+	return &mongo_entity.Organization{
+		Resources: []mongo_entity.Resource{
+			{Identifier: "document", Relations: []mongo_entity.RelationDefinition{{Name: "owner"}, {Name: "viewer"}}},
+		},
+		Users:  []mongo_entity.User{{Identifier: "alice"}, {Identifier: "bob"}},
+		Groups: []mongo_entity.Group{{Identifier: "finance"}},
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -24,6 +25,8 @@ type Repository interface {
 	CheckResourceExistsByIdentifier(ctx context.Context, org_id string, key string) (bool, error)
 	CheckActionAlreadyAddedToResourceByIdentifier(ctx context.Context, org_id string, resource_id string, action_identifier string) (bool, error)
 	CheckActionExistsByIdentifier(ctx context.Context, org_id string, resource_identifier string, action_identifier string) (bool, error)
+	CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error)
+	GetOrganizationRevision(ctx context.Context, org_id string) (int64, error)
 }
 
 type repository struct {
@@ -82,7 +85,7 @@ func (r repository) Create(ctx context.Context, org_id string, resource mongo_en
 	// Update the APIResources array for the given organization
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$push": bson.M{"resources": resource}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -107,7 +110,18 @@ func (r repository) Update(ctx context.Context, org_id string, id string, update
 
 		filter := bson.M{"_id": orgId, "resources._id": resId}
 		update := bson.M{"$set": bson.M{"resources.$.display_name": *update_resource.DisplayName}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replace the relation definitions of the resource.
+	if update_resource.Relations != nil {
+
+		filter := bson.M{"_id": orgId, "resources._id": resId}
+		update := bson.M{"$set": bson.M{"resources.$.relations": *update_resource.Relations}}
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -134,7 +148,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		update := bson.M{"$push": bson.M{"resources.$.actions": bson.M{
 			"$each": patch_resource.AddedActions,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -146,7 +160,31 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		update := bson.M{"$pull": bson.M{"resources.$.actions": bson.M{
 			"identifier": bson.M{"$in": patch_resource.RemovedActions},
 		}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
+		if err != nil {
+			return err
+		}
+	}
+
+	// add policies
+	if len(patch_resource.AddedPolicies) > 0 {
+
+		filter := bson.M{"_id": orgId, "resources._id": resId}
+		update := bson.M{"$addToSet": bson.M{"resources.$.policies": bson.M{
+			"$each": patch_resource.AddedPolicies,
+		}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove policies
+	if len(patch_resource.RemovedPolicies) > 0 {
+
+		filter := bson.M{"_id": orgId, "resources._id": resId}
+		update := bson.M{"$pull": bson.M{"resources.$.policies": bson.M{"$in": patch_resource.RemovedPolicies}}}
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -228,7 +266,7 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$pull": bson.M{"resources": bson.M{"_id": resId}}}
 	// Find the resource document in the "organizations" collection
-	result, err := r.mongoColl.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(false))
+	result, err := r.mongoColl.UpdateOne(context.Background(), filter, revision.Update(update), options.Update().SetUpsert(false))
 	if err != nil {
 		return err
 	}
@@ -269,6 +307,34 @@ func (r repository) CheckResourceExistById(ctx context.Context, org_id string, i
 	}
 }
 
+// Check if policy exists by id.
+func (r repository) CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return false, err
+	}
+
+	policyId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": orgId, "policies._id": policyId}
+
+	// Search for the policy in the "organizations" collection
+	result := r.mongoColl.FindOne(context.Background(), filter)
+
+	// Check if the policy was found
+	if result.Err() == nil {
+		return true, nil
+	} else if result.Err() == mongo.ErrNoDocuments {
+		return false, nil
+	} else {
+		return false, result.Err()
+	}
+}
+
 // Check if resource exists by key.
 func (r repository) CheckResourceExistsByIdentifier(ctx context.Context, org_id string, identifier string) (bool, error) {
 
@@ -348,3 +414,9 @@ func (r repository) CheckActionExistsByIdentifier(ctx context.Context, org_id st
 		return false, result.Err()
 	}
 }
+
+// Get the revision the organization is at.
+func (r repository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, org_id)
+}
@@ -3,7 +3,9 @@ package resource
 import (
 	"context"
 
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
@@ -30,31 +32,56 @@ type CreateResourceRequest struct {
 	DisplayName string                    `json:"display_name" bson:"display_name"`
 	Actions     []mongo_entity.Action     `json:"actions,omitempty" bson:"actions"`
 	Type        mongo_entity.ResourceType `json:"type,omitempty" bson:"type"`
+	// Relations define the namespace for relation tuples about instances of the resource.
+	Relations []mongo_entity.RelationDefinition `json:"relations,omitempty" bson:"relations"`
+	// Policies must pass for every check on the resource.
+	Policies []primitive.ObjectID `json:"policies,omitempty" bson:"policies"`
 }
 
 func (m CreateResourceRequest) Validate() error {
 
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Identifier, validation.Required),
+		validation.Field(&m.Relations, validation.By(validateRelations)),
 	)
 }
 
 type UpdateResourceRequest struct {
-	DisplayName *string `json:"display_name" bson:"display_name"`
+	DisplayName *string                            `json:"display_name" bson:"display_name"`
+	Relations   *[]mongo_entity.RelationDefinition `json:"relations,omitempty" bson:"relations"`
+}
+
+func (m UpdateResourceRequest) Validate() error {
+
+	if m.Relations == nil {
+		return nil
+	}
+	return validateRelations(*m.Relations)
+}
+
+func validateRelations(value interface{}) error {
+
+	relations, _ := value.([]mongo_entity.RelationDefinition)
+	return mongo_entity.ValidateRelationDefinitions(relations)
 }
 
 type PatchResourceRequest struct {
-	AddedActions   []mongo_entity.Action `json:"added_actions,omitempty" bson:"added_actions"`
-	RemovedActions []string              `json:"removed_actions,omitempty" bson:"removed_actions"`
+	AddedActions    []mongo_entity.Action `json:"added_actions,omitempty" bson:"added_actions"`
+	RemovedActions  []string              `json:"removed_actions,omitempty" bson:"removed_actions"`
+	AddedPolicies   []primitive.ObjectID  `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies []primitive.ObjectID  `json:"removed_policies,omitempty" bson:"removed_policies"`
 }
 
 type UpdateResource struct {
-	DisplayName *string `json:"display_name" bson:"display_name"`
+	DisplayName *string                            `json:"display_name" bson:"display_name"`
+	Relations   *[]mongo_entity.RelationDefinition `json:"relations,omitempty" bson:"relations"`
 }
 
 type PatchResource struct {
-	AddedActions   []mongo_entity.Action `json:"added_actions,omitempty" bson:"added_actions"`
-	RemovedActions []string              `json:"removed_actions,omitempty" bson:"removed_actions"`
+	AddedActions    []mongo_entity.Action `json:"added_actions,omitempty" bson:"added_actions"`
+	RemovedActions  []string              `json:"removed_actions,omitempty" bson:"removed_actions"`
+	AddedPolicies   []primitive.ObjectID  `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies []primitive.ObjectID  `json:"removed_policies,omitempty" bson:"removed_policies"`
 }
 
 type Action struct {
@@ -65,11 +92,25 @@ type Action struct {
 type service struct {
 	repo   Repository
 	logger *zap.Logger
+	events *event.Bus
 }
 
-func NewService(repo Repository, logger *zap.Logger) Service {
+func NewService(repo Repository, logger *zap.Logger, events *event.Bus) Service {
 
-	return service{repo: repo, logger: logger}
+	return service{repo: repo, logger: logger, events: events}
+}
+
+// changed publishes a write to a resource, and tracks the revision the write moved the organization to.
+func (s service) changed(ctx context.Context, org_id string, id string) error {
+
+	s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.ResourceEntity, ID: id})
+	rev, err := s.repo.GetOrganizationRevision(ctx, org_id)
+	if err != nil {
+		s.logger.Error("Error while reading organization revision.", zap.String("organization_id", org_id), zap.Error(err))
+		return err
+	}
+	revision.Track(ctx, rev)
+	return nil
 }
 
 // Get resource by id.
@@ -98,6 +139,12 @@ func (s service) Create(ctx context.Context, org_id string, req CreateResourceRe
 		s.logger.Debug("Resource already exists.")
 		return Resource{}, &util.AlreadyExistsError{Path: "Resource : " + req.Identifier}
 	}
+	for _, policyId := range req.Policies {
+		exists, _ := s.repo.CheckPolicyExistById(ctx, org_id, policyId.Hex())
+		if !exists {
+			return Resource{}, &util.InvalidInputError{Path: "Invalid policy id " + policyId.String()}
+		}
+	}
 	resId := primitive.NewObjectID()
 	actions := []mongo_entity.Action{}
 	for _, action := range req.Actions {
@@ -114,12 +161,17 @@ func (s service) Create(ctx context.Context, org_id string, req CreateResourceRe
 		DisplayName: req.DisplayName,
 		Actions:     actions,
 		Type:        req.Type,
+		Relations:   req.Relations,
+		Policies:    req.Policies,
 	})
 	if err != nil {
 		s.logger.Info(err.Error())
 		s.logger.Error("Error while creating resource.", zap.String("organization_id", org_id), zap.String("resource identifier", req.Identifier))
 		return Resource{}, err
 	}
+	if err := s.changed(ctx, org_id, resId.Hex()); err != nil {
+		return Resource{}, err
+	}
 	return s.Get(ctx, org_id, resId.Hex())
 }
 
@@ -133,14 +185,23 @@ func (s service) Update(ctx context.Context, org_id string, id string, req Updat
 		return Resource{}, &util.NotFoundError{Path: "Resource " + id + " not exists."}
 	}
 
+	if err := req.Validate(); err != nil {
+		s.logger.Error("Error while validating resource update request.")
+		return Resource{}, &util.InvalidInputError{Path: "Invalid relations for resource : " + err.Error()}
+	}
+
 	if err := s.repo.Update(ctx, org_id, id, UpdateResource{
 		DisplayName: req.DisplayName,
+		Relations:   req.Relations,
 	}); err != nil {
 		s.logger.Error("Error while updating resource.",
 			zap.String("organization_id", org_id),
 			zap.String("resource_id", id))
 		return Resource{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return Resource{}, err
+	}
 	updatedResource, err := s.repo.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("Resource not exists.", zap.String("resource_id", id))
@@ -183,16 +244,27 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchR
 			return Resource{}, &util.NotFoundError{Path: "Action " + action + " not exists."}
 		}
 	}
+	for _, policyId := range req.AddedPolicies {
+		exists, _ := s.repo.CheckPolicyExistById(ctx, org_id, policyId.Hex())
+		if !exists {
+			return Resource{}, &util.InvalidInputError{Path: "Invalid policy id " + policyId.String()}
+		}
+	}
 
 	if err := s.repo.Patch(ctx, org_id, id, PatchResource{
-		AddedActions:   addedActions,
-		RemovedActions: removedActions,
+		AddedActions:    addedActions,
+		RemovedActions:  removedActions,
+		AddedPolicies:   req.AddedPolicies,
+		RemovedPolicies: req.RemovedPolicies,
 	}); err != nil {
 		s.logger.Error("Error while updating resource.",
 			zap.String("organization_id", org_id),
 			zap.String("resource_id", id))
 		return Resource{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return Resource{}, err
+	}
 	updatedResource, err := s.repo.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("Resource not exists.", zap.String("resource_id", id))
@@ -215,7 +287,7 @@ func (s service) Delete(ctx context.Context, org_id string, id string) error {
 			zap.String("resource_id", id))
 		return err
 	}
-	return nil
+	return s.changed(ctx, org_id, id)
 }
 
 // Pagination filter.
@@ -0,0 +1,123 @@
+package revision
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shashimalcse/cronuseo/internal/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Header carries the revision of the organization after a write, as a consistency token
+// which checks can ask to be at least as fresh as.
+const Header = "X-Cronuseo-Revision"
+
+// Update adds the increment of the organization revision to an update of its access data, so the
+// write and the revision it moves the organization to are applied together.
+func Update(update bson.M) bson.M {
+
+	inc, ok := update["$inc"].(bson.M)
+	if !ok {
+		inc = bson.M{}
+	}
+	inc["revision"] = 1
+	update["$inc"] = inc
+	return update
+}
+
+// Get returns the revision the organization is at.
+func Get(ctx context.Context, coll *mongo.Collection, org_id string) (int64, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := bson.M{"_id": orgId}
+	opts := options.FindOne().SetProjection(bson.M{"revision": 1})
+	var org struct {
+		Revision int64 `bson:"revision"`
+	}
+	if err := coll.FindOne(ctx, filter, opts).Decode(&org); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, &util.NotFoundError{Path: "Organization"}
+		}
+		return 0, err
+	}
+	return org.Revision, nil
+}
+
+func Format(revision int64) string {
+
+	return strconv.FormatInt(revision, 10)
+}
+
+// Parse reads a consistency token. An empty token is revision zero, which any data satisfies.
+func Parse(token string) (int64, error) {
+
+	if token == "" {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, &util.InvalidInputError{Path: "revision " + token}
+	}
+	return revision, nil
+}
+
+type trackerKey struct{}
+
+// tracker holds the latest revision reached by the writes of a request.
+type tracker struct {
+	revision int64
+}
+
+// Track records that a write of the current request moved its organization to the revision.
+func Track(ctx context.Context, revision int64) {
+
+	if t, ok := ctx.Value(trackerKey{}).(*tracker); ok {
+		for {
+			current := atomic.LoadInt64(&t.revision)
+			if revision <= current || atomic.CompareAndSwapInt64(&t.revision, current, revision) {
+				return
+			}
+		}
+	}
+}
+
+// Tracking returns a context which tracks the latest revision reached by the writes made with it.
+func Tracking(ctx context.Context) context.Context {
+
+	return context.WithValue(ctx, trackerKey{}, &tracker{})
+}
+
+// Tracked returns the latest revision reached by the writes made with a tracking context, zero if none.
+func Tracked(ctx context.Context) int64 {
+
+	if t, ok := ctx.Value(trackerKey{}).(*tracker); ok {
+		return atomic.LoadInt64(&t.revision)
+	}
+	return 0
+}
+
+// Middleware returns the revision reached by the writes of a request in the revision header.
+func Middleware() echo.MiddlewareFunc {
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := Tracking(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+			c.Response().Before(func() {
+				if revision := Tracked(ctx); revision > 0 {
+					c.Response().Header().Set(Header, Format(revision))
+				}
+			})
+			return next(c)
+		}
+	}
+}
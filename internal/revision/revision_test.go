@@ -0,0 +1,18 @@
+package revision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_Update(t *testing.T) {
+	// the revision is incremented by the same update as the write
+	update := Update(bson.M{"$set": bson.M{"display_name": "Super"}})
+	assert.Equal(t, bson.M{"$set": bson.M{"display_name": "Super"}, "$inc": bson.M{"revision": 1}}, update)
+
+	// increments of the write are kept
+	update = Update(bson.M{"$inc": bson.M{"relations_revision": 1}})
+	assert.Equal(t, bson.M{"$inc": bson.M{"relations_revision": 1, "revision": 1}}, update)
+}
@@ -2,9 +2,12 @@ package role
 
 import (
 	"context"
+	"time"
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -16,6 +19,7 @@ type Repository interface {
 	Get(ctx context.Context, org_id string, id string) (*RoleResponse, error)
 	GetRoleByIdentifier(ctx context.Context, org_id string, identifier string) (*mongo_entity.Role, error)
 	Query(ctx context.Context, org_id string) (*[]mongo_entity.Role, error)
+	GetRoleGraph(ctx context.Context, org_id string) ([]mongo_entity.Role, error)
 	Create(ctx context.Context, org_id string, user mongo_entity.Role) error
 	Update(ctx context.Context, org_id string, id string, update_role UpdateRole) error
 	Patch(ctx context.Context, org_id string, id string, update_role PatchRole) error
@@ -29,6 +33,10 @@ type Repository interface {
 	CheckPermissionExists(ctx context.Context, org_id string, role_id string, resource_identifier string, action_identifier string) (bool, error)
 	CheckGroupExistById(ctx context.Context, org_id string, id string) (bool, error)
 	CheckGroupAlreadyAssignToRoleById(ctx context.Context, org_id string, role_id string, group_id string) (bool, error)
+	CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error)
+	GetOrganizationRevision(ctx context.Context, org_id string) (int64, error)
+	ExpiredAssignments(ctx context.Context, now time.Time) ([]ExpiredAssignment, error)
+	RemoveExpiredAssignment(ctx context.Context, assignment ExpiredAssignment, now time.Time) (bool, error)
 }
 
 type repository struct {
@@ -78,6 +86,11 @@ func (r repository) Get(ctx context.Context, org_id string, id string) (*RoleRes
 	if err != nil {
 		return nil, err
 	}
+	// Parent roles are resolved to their identifiers by the service, along with inherited permissions.
+	parentRoles := []mongo_entity.AssignedRole{}
+	for _, parentId := range role.ParentRoles {
+		parentRoles = append(parentRoles, mongo_entity.AssignedRole{ID: parentId})
+	}
 	roleResponse := RoleResponse{
 		ID:          role.ID,
 		Identifier:  role.Identifier,
@@ -85,6 +98,8 @@ func (r repository) Get(ctx context.Context, org_id string, id string) (*RoleRes
 		Users:       assignedUsers,
 		Groups:      assignedGroups,
 		Permissions: role.Permissions,
+		ParentRoles: parentRoles,
+		Policies:    role.Policies,
 	}
 	return &roleResponse, nil
 }
@@ -126,7 +141,7 @@ func (r repository) Create(ctx context.Context, org_id string, role mongo_entity
 	// Update the APIResources array for the given organization
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$push": bson.M{"roles": role}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -134,7 +149,7 @@ func (r repository) Create(ctx context.Context, org_id string, role mongo_entity
 		for _, userId := range role.Users {
 			filter := bson.M{"_id": orgId, "users._id": userId}
 			update := bson.M{"$addToSet": bson.M{"users.$.roles": role.ID}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -145,7 +160,7 @@ func (r repository) Create(ctx context.Context, org_id string, role mongo_entity
 		for _, userId := range role.Groups {
 			filter := bson.M{"_id": orgId, "groups._id": userId}
 			update := bson.M{"$addToSet": bson.M{"groups.$.roles": role.ID}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -173,7 +188,7 @@ func (r repository) Update(ctx context.Context, org_id string, id string, update
 	if update_role.DisplayName != nil && *update_role.DisplayName != "" {
 		update["$set"].(bson.M)["roles.$.display_name"] = *update_role.DisplayName
 	}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -199,7 +214,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		update := bson.M{"$push": bson.M{"roles.$.users": bson.M{
 			"$each": patch_role.AddedUsers,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -207,10 +222,16 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		for _, userId := range patch_role.AddedUsers {
 			filter := bson.M{"_id": orgId, "users._id": userId}
 			update := bson.M{"$addToSet": bson.M{"users.$.roles": roleId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
+			for _, update := range mongo_entity.TimedRoleUpdates("users", []mongo_entity.TimedRole{patch_role.Bounds}) {
+				_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -219,15 +240,18 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 
 		filter := bson.M{"_id": orgId, "roles._id": roleId}
 		update := bson.M{"$pull": bson.M{"roles.$.users": bson.M{"$in": patch_role.RemovedUsers}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
 
 		for _, userId := range patch_role.RemovedUsers {
 			filter := bson.M{"_id": orgId, "users._id": userId}
-			update := bson.M{"$pull": bson.M{"users.$.roles": roleId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			update := bson.M{"$pull": bson.M{
+				"users.$.roles":       roleId,
+				"users.$.timed_roles": bson.M{"role": roleId},
+			}}
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -241,7 +265,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		update := bson.M{"$push": bson.M{"roles.$.groups": bson.M{
 			"$each": patch_role.AddedGroups,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -249,10 +273,16 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		for _, groupId := range patch_role.AddedGroups {
 			filter := bson.M{"_id": orgId, "groups._id": groupId}
 			update := bson.M{"$addToSet": bson.M{"groups.$.roles": roleId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
+			for _, update := range mongo_entity.TimedRoleUpdates("groups", []mongo_entity.TimedRole{patch_role.Bounds}) {
+				_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 	}
@@ -262,15 +292,18 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 
 		filter := bson.M{"_id": orgId, "roles._id": roleId}
 		update := bson.M{"$pull": bson.M{"roles.$.groups": bson.M{"$in": patch_role.RemovedGroups}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
 
 		for _, groupId := range patch_role.RemovedGroups {
 			filter := bson.M{"_id": orgId, "groups._id": groupId}
-			update := bson.M{"$pull": bson.M{"groups.$.roles": roleId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			update := bson.M{"$pull": bson.M{
+				"groups.$.roles":       roleId,
+				"groups.$.timed_roles": bson.M{"role": roleId},
+			}}
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -284,7 +317,55 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 		update := bson.M{"$push": bson.M{"roles.$.permissions": bson.M{
 			"$each": patch_role.AddedPermissions,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	// add parent roles
+	if len(patch_role.AddedParentRoles) > 0 {
+
+		filter := bson.M{"_id": orgId, "roles._id": roleId}
+		update := bson.M{"$addToSet": bson.M{"roles.$.parent_roles": bson.M{
+			"$each": patch_role.AddedParentRoles,
+		}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove parent roles
+	if len(patch_role.RemovedParentRoles) > 0 {
+
+		filter := bson.M{"_id": orgId, "roles._id": roleId}
+		update := bson.M{"$pull": bson.M{"roles.$.parent_roles": bson.M{"$in": patch_role.RemovedParentRoles}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
+		if err != nil {
+			return err
+		}
+	}
+
+	// add policies
+	if len(patch_role.AddedPolicies) > 0 {
+
+		filter := bson.M{"_id": orgId, "roles._id": roleId}
+		update := bson.M{"$addToSet": bson.M{"roles.$.policies": bson.M{
+			"$each": patch_role.AddedPolicies,
+		}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove policies
+	if len(patch_role.RemovedPolicies) > 0 {
+
+		filter := bson.M{"_id": orgId, "roles._id": roleId}
+		update := bson.M{"$pull": bson.M{"roles.$.policies": bson.M{"$in": patch_role.RemovedPolicies}}}
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -293,9 +374,14 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_r
 	// remove permissions
 	if len(patch_role.RemovedPermissions) > 0 {
 
+		// Match on resource and action only, a role holds a single effect for each pair.
+		var removed []bson.M
+		for _, permission := range patch_role.RemovedPermissions {
+			removed = append(removed, bson.M{"resource": permission.Resource, "action": permission.Action})
+		}
 		filter := bson.M{"_id": orgId, "roles._id": roleId}
-		update := bson.M{"$pull": bson.M{"roles.$.permissions": bson.M{"$in": patch_role.RemovedPermissions}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		update := bson.M{"$pull": bson.M{"roles.$.permissions": bson.M{"$or": removed}}}
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -320,7 +406,7 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$pull": bson.M{"roles": bson.M{"_id": roleId}}}
 	// Find the role document in the "organizations" collection
-	result, err := r.mongoColl.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(false))
+	result, err := r.mongoColl.UpdateOne(context.Background(), filter, revision.Update(update), options.Update().SetUpsert(false))
 	if err != nil {
 		return err
 	}
@@ -331,15 +417,29 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 	}
 
 	filter = bson.M{"_id": orgId}
-	update = bson.M{"$pull": bson.M{"groups.$[].roles": roleId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	update = bson.M{"$pull": bson.M{
+		"groups.$[].roles":       roleId,
+		"groups.$[].timed_roles": bson.M{"role": roleId},
+	}}
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+	if err != nil {
+		return err
+	}
+
+	filter = bson.M{"_id": orgId}
+	update = bson.M{"$pull": bson.M{
+		"users.$[].roles":       roleId,
+		"users.$[].timed_roles": bson.M{"role": roleId},
+	}}
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
 
+	// Roles which inherited from the deleted role no longer do.
 	filter = bson.M{"_id": orgId}
-	update = bson.M{"$pull": bson.M{"users.$[].roles": roleId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	update = bson.M{"$pull": bson.M{"roles.$[].parent_roles": roleId}}
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
@@ -375,6 +475,27 @@ func (r repository) Query(ctx context.Context, org_id string) (*[]mongo_entity.R
 	return &org.Roles, nil
 }
 
+// Get every role with its parent roles and permissions, enough to walk the role hierarchy.
+func (r repository) GetRoleGraph(ctx context.Context, org_id string) ([]mongo_entity.Role, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": orgId}
+	projection := bson.M{"roles._id": 1, "roles.identifier": 1, "roles.display_name": 1, "roles.permissions": 1, "roles.parent_roles": 1}
+	var org mongo_entity.Organization
+	err = r.mongoColl.FindOne(ctx, filter, options.FindOne().SetProjection(projection)).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &util.NotFoundError{Path: "Organization"}
+		}
+		return nil, err
+	}
+	return org.Roles, nil
+}
+
 // Check if role exists by id.
 func (r repository) CheckRoleExistById(ctx context.Context, org_id string, id string) (bool, error) {
 
@@ -498,17 +619,27 @@ func (r repository) CheckResourceActionExists(ctx context.Context, org_id string
 		return false, err
 	}
 
-	filter := bson.M{"_id": orgId, "resources.identifier": resource_identifier, "resources.actions.identifier": action_identifier}
-	result := r.mongoColl.FindOne(context.Background(), filter)
-
-	// Check if the resource was found
-	if result.Err() == nil {
-		return true, nil
-	} else if result.Err() == mongo.ErrNoDocuments {
-		return false, nil
-	} else {
-		return false, result.Err()
+	// Resources and actions may be wildcard patterns, which must match at least one declared action.
+	var org mongo_entity.Organization
+	projection := bson.M{"resources": 1}
+	err = r.mongoColl.FindOne(ctx, bson.M{"_id": orgId}, options.FindOne().SetProjection(projection)).Decode(&org)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, resource := range org.Resources {
+		if !mongo_entity.MatchResource(resource_identifier, resource.Identifier) {
+			continue
+		}
+		for _, action := range resource.Actions {
+			if mongo_entity.MatchAction(action_identifier, action.Identifier) {
+				return true, nil
+			}
+		}
 	}
+	return false, nil
 }
 
 // check user already added to role
@@ -613,6 +744,34 @@ func (r repository) CheckGroupExistById(ctx context.Context, org_id string, id s
 	}
 }
 
+// Check if policy exists by id.
+func (r repository) CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error) {
+
+	orgId, err := primitive.ObjectIDFromHex(org_id)
+	if err != nil {
+		return false, err
+	}
+
+	policyId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": orgId, "policies._id": policyId}
+
+	// Search for the policy in the "organizations" collection
+	result := r.mongoColl.FindOne(context.Background(), filter)
+
+	// Check if the policy was found
+	if result.Err() == nil {
+		return true, nil
+	} else if result.Err() == mongo.ErrNoDocuments {
+		return false, nil
+	} else {
+		return false, result.Err()
+	}
+}
+
 // Check if group already assign to user by id.
 func (r repository) CheckGroupAlreadyAssignToRoleById(ctx context.Context, org_id string, role_id string, group_id string) (bool, error) {
 
@@ -708,3 +867,86 @@ func (r repository) resolveAssignedGroups(ctx context.Context, orgId primitive.O
 
 	return results[0].Groups, nil
 }
+
+// Get the revision the organization is at.
+func (r repository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, org_id)
+}
+
+// ExpiredAssignments finds the role assignments of users and groups of every organization which have
+// expired at the time.
+func (r repository) ExpiredAssignments(ctx context.Context, now time.Time) ([]ExpiredAssignment, error) {
+
+	filter := bson.M{"$or": []bson.M{
+		{"users.timed_roles.expires_at": bson.M{"$lte": now}},
+		{"groups.timed_roles.expires_at": bson.M{"$lte": now}},
+	}}
+	projection := bson.M{"users._id": 1, "users.timed_roles": 1, "groups._id": 1, "groups.timed_roles": 1}
+	cursor, err := r.mongoColl.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	assignments := []ExpiredAssignment{}
+	for cursor.Next(ctx) {
+		var org mongo_entity.Organization
+		if err := cursor.Decode(&org); err != nil {
+			return nil, err
+		}
+		for _, user := range org.Users {
+			for _, timedRole := range user.TimedRoles {
+				if timedRole.Expired(now) {
+					assignments = append(assignments, ExpiredAssignment{
+						OrganizationID: org.ID, Role: timedRole.Role, Entity: event.UserEntity, ID: user.ID})
+				}
+			}
+		}
+		for _, group := range org.Groups {
+			for _, timedRole := range group.TimedRoles {
+				if timedRole.Expired(now) {
+					assignments = append(assignments, ExpiredAssignment{
+						OrganizationID: org.ID, Role: timedRole.Role, Entity: event.GroupEntity, ID: group.ID})
+				}
+			}
+		}
+	}
+	return assignments, cursor.Err()
+}
+
+// RemoveExpiredAssignment removes a role from a user or a group, as long as the assignment is still expired
+// at the time. It reports false when the assignment was renewed or removed in the meantime.
+func (r repository) RemoveExpiredAssignment(ctx context.Context, assignment ExpiredAssignment, now time.Time) (bool, error) {
+
+	holders, members := "users", "roles.$.users"
+	if assignment.Entity == event.GroupEntity {
+		holders, members = "groups", "roles.$.groups"
+	}
+
+	filter := bson.M{"_id": assignment.OrganizationID, holders: bson.M{"$elemMatch": bson.M{
+		"_id": assignment.ID,
+		"timed_roles": bson.M{"$elemMatch": bson.M{
+			"role":       assignment.Role,
+			"expires_at": bson.M{"$lte": now},
+		}},
+	}}}
+	update := bson.M{"$pull": bson.M{
+		holders + ".$.roles":       assignment.Role,
+		holders + ".$.timed_roles": bson.M{"role": assignment.Role},
+	}}
+	result, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+	if err != nil {
+		return false, err
+	}
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	filter = bson.M{"_id": assignment.OrganizationID, "roles._id": assignment.Role}
+	update = bson.M{"$pull": bson.M{members: assignment.ID}}
+	if _, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
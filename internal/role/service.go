@@ -2,8 +2,11 @@ package role
 
 import (
 	"context"
+	"time"
 
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
@@ -34,6 +37,16 @@ type RoleResponse struct {
 	Users       []mongo_entity.AssignedUser  `json:"users,omitempty" bson:"users"`
 	Groups      []mongo_entity.AssignedGroup `json:"groups,omitempty" bson:"groups"`
 	Permissions []mongo_entity.Permission    `json:"permissions,omitempty" bson:"permissions"`
+	ParentRoles []mongo_entity.AssignedRole  `json:"parent_roles,omitempty" bson:"parent_roles"`
+	Policies    []primitive.ObjectID         `json:"policies,omitempty" bson:"policies"`
+	// Permissions the role gets from its parent roles, directly or transitively.
+	InheritedPermissions []InheritedPermission `json:"inherited_permissions,omitempty" bson:"-"`
+}
+
+// InheritedPermission is a permission of an ancestor role, along with the role it was declared on.
+type InheritedPermission struct {
+	mongo_entity.Permission `bson:",inline"`
+	Role                    mongo_entity.AssignedRole `json:"role" bson:"role"`
 }
 
 type CreateRoleRequest struct {
@@ -42,14 +55,36 @@ type CreateRoleRequest struct {
 	Users       []primitive.ObjectID      `json:"users,omitempty" bson:"users"`
 	Groups      []primitive.ObjectID      `json:"groups,omitempty" bson:"groups"`
 	Permissions []mongo_entity.Permission `json:"permissions,omitempty" bson:"permissions"`
+	ParentRoles []primitive.ObjectID      `json:"parent_roles,omitempty" bson:"parent_roles"`
+	// Policies condition every permission of the role, including the inherited ones.
+	Policies []primitive.ObjectID `json:"policies,omitempty" bson:"policies"`
 }
 
 func (m CreateRoleRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Identifier, validation.Required),
+		validation.Field(&m.Permissions, validation.By(validatePermissions)),
 	)
 }
 
+// validatePermissions makes sure every permission has a known effect and is given only once per resource and action.
+func validatePermissions(value interface{}) error {
+
+	permissions, _ := value.([]mongo_entity.Permission)
+	seen := make(map[mongo_entity.PermissionKey]bool)
+	for _, permission := range permissions {
+		if err := validation.Validate(permission.Effect, validation.In(mongo_entity.AllowEffect, mongo_entity.DenyEffect)); err != nil {
+			return err
+		}
+		key := permission.Key()
+		if seen[key] {
+			return validation.NewError("validation_permission_duplicated", "permission is given more than once")
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
 type UpdateRoleRequest struct {
 	DisplayName *string `json:"display_name" bson:"display_name"`
 }
@@ -61,6 +96,20 @@ type PatchRoleRequest struct {
 	RemovedGroups      []primitive.ObjectID      `json:"removed_groups,omitempty" bson:"removed_groups"`
 	AddedPermissions   []mongo_entity.Permission `json:"added_permissions,omitempty" bson:"added_permissions"`
 	RemovedPermissions []mongo_entity.Permission `json:"removed_permissions,omitempty" bson:"removed_permissions"`
+	AddedParentRoles   []primitive.ObjectID      `json:"added_parent_roles,omitempty" bson:"added_parent_roles"`
+	RemovedParentRoles []primitive.ObjectID      `json:"removed_parent_roles,omitempty" bson:"removed_parent_roles"`
+	AddedPolicies      []primitive.ObjectID      `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies    []primitive.ObjectID      `json:"removed_policies,omitempty" bson:"removed_policies"`
+	// NotBefore and ExpiresAt bound when the added users and groups hold the role.
+	NotBefore *time.Time `json:"not_before,omitempty" bson:"not_before"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at"`
+}
+
+func (m PatchRoleRequest) Validate() error {
+
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.AddedPermissions, validation.By(validatePermissions)),
+	)
 }
 
 type UpdateRole struct {
@@ -74,6 +123,12 @@ type PatchRole struct {
 	RemovedGroups      []primitive.ObjectID      `json:"removed_groups,omitempty" bson:"removed_groups"`
 	AddedPermissions   []mongo_entity.Permission `json:"added_permissions,omitempty" bson:"added_permissions"`
 	RemovedPermissions []mongo_entity.Permission `json:"removed_permissions,omitempty" bson:"removed_permissions"`
+	AddedParentRoles   []primitive.ObjectID      `json:"added_parent_roles,omitempty" bson:"added_parent_roles"`
+	RemovedParentRoles []primitive.ObjectID      `json:"removed_parent_roles,omitempty" bson:"removed_parent_roles"`
+	AddedPolicies      []primitive.ObjectID      `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies    []primitive.ObjectID      `json:"removed_policies,omitempty" bson:"removed_policies"`
+	// Bounds of the role for the added users and groups. They hold the role for good when it is not bounded.
+	Bounds mongo_entity.TimedRole `json:"-" bson:"-"`
 }
 
 func (m UpdateRoleRequest) Validate() error {
@@ -84,11 +139,25 @@ func (m UpdateRoleRequest) Validate() error {
 type service struct {
 	repo   Repository
 	logger *zap.Logger
+	events *event.Bus
+}
+
+func NewService(repo Repository, logger *zap.Logger, events *event.Bus) Service {
+
+	return service{repo: repo, logger: logger, events: events}
 }
 
-func NewService(repo Repository, logger *zap.Logger) Service {
+// changed publishes a write to a role, and tracks the revision the write moved the organization to.
+func (s service) changed(ctx context.Context, org_id string, id string) error {
 
-	return service{repo: repo, logger: logger}
+	s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.RoleEntity, ID: id})
+	rev, err := s.repo.GetOrganizationRevision(ctx, org_id)
+	if err != nil {
+		s.logger.Error("Error while reading organization revision.", zap.String("organization_id", org_id), zap.Error(err))
+		return err
+	}
+	revision.Track(ctx, rev)
+	return nil
 }
 
 // Get role by id.
@@ -101,6 +170,28 @@ func (s service) Get(ctx context.Context, org_id string, id string) (RoleRespons
 			zap.String("role_id", id))
 		return RoleResponse{}, &util.NotFoundError{Path: "Role"}
 	}
+
+	// Resolve the parent roles and everything inherited through them.
+	if len(role.ParentRoles) > 0 {
+		roles, err := s.repo.GetRoleGraph(ctx, org_id)
+		if err != nil {
+			s.logger.Error("Error while getting the role hierarchy.",
+				zap.String("organization_id", org_id),
+				zap.String("role_id", id))
+			return RoleResponse{}, err
+		}
+		for _, ancestor := range mongo_entity.AncestorRoles(roles, role.ID) {
+			assigned := mongo_entity.AssignedRole{ID: ancestor.ID, Identifier: ancestor.Identifier, DisplayName: ancestor.DisplayName}
+			for i, parent := range role.ParentRoles {
+				if parent.ID == ancestor.ID {
+					role.ParentRoles[i] = assigned
+				}
+			}
+			for _, permission := range ancestor.Permissions {
+				role.InheritedPermissions = append(role.InheritedPermissions, InheritedPermission{Permission: permission, Role: assigned})
+			}
+		}
+	}
 	return *role, nil
 }
 
@@ -158,6 +249,17 @@ func (s service) Create(ctx context.Context, org_id string, req CreateRoleReques
 		}
 	}
 
+	for _, parentId := range req.ParentRoles {
+		exists, _ := s.repo.CheckRoleExistById(ctx, org_id, parentId.Hex())
+		if !exists {
+			return RoleResponse{}, &util.InvalidInputError{Path: "Invalid parent role id " + parentId.String()}
+		}
+	}
+
+	if err := s.checkPolicies(ctx, org_id, req.Policies, req.Permissions); err != nil {
+		return RoleResponse{}, err
+	}
+
 	var users []primitive.ObjectID
 	if req.Users == nil {
 		users = []primitive.ObjectID{}
@@ -179,6 +281,13 @@ func (s service) Create(ctx context.Context, org_id string, req CreateRoleReques
 		permissions = req.Permissions
 	}
 
+	var parentRoles []primitive.ObjectID
+	if req.ParentRoles == nil {
+		parentRoles = []primitive.ObjectID{}
+	} else {
+		parentRoles = req.ParentRoles
+	}
+
 	err := s.repo.Create(ctx, org_id, mongo_entity.Role{
 		ID:          roleId,
 		Identifier:  req.Identifier,
@@ -186,6 +295,8 @@ func (s service) Create(ctx context.Context, org_id string, req CreateRoleReques
 		Users:       users,
 		Groups:      groups,
 		Permissions: permissions,
+		ParentRoles: parentRoles,
+		Policies:    req.Policies,
 	})
 
 	if err != nil {
@@ -194,6 +305,9 @@ func (s service) Create(ctx context.Context, org_id string, req CreateRoleReques
 			zap.String("role identifier", req.Identifier))
 		return RoleResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, roleId.Hex()); err != nil {
+		return RoleResponse{}, err
+	}
 	return s.Get(ctx, org_id, roleId.Hex())
 }
 
@@ -212,11 +326,20 @@ func (s service) Update(ctx context.Context, org_id string, id string, req Updat
 		s.logger.Error("Error while updating role.", zap.String("organization_id", org_id), zap.String("role_id", id))
 		return RoleResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return RoleResponse{}, err
+	}
 	return s.Get(ctx, org_id, id)
 }
 
 func (s service) Patch(ctx context.Context, org_id string, id string, req PatchRoleRequest) (RoleResponse, error) {
 
+	// Validate role request.
+	if err := req.Validate(); err != nil {
+		s.logger.Error("Error while validating role patch request.")
+		return RoleResponse{}, &util.InvalidInputError{Path: "Invalid input for role."}
+	}
+
 	_, err := s.Get(ctx, org_id, id)
 	if err != nil {
 		s.logger.Debug("Role not exists.", zap.String("role_id", id))
@@ -279,6 +402,12 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchR
 		}
 	}
 
+	roleId, _ := primitive.ObjectIDFromHex(id)
+	bounds := mongo_entity.TimedRole{Role: roleId, NotBefore: req.NotBefore, ExpiresAt: req.ExpiresAt}
+	if bounds.Bounded() && (len(req.AddedUsers)+len(req.AddedGroups) == 0 || !bounds.Valid(time.Now())) {
+		return RoleResponse{}, &util.InvalidInputError{Path: "Invalid role assignment bounds."}
+	}
+
 	// permissions
 	for _, permission := range req.AddedPermissions {
 
@@ -308,6 +437,27 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchR
 
 	}
 
+	// parent roles
+	for _, parentId := range req.AddedParentRoles {
+		exists, _ := s.repo.CheckRoleExistById(ctx, org_id, parentId.Hex())
+		if !exists {
+			return RoleResponse{}, &util.InvalidInputError{Path: "Invalid parent role id " + parentId.String()}
+		}
+	}
+	if err := s.checkPolicies(ctx, org_id, req.AddedPolicies, req.AddedPermissions); err != nil {
+		return RoleResponse{}, err
+	}
+
+	if len(req.AddedParentRoles) > 0 {
+		roles, err := s.repo.GetRoleGraph(ctx, org_id)
+		if err != nil {
+			return RoleResponse{}, err
+		}
+		if mongo_entity.CreatesRoleCycle(roles, roleId, req.AddedParentRoles) {
+			return RoleResponse{}, &util.InvalidInputError{Path: "Parent roles of role : " + id + " create a cycle."}
+		}
+	}
+
 	if err := s.repo.Patch(ctx, org_id, id, PatchRole{
 		AddedUsers:         req.AddedUsers,
 		RemovedUsers:       req.RemovedUsers,
@@ -315,13 +465,37 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchR
 		RemovedGroups:      req.RemovedGroups,
 		AddedPermissions:   req.AddedPermissions,
 		RemovedPermissions: req.RemovedPermissions,
+		AddedParentRoles:   req.AddedParentRoles,
+		RemovedParentRoles: req.RemovedParentRoles,
+		AddedPolicies:      req.AddedPolicies,
+		RemovedPolicies:    req.RemovedPolicies,
+		Bounds:             bounds,
 	}); err != nil {
 		s.logger.Error("Error while updating role.", zap.String("organization_id", org_id), zap.String("role_id", id))
 		return RoleResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return RoleResponse{}, err
+	}
 	return s.Get(ctx, org_id, id)
 }
 
+// checkPolicies makes sure the policies of a role and of its permissions exist.
+func (s service) checkPolicies(ctx context.Context, org_id string, policies []primitive.ObjectID, permissions []mongo_entity.Permission) error {
+
+	ids := append([]primitive.ObjectID{}, policies...)
+	for _, permission := range permissions {
+		ids = append(ids, permission.Policies...)
+	}
+	for _, policyId := range ids {
+		exists, _ := s.repo.CheckPolicyExistById(ctx, org_id, policyId.Hex())
+		if !exists {
+			return &util.InvalidInputError{Path: "Invalid policy id " + policyId.String()}
+		}
+	}
+	return nil
+}
+
 // Delete role.
 func (s service) Delete(ctx context.Context, org_id string, id string) error {
 
@@ -336,7 +510,7 @@ func (s service) Delete(ctx context.Context, org_id string, id string) error {
 			zap.String("resource_id", id))
 		return err
 	}
-	return nil
+	return s.changed(ctx, org_id, id)
 }
 
 type Filter struct {
@@ -374,7 +548,7 @@ func (s service) GetPermissions(ctx context.Context, org_id string, role_id stri
 	}
 
 	for _, item := range *items {
-		result = append(result, mongo_entity.Permission{Action: item.Action, Resource: item.Resource})
+		result = append(result, mongo_entity.Permission{Action: item.Action, Resource: item.Resource, Effect: item.Effect, Policies: item.Policies})
 	}
 	return result, err
 }
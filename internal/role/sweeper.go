@@ -0,0 +1,90 @@
+package role
+
+import (
+	"context"
+	"time"
+
+	"github.com/shashimalcse/cronuseo/internal/event"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ExpiredAssignment is a role assigned to a user or a group, whose assignment has expired.
+type ExpiredAssignment struct {
+	OrganizationID primitive.ObjectID
+	Role           primitive.ObjectID
+	// Entity is either a user or a group, identified by ID.
+	Entity event.Entity
+	ID     primitive.ObjectID
+}
+
+// Sweeper removes role assignments from users and groups once they expire. Checks ignore expired
+// assignments on their own, so the sweeper only keeps the stored assignments tidy.
+type Sweeper struct {
+	repo     Repository
+	logger   *zap.Logger
+	events   *event.Bus
+	interval time.Duration
+}
+
+func NewSweeper(repo Repository, logger *zap.Logger, events *event.Bus, interval time.Duration) *Sweeper {
+
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Sweeper{repo: repo, logger: logger, events: events, interval: interval}
+}
+
+// Run sweeps expired assignments on every interval. It blocks until the context is done.
+func (s *Sweeper) Run(ctx context.Context) {
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.Sweep(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep removes the assignments which have expired at the time, and publishes a change for every
+// user, group and role it touched.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) {
+
+	assignments, err := s.repo.ExpiredAssignments(ctx, now)
+	if err != nil {
+		s.logger.Error("Error while finding expired role assignments.", zap.Error(err))
+		return
+	}
+
+	removed := map[primitive.ObjectID][]ExpiredAssignment{}
+	for _, assignment := range assignments {
+		ok, err := s.repo.RemoveExpiredAssignment(ctx, assignment, now)
+		if err != nil {
+			s.logger.Error("Error while removing expired role assignment.",
+				zap.String("organization_id", assignment.OrganizationID.Hex()),
+				zap.String("role_id", assignment.Role.Hex()),
+				zap.String(string(assignment.Entity)+"_id", assignment.ID.Hex()),
+				zap.Error(err))
+			continue
+		}
+		if ok {
+			removed[assignment.OrganizationID] = append(removed[assignment.OrganizationID], assignment)
+		}
+	}
+
+	for orgId, assignments := range removed {
+		org_id := orgId.Hex()
+		for _, assignment := range assignments {
+			s.logger.Info("Removed expired role assignment.",
+				zap.String("organization_id", org_id),
+				zap.String("role_id", assignment.Role.Hex()),
+				zap.String(string(assignment.Entity)+"_id", assignment.ID.Hex()))
+			s.events.Publish(event.Event{OrganizationID: org_id, Entity: assignment.Entity, ID: assignment.ID.Hex()})
+			s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.RoleEntity, ID: assignment.Role.Hex()})
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	db "github.com/shashimalcse/cronuseo/internal/db/mongo"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -29,6 +30,7 @@ type Repository interface {
 	CheckPolicyExistById(ctx context.Context, org_id string, id string) (bool, error)
 	CheckPolicyAlreadyAssignToUserById(ctx context.Context, org_id string, user_id string, policy_id string) (bool, error)
 	GetOrgIdByIdentifier(ctx context.Context, identifier string) (string, error)
+	GetOrganizationRevision(ctx context.Context, org_id string) (int64, error)
 }
 
 type repository struct {
@@ -131,7 +133,7 @@ func (r repository) Create(ctx context.Context, org_id string, user mongo_entity
 	// Update the APIResources array for the given organization
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$push": bson.M{"users": user}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -142,7 +144,7 @@ func (r repository) Create(ctx context.Context, org_id string, user mongo_entity
 		for _, roleId := range user.Roles {
 			filter := bson.M{"_id": orgId, "roles._id": roleId}
 			update := bson.M{"$addToSet": bson.M{"roles.$.users": user.ID}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -155,7 +157,7 @@ func (r repository) Create(ctx context.Context, org_id string, user mongo_entity
 		for _, groupId := range user.Groups {
 			filter := bson.M{"_id": orgId, "groups._id": groupId}
 			update := bson.M{"$addToSet": bson.M{"groups.$.users": user.ID}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -181,7 +183,7 @@ func (r repository) Update(ctx context.Context, org_id string, id string, update
 	if update_user.UserProperties != nil {
 		update["$set"].(bson.M)["users.$.user_properties"] = *&update_user.UserProperties
 	}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -208,7 +210,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 	}
 
 	update := bson.M{"$set": updates}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -220,7 +222,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		update := bson.M{"$push": bson.M{"users.$.roles": bson.M{
 			"$each": patch_user.AddedRoles,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -228,7 +230,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		for _, roleId := range patch_user.AddedRoles {
 			filter := bson.M{"_id": orgId, "roles._id": roleId}
 			update := bson.M{"$addToSet": bson.M{"roles.$.users": userId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -236,12 +238,27 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 
 	}
 
+	// bound roles
+	if len(patch_user.TimedRoles) > 0 {
+
+		filter := bson.M{"_id": orgId, "users._id": userId}
+		for _, update := range mongo_entity.TimedRoleUpdates("users", patch_user.TimedRoles) {
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// remove roles
 	if len(patch_user.RemovedRoles) > 0 {
 
 		filter := bson.M{"_id": orgId, "users._id": userId}
-		update := bson.M{"$pull": bson.M{"users.$.roles": bson.M{"$in": patch_user.RemovedRoles}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		update := bson.M{"$pull": bson.M{
+			"users.$.roles":       bson.M{"$in": patch_user.RemovedRoles},
+			"users.$.timed_roles": bson.M{"role": bson.M{"$in": patch_user.RemovedRoles}},
+		}}
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -249,7 +266,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		for _, roleId := range patch_user.RemovedRoles {
 			filter := bson.M{"_id": orgId, "roles._id": roleId}
 			update := bson.M{"$pull": bson.M{"roles.$.users": userId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -263,7 +280,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		update := bson.M{"$push": bson.M{"users.$.groups": bson.M{
 			"$each": patch_user.AddedGroups,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -271,7 +288,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		for _, groupId := range patch_user.AddedGroups {
 			filter := bson.M{"_id": orgId, "groups._id": groupId}
 			update := bson.M{"$addToSet": bson.M{"groups.$.users": userId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -284,7 +301,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 
 		filter := bson.M{"_id": orgId, "users._id": userId}
 		update := bson.M{"$pull": bson.M{"users.$.groups": bson.M{"$in": patch_user.RemovedGroups}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -292,7 +309,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		for _, groupId := range patch_user.RemovedGroups {
 			filter := bson.M{"_id": orgId, "groups._id": groupId}
 			update := bson.M{"$pull": bson.M{"groups.$.users": userId}}
-			_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+			_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 			if err != nil {
 				return err
 			}
@@ -306,7 +323,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 		update := bson.M{"$push": bson.M{"users.$.policies": bson.M{
 			"$each": patch_user.AddedPolicies,
 		}}}
-		_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+		_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 		if err != nil {
 			return err
 		}
@@ -317,7 +334,7 @@ func (r repository) Patch(ctx context.Context, org_id string, id string, patch_u
 
 		filter := bson.M{"_id": orgId, "users._id": userId}
 		update := bson.M{"$pull": bson.M{"users.$.policies": bson.M{"$in": patch_user.RemovedPolicies}}}
-		_, err := r.mongoColl.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+		_, err := r.mongoColl.UpdateOne(ctx, filter, revision.Update(update), options.Update().SetUpsert(false))
 		if err != nil {
 			return err
 		}
@@ -343,7 +360,7 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 	filter := bson.M{"_id": orgId}
 	update := bson.M{"$pull": bson.M{"users": bson.M{"_id": userId}}}
 	// Find the user document in the "organizations" collection
-	result, err := r.mongoColl.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(false))
+	result, err := r.mongoColl.UpdateOne(context.Background(), filter, revision.Update(update), options.Update().SetUpsert(false))
 	if err != nil {
 		return err
 	}
@@ -355,14 +372,14 @@ func (r repository) Delete(ctx context.Context, org_id string, id string) error
 
 	filter = bson.M{"_id": orgId}
 	update = bson.M{"$pull": bson.M{"groups.$[].users": userId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
 
 	filter = bson.M{"_id": orgId}
 	update = bson.M{"$pull": bson.M{"roles.$[].users": userId}}
-	_, err = r.mongoColl.UpdateOne(ctx, filter, update)
+	_, err = r.mongoColl.UpdateOne(ctx, filter, revision.Update(update))
 	if err != nil {
 		return err
 	}
@@ -756,3 +773,9 @@ func (r repository) resolveAssignedPolicies(ctx context.Context, orgId primitive
 
 	return results[0].Policies, nil
 }
+
+// Get the revision the organization is at.
+func (r repository) GetOrganizationRevision(ctx context.Context, org_id string) (int64, error) {
+
+	return revision.Get(ctx, r.mongoColl, org_id)
+}
@@ -2,8 +2,11 @@ package user
 
 import (
 	"context"
+	"time"
 
+	"github.com/shashimalcse/cronuseo/internal/event"
 	"github.com/shashimalcse/cronuseo/internal/mongo_entity"
+	"github.com/shashimalcse/cronuseo/internal/revision"
 	"github.com/shashimalcse/cronuseo/internal/role"
 	"github.com/shashimalcse/cronuseo/internal/util"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -86,13 +89,16 @@ type UpdateUserRequest struct {
 }
 
 type PatchUserRequest struct {
-	UserProperties  map[string]interface{} `json:"user_properties,omitempty" bson:"user_properties"`
-	AddedRoles      []primitive.ObjectID   `json:"added_roles,omitempty" bson:"added_roles"`
-	RemovedRoles    []primitive.ObjectID   `json:"removed_roles,omitempty" bson:"removed_roles"`
-	AddedGroups     []primitive.ObjectID   `json:"added_groups,omitempty" bson:"added_groups"`
-	RemovedGroups   []primitive.ObjectID   `json:"removed_groups,omitempty" bson:"removed_groups"`
-	AddedPolicies   []primitive.ObjectID   `json:"added_policies,omitempty" bson:"added_policies"`
-	RemovedPolicies []primitive.ObjectID   `json:"removed_policies,omitempty" bson:"removed_policies"`
+	UserProperties map[string]interface{} `json:"user_properties,omitempty" bson:"user_properties"`
+	AddedRoles     []primitive.ObjectID   `json:"added_roles,omitempty" bson:"added_roles"`
+	// NotBefore and ExpiresAt bound when the added roles are held, replacing their previous bounds.
+	NotBefore       *time.Time           `json:"not_before,omitempty" bson:"not_before"`
+	ExpiresAt       *time.Time           `json:"expires_at,omitempty" bson:"expires_at"`
+	RemovedRoles    []primitive.ObjectID `json:"removed_roles,omitempty" bson:"removed_roles"`
+	AddedGroups     []primitive.ObjectID `json:"added_groups,omitempty" bson:"added_groups"`
+	RemovedGroups   []primitive.ObjectID `json:"removed_groups,omitempty" bson:"removed_groups"`
+	AddedPolicies   []primitive.ObjectID `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies []primitive.ObjectID `json:"removed_policies,omitempty" bson:"removed_policies"`
 }
 
 type UpdateUser struct {
@@ -100,13 +106,16 @@ type UpdateUser struct {
 }
 
 type PatchUser struct {
-	UserProperties  map[string]interface{} `json:"user_properties,omitempty" bson:"user_properties"`
-	AddedRoles      []primitive.ObjectID   `json:"added_roles,omitempty" bson:"added_roles"`
-	RemovedRoles    []primitive.ObjectID   `json:"removed_roles,omitempty" bson:"removed_roles"`
-	AddedGroups     []primitive.ObjectID   `json:"added_groups,omitempty" bson:"added_groups"`
-	RemovedGroups   []primitive.ObjectID   `json:"removed_groups,omitempty" bson:"removed_groups"`
-	AddedPolicies   []primitive.ObjectID   `json:"added_policies,omitempty" bson:"added_policies"`
-	RemovedPolicies []primitive.ObjectID   `json:"removed_policies,omitempty" bson:"removed_policies"`
+	UserProperties map[string]interface{} `json:"user_properties,omitempty" bson:"user_properties"`
+	AddedRoles     []primitive.ObjectID   `json:"added_roles,omitempty" bson:"added_roles"`
+	// TimedRoles bound every role added by the request, replacing the previous bounds of the role. A role
+	// without bounds is held for good.
+	TimedRoles      []mongo_entity.TimedRole `json:"timed_roles,omitempty" bson:"timed_roles"`
+	RemovedRoles    []primitive.ObjectID     `json:"removed_roles,omitempty" bson:"removed_roles"`
+	AddedGroups     []primitive.ObjectID     `json:"added_groups,omitempty" bson:"added_groups"`
+	RemovedGroups   []primitive.ObjectID     `json:"removed_groups,omitempty" bson:"removed_groups"`
+	AddedPolicies   []primitive.ObjectID     `json:"added_policies,omitempty" bson:"added_policies"`
+	RemovedPolicies []primitive.ObjectID     `json:"removed_policies,omitempty" bson:"removed_policies"`
 }
 
 func (m UpdateUserRequest) Validate() error {
@@ -117,11 +126,25 @@ type service struct {
 	repo        Repository
 	logger      *zap.Logger
 	roleService role.Service
+	events      *event.Bus
 }
 
-func NewService(repo Repository, logger *zap.Logger, roleService role.Service) Service {
+func NewService(repo Repository, logger *zap.Logger, roleService role.Service, events *event.Bus) Service {
 
-	return service{repo: repo, logger: logger, roleService: roleService}
+	return service{repo: repo, logger: logger, roleService: roleService, events: events}
+}
+
+// changed publishes a write to a user, and tracks the revision the write moved the organization to.
+func (s service) changed(ctx context.Context, org_id string, id string) error {
+
+	s.events.Publish(event.Event{OrganizationID: org_id, Entity: event.UserEntity, ID: id})
+	rev, err := s.repo.GetOrganizationRevision(ctx, org_id)
+	if err != nil {
+		s.logger.Error("Error while reading organization revision.", zap.String("organization_id", org_id), zap.Error(err))
+		return err
+	}
+	revision.Track(ctx, rev)
+	return nil
 }
 
 // Get user by id.
@@ -227,6 +250,9 @@ func (s service) Create(ctx context.Context, org_id string, req CreateUserReques
 			zap.String("organization_id", org_id))
 		return UserResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, userId.Hex()); err != nil {
+		return UserResponse{}, err
+	}
 	return s.Get(ctx, org_id, userId.Hex())
 }
 
@@ -347,6 +373,9 @@ func (s service) Update(ctx context.Context, org_id string, id string, req Updat
 			zap.String("user_id", id))
 		return UserResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return UserResponse{}, err
+	}
 	return s.Get(ctx, org_id, id)
 }
 
@@ -365,6 +394,15 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchU
 			return UserResponse{}, &util.InvalidInputError{Path: "Invalid role id " + roleId.String()}
 		}
 	}
+	bounds := mongo_entity.TimedRole{NotBefore: req.NotBefore, ExpiresAt: req.ExpiresAt}
+	if bounds.Bounded() && (len(req.AddedRoles) == 0 || !bounds.Valid(time.Now())) {
+		return UserResponse{}, &util.InvalidInputError{Path: "Invalid role assignment bounds."}
+	}
+	timed_roles := []mongo_entity.TimedRole{}
+	for _, roleId := range req.AddedRoles {
+		bounds.Role = roleId
+		timed_roles = append(timed_roles, bounds)
+	}
 	for _, roleId := range req.RemovedRoles {
 		exists, _ := s.repo.CheckRoleExistById(ctx, org_id, roleId.Hex())
 		if !exists {
@@ -448,6 +486,7 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchU
 	if err := s.repo.Patch(ctx, org_id, id, PatchUser{
 		UserProperties:  req.UserProperties,
 		AddedRoles:      added_roles,
+		TimedRoles:      timed_roles,
 		RemovedRoles:    removed_roles,
 		AddedGroups:     added_groups,
 		RemovedGroups:   removed_groups,
@@ -459,6 +498,9 @@ func (s service) Patch(ctx context.Context, org_id string, id string, req PatchU
 			zap.String("user_id", id))
 		return UserResponse{}, err
 	}
+	if err := s.changed(ctx, org_id, id); err != nil {
+		return UserResponse{}, err
+	}
 	return s.Get(ctx, org_id, id)
 }
 
@@ -477,7 +519,7 @@ func (s service) Delete(ctx context.Context, org_id string, id string) error {
 			zap.String("user_id", id))
 		return err
 	}
-	return nil
+	return s.changed(ctx, org_id, id)
 }
 
 // Pagination filter.
@@ -0,0 +1,21 @@
+package util
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a copy of the context which carries the subject making the request.
+func WithActor(ctx context.Context, subject string) context.Context {
+
+	return context.WithValue(ctx, actorKey{}, subject)
+}
+
+// Actor returns the subject making the request of the context, empty when the request is not authenticated.
+func Actor(ctx context.Context) string {
+
+	if ctx == nil {
+		return ""
+	}
+	subject, _ := ctx.Value(actorKey{}).(string)
+	return subject
+}
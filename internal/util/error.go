@@ -42,6 +42,30 @@ func (e *InvalidInputError) Error() string {
 	return "Invalid input."
 }
 
+// InvalidContentError reports where the content of an input can not be parsed. Line and column are
+// 1-based, and zero when the error has no position.
+type InvalidContentError struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *InvalidContentError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("Invalid %v: %v", e.Path, e.Message)
+	}
+	return fmt.Sprintf("Invalid %v at line %d, column %d: %v", e.Path, e.Line, e.Column, e.Message)
+}
+
+type PreconditionFailedError struct {
+	Path string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("Precondition failed: %v", e.Path)
+}
+
 type UnauthorizedError struct {
 	Message string
 }
@@ -54,12 +78,16 @@ func HandleError(err error) *echo.HTTPError {
 	switch e := err.(type) {
 	case *InvalidInputError:
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid inputs. Please check your inputs.")
+	case *InvalidContentError:
+		return echo.NewHTTPError(http.StatusBadRequest, e)
 	case *AlreadyExistsError:
 		return echo.NewHTTPError(http.StatusConflict, e.Error())
 	case *NotFoundError:
 		return echo.NewHTTPError(http.StatusNotFound, e.Error())
 	case *SystemError:
 		return echo.NewHTTPError(http.StatusInternalServerError, e.Error())
+	case *PreconditionFailedError:
+		return echo.NewHTTPError(http.StatusPreconditionFailed, e.Error())
 	case *UnauthorizedError:
 		return echo.NewHTTPError(http.StatusUnauthorized, e.Error())
 	default:
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.30.0
+// 	protoc-gen-go v1.34.2
 // 	protoc        v3.21.12
 // source: proto/check.proto
 
@@ -25,29 +25,1203 @@ type GrpcCheckRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	Username     string            `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Action       string            `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Resource     string            `protobuf:"bytes,3,opt,name=resource,proto3" json:"resource,omitempty"`
+	Organization string            `protobuf:"bytes,4,opt,name=organization,proto3" json:"organization,omitempty"`
+	Explain      bool              `protobuf:"varint,5,opt,name=explain,proto3" json:"explain,omitempty"`
+	ObjectId     string            `protobuf:"bytes,6,opt,name=object_id,json=objectId,proto3" json:"object_id,omitempty"`
+	Revision     string            `protobuf:"bytes,7,opt,name=revision,proto3" json:"revision,omitempty"`
+	Context      map[string]string `protobuf:"bytes,8,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *GrpcCheckRequest) Reset() {
+	*x = GrpcCheckRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcCheckRequest) ProtoMessage() {}
+
+func (x *GrpcCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcCheckRequest.ProtoReflect.Descriptor instead.
+func (*GrpcCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GrpcCheckRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *GrpcCheckRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *GrpcCheckRequest) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *GrpcCheckRequest) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+func (x *GrpcCheckRequest) GetExplain() bool {
+	if x != nil {
+		return x.Explain
+	}
+	return false
+}
+
+func (x *GrpcCheckRequest) GetObjectId() string {
+	if x != nil {
+		return x.ObjectId
+	}
+	return ""
+}
+
+func (x *GrpcCheckRequest) GetRevision() string {
+	if x != nil {
+		return x.Revision
+	}
+	return ""
+}
+
+func (x *GrpcCheckRequest) GetContext() map[string]string {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+type GrpcCheckResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Allow bool            `protobuf:"varint,1,opt,name=allow,proto3" json:"allow,omitempty"`
+	Trace *GrpcCheckTrace `protobuf:"bytes,2,opt,name=trace,proto3" json:"trace,omitempty"`
+}
+
+func (x *GrpcCheckResponse) Reset() {
+	*x = GrpcCheckResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcCheckResponse) ProtoMessage() {}
+
+func (x *GrpcCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcCheckResponse.ProtoReflect.Descriptor instead.
+func (*GrpcCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GrpcCheckResponse) GetAllow() bool {
+	if x != nil {
+		return x.Allow
+	}
+	return false
+}
+
+func (x *GrpcCheckResponse) GetTrace() *GrpcCheckTrace {
+	if x != nil {
+		return x.Trace
+	}
+	return nil
+}
+
+type GrpcCheckTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Reason    string             `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	Roles     []*GrpcRoleTrace   `protobuf:"bytes,2,rep,name=roles,proto3" json:"roles,omitempty"`
+	Policies  []*GrpcPolicyTrace `protobuf:"bytes,3,rep,name=policies,proto3" json:"policies,omitempty"`
+	Algorithm string             `protobuf:"bytes,4,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+}
+
+func (x *GrpcCheckTrace) Reset() {
+	*x = GrpcCheckTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcCheckTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcCheckTrace) ProtoMessage() {}
+
+func (x *GrpcCheckTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcCheckTrace.ProtoReflect.Descriptor instead.
+func (*GrpcCheckTrace) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GrpcCheckTrace) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *GrpcCheckTrace) GetRoles() []*GrpcRoleTrace {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *GrpcCheckTrace) GetPolicies() []*GrpcPolicyTrace {
+	if x != nil {
+		return x.Policies
+	}
+	return nil
+}
+
+func (x *GrpcCheckTrace) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+type GrpcRoleTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id         string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Identifier string `protobuf:"bytes,2,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Source     string `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	Group      string `protobuf:"bytes,4,opt,name=group,proto3" json:"group,omitempty"`
+	Granted    bool   `protobuf:"varint,5,opt,name=granted,proto3" json:"granted,omitempty"`
+	Denied     bool   `protobuf:"varint,6,opt,name=denied,proto3" json:"denied,omitempty"`
+}
+
+func (x *GrpcRoleTrace) Reset() {
+	*x = GrpcRoleTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcRoleTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcRoleTrace) ProtoMessage() {}
+
+func (x *GrpcRoleTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcRoleTrace.ProtoReflect.Descriptor instead.
+func (*GrpcRoleTrace) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GrpcRoleTrace) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GrpcRoleTrace) GetIdentifier() string {
+	if x != nil {
+		return x.Identifier
+	}
+	return ""
+}
+
+func (x *GrpcRoleTrace) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *GrpcRoleTrace) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *GrpcRoleTrace) GetGranted() bool {
+	if x != nil {
+		return x.Granted
+	}
+	return false
+}
+
+func (x *GrpcRoleTrace) GetDenied() bool {
+	if x != nil {
+		return x.Denied
+	}
+	return false
+}
+
+type GrpcPolicyTrace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id         string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Identifier string `protobuf:"bytes,2,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	Version    string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Result     bool   `protobuf:"varint,4,opt,name=result,proto3" json:"result,omitempty"`
+	Language   string `protobuf:"bytes,5,opt,name=language,proto3" json:"language,omitempty"`
+	Scope      string `protobuf:"bytes,6,opt,name=scope,proto3" json:"scope,omitempty"`
+}
+
+func (x *GrpcPolicyTrace) Reset() {
+	*x = GrpcPolicyTrace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcPolicyTrace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcPolicyTrace) ProtoMessage() {}
+
+func (x *GrpcPolicyTrace) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcPolicyTrace.ProtoReflect.Descriptor instead.
+func (*GrpcPolicyTrace) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GrpcPolicyTrace) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GrpcPolicyTrace) GetIdentifier() string {
+	if x != nil {
+		return x.Identifier
+	}
+	return ""
+}
+
+func (x *GrpcPolicyTrace) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *GrpcPolicyTrace) GetResult() bool {
+	if x != nil {
+		return x.Result
+	}
+	return false
+}
+
+func (x *GrpcPolicyTrace) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *GrpcPolicyTrace) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+type GrpcBatchCheckItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Username string            `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Action   string            `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Resource string            `protobuf:"bytes,3,opt,name=resource,proto3" json:"resource,omitempty"`
+	ObjectId string            `protobuf:"bytes,4,opt,name=object_id,json=objectId,proto3" json:"object_id,omitempty"`
+	Context  map[string]string `protobuf:"bytes,5,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Explain  bool              `protobuf:"varint,6,opt,name=explain,proto3" json:"explain,omitempty"`
+}
+
+func (x *GrpcBatchCheckItem) Reset() {
+	*x = GrpcBatchCheckItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcBatchCheckItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcBatchCheckItem) ProtoMessage() {}
+
+func (x *GrpcBatchCheckItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcBatchCheckItem.ProtoReflect.Descriptor instead.
+func (*GrpcBatchCheckItem) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GrpcBatchCheckItem) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *GrpcBatchCheckItem) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *GrpcBatchCheckItem) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *GrpcBatchCheckItem) GetObjectId() string {
+	if x != nil {
+		return x.ObjectId
+	}
+	return ""
+}
+
+func (x *GrpcBatchCheckItem) GetContext() map[string]string {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *GrpcBatchCheckItem) GetExplain() bool {
+	if x != nil {
+		return x.Explain
+	}
+	return false
+}
+
+type GrpcBatchCheckRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Organization string                `protobuf:"bytes,1,opt,name=organization,proto3" json:"organization,omitempty"`
+	Checks       []*GrpcBatchCheckItem `protobuf:"bytes,2,rep,name=checks,proto3" json:"checks,omitempty"`
+	Revision     string                `protobuf:"bytes,3,opt,name=revision,proto3" json:"revision,omitempty"`
+}
+
+func (x *GrpcBatchCheckRequest) Reset() {
+	*x = GrpcBatchCheckRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcBatchCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcBatchCheckRequest) ProtoMessage() {}
+
+func (x *GrpcBatchCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcBatchCheckRequest.ProtoReflect.Descriptor instead.
+func (*GrpcBatchCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GrpcBatchCheckRequest) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+func (x *GrpcBatchCheckRequest) GetChecks() []*GrpcBatchCheckItem {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
+func (x *GrpcBatchCheckRequest) GetRevision() string {
+	if x != nil {
+		return x.Revision
+	}
+	return ""
+}
+
+type GrpcBatchCheckResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*GrpcCheckResponse `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+}
+
+func (x *GrpcBatchCheckResponse) Reset() {
+	*x = GrpcBatchCheckResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcBatchCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcBatchCheckResponse) ProtoMessage() {}
+
+func (x *GrpcBatchCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcBatchCheckResponse.ProtoReflect.Descriptor instead.
+func (*GrpcBatchCheckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GrpcBatchCheckResponse) GetResults() []*GrpcCheckResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type GrpcEffectivePermissionsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
 	Username     string `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
-	Action       string `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
-	Resource     string `protobuf:"bytes,3,opt,name=resource,proto3" json:"resource,omitempty"`
-	Organization string `protobuf:"bytes,4,opt,name=organization,proto3" json:"organization,omitempty"`
+	Organization string `protobuf:"bytes,2,opt,name=organization,proto3" json:"organization,omitempty"`
+}
+
+func (x *GrpcEffectivePermissionsRequest) Reset() {
+	*x = GrpcEffectivePermissionsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcEffectivePermissionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcEffectivePermissionsRequest) ProtoMessage() {}
+
+func (x *GrpcEffectivePermissionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcEffectivePermissionsRequest.ProtoReflect.Descriptor instead.
+func (*GrpcEffectivePermissionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GrpcEffectivePermissionsRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *GrpcEffectivePermissionsRequest) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+type GrpcPermissionGrant struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RoleId         string `protobuf:"bytes,1,opt,name=role_id,json=roleId,proto3" json:"role_id,omitempty"`
+	RoleIdentifier string `protobuf:"bytes,2,opt,name=role_identifier,json=roleIdentifier,proto3" json:"role_identifier,omitempty"`
+	Source         string `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	Group          string `protobuf:"bytes,4,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (x *GrpcPermissionGrant) Reset() {
+	*x = GrpcPermissionGrant{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcPermissionGrant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcPermissionGrant) ProtoMessage() {}
+
+func (x *GrpcPermissionGrant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcPermissionGrant.ProtoReflect.Descriptor instead.
+func (*GrpcPermissionGrant) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GrpcPermissionGrant) GetRoleId() string {
+	if x != nil {
+		return x.RoleId
+	}
+	return ""
+}
+
+func (x *GrpcPermissionGrant) GetRoleIdentifier() string {
+	if x != nil {
+		return x.RoleIdentifier
+	}
+	return ""
+}
+
+func (x *GrpcPermissionGrant) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *GrpcPermissionGrant) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type GrpcEffectivePermission struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Resource string                 `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	Action   string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`
+	Grants   []*GrpcPermissionGrant `protobuf:"bytes,3,rep,name=grants,proto3" json:"grants,omitempty"`
+}
+
+func (x *GrpcEffectivePermission) Reset() {
+	*x = GrpcEffectivePermission{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcEffectivePermission) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcEffectivePermission) ProtoMessage() {}
+
+func (x *GrpcEffectivePermission) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcEffectivePermission.ProtoReflect.Descriptor instead.
+func (*GrpcEffectivePermission) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GrpcEffectivePermission) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *GrpcEffectivePermission) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *GrpcEffectivePermission) GetGrants() []*GrpcPermissionGrant {
+	if x != nil {
+		return x.Grants
+	}
+	return nil
+}
+
+type GrpcEffectivePermissionsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Permissions []*GrpcEffectivePermission `protobuf:"bytes,1,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	Policies    []*GrpcPolicyTrace         `protobuf:"bytes,2,rep,name=policies,proto3" json:"policies,omitempty"`
+	Denied      []*GrpcEffectivePermission `protobuf:"bytes,3,rep,name=denied,proto3" json:"denied,omitempty"`
+}
+
+func (x *GrpcEffectivePermissionsResponse) Reset() {
+	*x = GrpcEffectivePermissionsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcEffectivePermissionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcEffectivePermissionsResponse) ProtoMessage() {}
+
+func (x *GrpcEffectivePermissionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcEffectivePermissionsResponse.ProtoReflect.Descriptor instead.
+func (*GrpcEffectivePermissionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GrpcEffectivePermissionsResponse) GetPermissions() []*GrpcEffectivePermission {
+	if x != nil {
+		return x.Permissions
+	}
+	return nil
+}
+
+func (x *GrpcEffectivePermissionsResponse) GetPolicies() []*GrpcPolicyTrace {
+	if x != nil {
+		return x.Policies
+	}
+	return nil
+}
+
+func (x *GrpcEffectivePermissionsResponse) GetDenied() []*GrpcEffectivePermission {
+	if x != nil {
+		return x.Denied
+	}
+	return nil
+}
+
+type GrpcRelationTuple struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ObjectType      string `protobuf:"bytes,1,opt,name=object_type,json=objectType,proto3" json:"object_type,omitempty"`
+	ObjectId        string `protobuf:"bytes,2,opt,name=object_id,json=objectId,proto3" json:"object_id,omitempty"`
+	Relation        string `protobuf:"bytes,3,opt,name=relation,proto3" json:"relation,omitempty"`
+	SubjectType     string `protobuf:"bytes,4,opt,name=subject_type,json=subjectType,proto3" json:"subject_type,omitempty"`
+	SubjectId       string `protobuf:"bytes,5,opt,name=subject_id,json=subjectId,proto3" json:"subject_id,omitempty"`
+	SubjectRelation string `protobuf:"bytes,6,opt,name=subject_relation,json=subjectRelation,proto3" json:"subject_relation,omitempty"`
+}
+
+func (x *GrpcRelationTuple) Reset() {
+	*x = GrpcRelationTuple{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcRelationTuple) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcRelationTuple) ProtoMessage() {}
+
+func (x *GrpcRelationTuple) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcRelationTuple.ProtoReflect.Descriptor instead.
+func (*GrpcRelationTuple) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GrpcRelationTuple) GetObjectType() string {
+	if x != nil {
+		return x.ObjectType
+	}
+	return ""
+}
+
+func (x *GrpcRelationTuple) GetObjectId() string {
+	if x != nil {
+		return x.ObjectId
+	}
+	return ""
+}
+
+func (x *GrpcRelationTuple) GetRelation() string {
+	if x != nil {
+		return x.Relation
+	}
+	return ""
+}
+
+func (x *GrpcRelationTuple) GetSubjectType() string {
+	if x != nil {
+		return x.SubjectType
+	}
+	return ""
+}
+
+func (x *GrpcRelationTuple) GetSubjectId() string {
+	if x != nil {
+		return x.SubjectId
+	}
+	return ""
+}
+
+func (x *GrpcRelationTuple) GetSubjectRelation() string {
+	if x != nil {
+		return x.SubjectRelation
+	}
+	return ""
+}
+
+type GrpcRelationPrecondition struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Operation string             `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"`
+	Filter    *GrpcRelationTuple `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+}
+
+func (x *GrpcRelationPrecondition) Reset() {
+	*x = GrpcRelationPrecondition{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcRelationPrecondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcRelationPrecondition) ProtoMessage() {}
+
+func (x *GrpcRelationPrecondition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcRelationPrecondition.ProtoReflect.Descriptor instead.
+func (*GrpcRelationPrecondition) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GrpcRelationPrecondition) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *GrpcRelationPrecondition) GetFilter() *GrpcRelationTuple {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+type GrpcWriteRelationsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Organization  string                      `protobuf:"bytes,1,opt,name=organization,proto3" json:"organization,omitempty"`
+	Writes        []*GrpcRelationTuple        `protobuf:"bytes,2,rep,name=writes,proto3" json:"writes,omitempty"`
+	Deletes       []*GrpcRelationTuple        `protobuf:"bytes,3,rep,name=deletes,proto3" json:"deletes,omitempty"`
+	Preconditions []*GrpcRelationPrecondition `protobuf:"bytes,4,rep,name=preconditions,proto3" json:"preconditions,omitempty"`
+}
+
+func (x *GrpcWriteRelationsRequest) Reset() {
+	*x = GrpcWriteRelationsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcWriteRelationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcWriteRelationsRequest) ProtoMessage() {}
+
+func (x *GrpcWriteRelationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcWriteRelationsRequest.ProtoReflect.Descriptor instead.
+func (*GrpcWriteRelationsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GrpcWriteRelationsRequest) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+func (x *GrpcWriteRelationsRequest) GetWrites() []*GrpcRelationTuple {
+	if x != nil {
+		return x.Writes
+	}
+	return nil
+}
+
+func (x *GrpcWriteRelationsRequest) GetDeletes() []*GrpcRelationTuple {
+	if x != nil {
+		return x.Deletes
+	}
+	return nil
+}
+
+func (x *GrpcWriteRelationsRequest) GetPreconditions() []*GrpcRelationPrecondition {
+	if x != nil {
+		return x.Preconditions
+	}
+	return nil
+}
+
+type GrpcWriteRelationsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Revision string `protobuf:"bytes,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	// organization_revision is the revision of the organization after the write, as a consistency token.
+	OrganizationRevision string `protobuf:"bytes,2,opt,name=organization_revision,json=organizationRevision,proto3" json:"organization_revision,omitempty"`
+}
+
+func (x *GrpcWriteRelationsResponse) Reset() {
+	*x = GrpcWriteRelationsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcWriteRelationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcWriteRelationsResponse) ProtoMessage() {}
+
+func (x *GrpcWriteRelationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcWriteRelationsResponse.ProtoReflect.Descriptor instead.
+func (*GrpcWriteRelationsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GrpcWriteRelationsResponse) GetRevision() string {
+	if x != nil {
+		return x.Revision
+	}
+	return ""
+}
+
+func (x *GrpcWriteRelationsResponse) GetOrganizationRevision() string {
+	if x != nil {
+		return x.OrganizationRevision
+	}
+	return ""
+}
+
+type GrpcReadRelationsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Organization string             `protobuf:"bytes,1,opt,name=organization,proto3" json:"organization,omitempty"`
+	Filter       *GrpcRelationTuple `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+}
+
+func (x *GrpcReadRelationsRequest) Reset() {
+	*x = GrpcReadRelationsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcReadRelationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcReadRelationsRequest) ProtoMessage() {}
+
+func (x *GrpcReadRelationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcReadRelationsRequest.ProtoReflect.Descriptor instead.
+func (*GrpcReadRelationsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GrpcReadRelationsRequest) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+func (x *GrpcReadRelationsRequest) GetFilter() *GrpcRelationTuple {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+type GrpcReadRelationsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Relations []*GrpcRelationTuple `protobuf:"bytes,1,rep,name=relations,proto3" json:"relations,omitempty"`
+	Revision  string               `protobuf:"bytes,2,opt,name=revision,proto3" json:"revision,omitempty"`
 }
 
-func (x *GrpcCheckRequest) Reset() {
-	*x = GrpcCheckRequest{}
+func (x *GrpcReadRelationsResponse) Reset() {
+	*x = GrpcReadRelationsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_check_proto_msgTypes[0]
+		mi := &file_proto_check_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GrpcCheckRequest) String() string {
+func (x *GrpcReadRelationsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GrpcCheckRequest) ProtoMessage() {}
+func (*GrpcReadRelationsResponse) ProtoMessage() {}
 
-func (x *GrpcCheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_check_proto_msgTypes[0]
+func (x *GrpcReadRelationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -58,64 +1232,107 @@ func (x *GrpcCheckRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GrpcCheckRequest.ProtoReflect.Descriptor instead.
-func (*GrpcCheckRequest) Descriptor() ([]byte, []int) {
-	return file_proto_check_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use GrpcReadRelationsResponse.ProtoReflect.Descriptor instead.
+func (*GrpcReadRelationsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *GrpcCheckRequest) GetUsername() string {
+func (x *GrpcReadRelationsResponse) GetRelations() []*GrpcRelationTuple {
 	if x != nil {
-		return x.Username
+		return x.Relations
 	}
-	return ""
+	return nil
 }
 
-func (x *GrpcCheckRequest) GetAction() string {
+func (x *GrpcReadRelationsResponse) GetRevision() string {
 	if x != nil {
-		return x.Action
+		return x.Revision
 	}
 	return ""
 }
 
-func (x *GrpcCheckRequest) GetResource() string {
+type GrpcWatchRelationsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Organization string `protobuf:"bytes,1,opt,name=organization,proto3" json:"organization,omitempty"`
+	Revision     string `protobuf:"bytes,2,opt,name=revision,proto3" json:"revision,omitempty"`
+}
+
+func (x *GrpcWatchRelationsRequest) Reset() {
+	*x = GrpcWatchRelationsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_check_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrpcWatchRelationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrpcWatchRelationsRequest) ProtoMessage() {}
+
+func (x *GrpcWatchRelationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrpcWatchRelationsRequest.ProtoReflect.Descriptor instead.
+func (*GrpcWatchRelationsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GrpcWatchRelationsRequest) GetOrganization() string {
 	if x != nil {
-		return x.Resource
+		return x.Organization
 	}
 	return ""
 }
 
-func (x *GrpcCheckRequest) GetOrganization() string {
+func (x *GrpcWatchRelationsRequest) GetRevision() string {
 	if x != nil {
-		return x.Organization
+		return x.Revision
 	}
 	return ""
 }
 
-type GrpcCheckResponse struct {
+type GrpcRelationChange struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Allow bool `protobuf:"varint,1,opt,name=allow,proto3" json:"allow,omitempty"`
+	Revision  string             `protobuf:"bytes,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Operation string             `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`
+	Tuple     *GrpcRelationTuple `protobuf:"bytes,3,opt,name=tuple,proto3" json:"tuple,omitempty"`
 }
 
-func (x *GrpcCheckResponse) Reset() {
-	*x = GrpcCheckResponse{}
+func (x *GrpcRelationChange) Reset() {
+	*x = GrpcRelationChange{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_check_proto_msgTypes[1]
+		mi := &file_proto_check_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GrpcCheckResponse) String() string {
+func (x *GrpcRelationChange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GrpcCheckResponse) ProtoMessage() {}
+func (*GrpcRelationChange) ProtoMessage() {}
 
-func (x *GrpcCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_check_proto_msgTypes[1]
+func (x *GrpcRelationChange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_check_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -126,16 +1343,30 @@ func (x *GrpcCheckResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GrpcCheckResponse.ProtoReflect.Descriptor instead.
-func (*GrpcCheckResponse) Descriptor() ([]byte, []int) {
-	return file_proto_check_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use GrpcRelationChange.ProtoReflect.Descriptor instead.
+func (*GrpcRelationChange) Descriptor() ([]byte, []int) {
+	return file_proto_check_proto_rawDescGZIP(), []int{19}
 }
 
-func (x *GrpcCheckResponse) GetAllow() bool {
+func (x *GrpcRelationChange) GetRevision() string {
 	if x != nil {
-		return x.Allow
+		return x.Revision
 	}
-	return false
+	return ""
+}
+
+func (x *GrpcRelationChange) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *GrpcRelationChange) GetTuple() *GrpcRelationTuple {
+	if x != nil {
+		return x.Tuple
+	}
+	return nil
 }
 
 var File_proto_check_proto protoreflect.FileDescriptor
@@ -143,7 +1374,7 @@ var File_proto_check_proto protoreflect.FileDescriptor
 var file_proto_check_proto_rawDesc = []byte{
 	0x0a, 0x11, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x12, 0x0e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68,
-	0x65, 0x63, 0x6b, 0x22, 0x86, 0x01, 0x0a, 0x10, 0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63,
+	0x65, 0x63, 0x6b, 0x22, 0xde, 0x02, 0x0a, 0x10, 0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63,
 	0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72,
 	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72,
 	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02,
@@ -151,17 +1382,244 @@ var file_proto_check_proto_rawDesc = []byte{
 	0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
 	0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x6f, 0x72, 0x67, 0x61,
 	0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
-	0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x29, 0x0a, 0x11,
+	0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07,
+	0x65, 0x78, 0x70, 0x6c, 0x61, 0x69, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65,
+	0x78, 0x70, 0x6c, 0x61, 0x69, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x5f, 0x69, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x47, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x2d, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x1a, 0x3a, 0x0a, 0x0c, 0x43, 0x6f, 0x6e, 0x74,
+	0x65, 0x78, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0x5f, 0x0a, 0x11, 0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63,
+	0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x12,
+	0x34, 0x0a, 0x05, 0x74, 0x72, 0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e,
+	0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e,
+	0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x05,
+	0x74, 0x72, 0x61, 0x63, 0x65, 0x22, 0xb8, 0x01, 0x0a, 0x0e, 0x47, 0x72, 0x70, 0x63, 0x43, 0x68,
+	0x65, 0x63, 0x6b, 0x54, 0x72, 0x61, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x12, 0x33, 0x0a, 0x05, 0x72, 0x6f, 0x6c, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1d, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b,
+	0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x6f, 0x6c, 0x65, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x05,
+	0x72, 0x6f, 0x6c, 0x65, 0x73, 0x12, 0x3b, 0x0a, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73,
+	0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x50, 0x6f, 0x6c,
+	0x69, 0x63, 0x79, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69,
+	0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d,
+	0x22, 0x9f, 0x01, 0x0a, 0x0d, 0x47, 0x72, 0x70, 0x63, 0x52, 0x6f, 0x6c, 0x65, 0x54, 0x72, 0x61,
+	0x63, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69,
+	0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70,
+	0x12, 0x18, 0x0a, 0x07, 0x67, 0x72, 0x61, 0x6e, 0x74, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x67, 0x72, 0x61, 0x6e, 0x74, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x65,
+	0x6e, 0x69, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x64, 0x65, 0x6e, 0x69,
+	0x65, 0x64, 0x22, 0xa5, 0x01, 0x0a, 0x0f, 0x47, 0x72, 0x70, 0x63, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x54, 0x72, 0x61, 0x63, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x66, 0x69, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x6e, 0x67,
+	0x75, 0x61, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x61, 0x6e, 0x67,
+	0x75, 0x61, 0x67, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x22, 0xa2, 0x02, 0x0a, 0x12, 0x47,
+	0x72, 0x70, 0x63, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x49, 0x74, 0x65,
+	0x6d, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x49, 0x64, 0x12, 0x49,
+	0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x2f, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b,
+	0x2e, 0x47, 0x72, 0x70, 0x63, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x49,
+	0x74, 0x65, 0x6d, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x78, 0x70,
+	0x6c, 0x61, 0x69, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x78, 0x70, 0x6c,
+	0x61, 0x69, 0x6e, 0x1a, 0x3a, 0x0a, 0x0c, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0x93, 0x01, 0x0a, 0x15, 0x47, 0x72, 0x70, 0x63, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x6f, 0x72, 0x67,
+	0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3a, 0x0a,
+	0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e,
+	0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47,
+	0x72, 0x70, 0x63, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x49, 0x74, 0x65,
+	0x6d, 0x52, 0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x76,
+	0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x76,
+	0x69, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x55, 0x0a, 0x16, 0x47, 0x72, 0x70, 0x63, 0x42, 0x61, 0x74,
+	0x63, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x3b, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x21, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x61, 0x0a, 0x1f,
+	0x47, 0x72, 0x70, 0x63, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x65, 0x72,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x6f,
+	0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22,
+	0x85, 0x01, 0x0a, 0x13, 0x47, 0x72, 0x70, 0x63, 0x50, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x6f, 0x6c, 0x65, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x6f, 0x6c, 0x65, 0x49, 0x64,
+	0x12, 0x27, 0x0a, 0x0f, 0x72, 0x6f, 0x6c, 0x65, 0x5f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66,
+	0x69, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x72, 0x6f, 0x6c, 0x65, 0x49,
+	0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x22, 0x8a, 0x01, 0x0a, 0x17, 0x47, 0x72, 0x70, 0x63,
+	0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3b, 0x0a, 0x06, 0x67, 0x72, 0x61, 0x6e, 0x74,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73,
+	0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x50, 0x65, 0x72,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x47, 0x72, 0x61, 0x6e, 0x74, 0x52, 0x06, 0x67, 0x72,
+	0x61, 0x6e, 0x74, 0x73, 0x22, 0xeb, 0x01, 0x0a, 0x20, 0x47, 0x72, 0x70, 0x63, 0x45, 0x66, 0x66,
+	0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x49, 0x0a, 0x0b, 0x70, 0x65, 0x72,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27,
+	0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e,
+	0x47, 0x72, 0x70, 0x63, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x65, 0x72,
+	0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0b, 0x70, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x73, 0x12, 0x3b, 0x0a, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65,
+	0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x50, 0x6f, 0x6c, 0x69,
+	0x63, 0x79, 0x54, 0x72, 0x61, 0x63, 0x65, 0x52, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65,
+	0x73, 0x12, 0x3f, 0x0a, 0x06, 0x64, 0x65, 0x6e, 0x69, 0x65, 0x64, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x27, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65,
+	0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65,
+	0x50, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x64, 0x65, 0x6e, 0x69,
+	0x65, 0x64, 0x22, 0xda, 0x01, 0x0a, 0x11, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x62, 0x6a, 0x65,
+	0x63, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6f,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6f, 0x62, 0x6a,
+	0x65, 0x63, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6f, 0x62,
+	0x6a, 0x65, 0x63, 0x74, 0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x5f, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x75, 0x62, 0x6a, 0x65,
+	0x63, 0x74, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x10, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f,
+	0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f,
+	0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22,
+	0x73, 0x0a, 0x18, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x50,
+	0x72, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x6f,
+	0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x39, 0x0a, 0x06, 0x66, 0x69, 0x6c,
+	0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x72, 0x6f, 0x6e,
+	0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52,
+	0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x52, 0x06, 0x66, 0x69,
+	0x6c, 0x74, 0x65, 0x72, 0x22, 0x87, 0x02, 0x0a, 0x19, 0x47, 0x72, 0x70, 0x63, 0x57, 0x72, 0x69,
+	0x74, 0x65, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69,
+	0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x39, 0x0a, 0x06, 0x77, 0x72, 0x69, 0x74, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65,
+	0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x52, 0x06, 0x77, 0x72, 0x69, 0x74, 0x65,
+	0x73, 0x12, 0x3b, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68,
+	0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x54, 0x75, 0x70, 0x6c, 0x65, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x73, 0x12, 0x4e,
+	0x0a, 0x0d, 0x70, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f,
+	0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x50, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x0d, 0x70, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x6d,
+	0x0a, 0x1a, 0x47, 0x72, 0x70, 0x63, 0x57, 0x72, 0x69, 0x74, 0x65, 0x52, 0x65, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x33, 0x0a, 0x15, 0x6f, 0x72, 0x67, 0x61,
+	0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x14, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x79, 0x0a,
+	0x18, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x61, 0x64, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x6f, 0x72, 0x67,
+	0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x39, 0x0a,
+	0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e,
+	0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47,
+	0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x75, 0x70, 0x6c, 0x65,
+	0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x22, 0x78, 0x0a, 0x19, 0x47, 0x72, 0x70, 0x63,
+	0x52, 0x65, 0x61, 0x64, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3f, 0x0a, 0x09, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75,
+	0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65,
+	0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x75, 0x70, 0x6c, 0x65, 0x52, 0x09, 0x72, 0x65, 0x6c,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0x5b, 0x0a, 0x19, 0x47, 0x72, 0x70, 0x63, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52,
+	0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x22, 0x0a, 0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x7a, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x22,
+	0x87, 0x01, 0x0a, 0x12, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x76, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x37, 0x0a, 0x05, 0x74, 0x75, 0x70, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x21, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b,
+	0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x75, 0x70,
+	0x6c, 0x65, 0x52, 0x05, 0x74, 0x75, 0x70, 0x6c, 0x65, 0x32, 0xb3, 0x02, 0x0a, 0x05, 0x43, 0x68,
+	0x65, 0x63, 0x6b, 0x12, 0x4e, 0x0a, 0x05, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x20, 0x2e, 0x63,
+	0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72,
+	0x70, 0x63, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21,
+	0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e,
 	0x47, 0x72, 0x70, 0x63, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08,
-	0x52, 0x05, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x32, 0x57, 0x0a, 0x05, 0x43, 0x68, 0x65, 0x63, 0x6b,
-	0x12, 0x4e, 0x0a, 0x05, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x12, 0x20, 0x2e, 0x63, 0x72, 0x6f, 0x6e,
-	0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x43,
-	0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x63, 0x72,
+	0x65, 0x22, 0x00, 0x12, 0x5d, 0x0a, 0x0a, 0x62, 0x61, 0x74, 0x63, 0x68, 0x43, 0x68, 0x65, 0x63,
+	0x6b, 0x12, 0x25, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65,
+	0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x68, 0x65, 0x63,
+	0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75,
+	0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x42, 0x61,
+	0x74, 0x63, 0x68, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x12, 0x7b, 0x0a, 0x14, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50,
+	0x65, 0x72, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2f, 0x2e, 0x63, 0x72, 0x6f,
+	0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63,
+	0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x65, 0x72, 0x6d, 0x69, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x30, 0x2e, 0x63, 0x72,
 	0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70,
-	0x63, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
-	0x42, 0x09, 0x5a, 0x07, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x63, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x65, 0x72, 0x6d, 0x69, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x32,
+	0xa7, 0x02, 0x0a, 0x08, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x60, 0x0a, 0x05,
+	0x77, 0x72, 0x69, 0x74, 0x65, 0x12, 0x29, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f,
+	0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x57, 0x72, 0x69, 0x74, 0x65,
+	0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x2a, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x57, 0x72, 0x69, 0x74, 0x65, 0x52, 0x65, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5d,
+	0x0a, 0x04, 0x72, 0x65, 0x61, 0x64, 0x12, 0x28, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65,
+	0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x61, 0x64,
+	0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x29, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x61, 0x64, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5a, 0x0a,
+	0x05, 0x77, 0x61, 0x74, 0x63, 0x68, 0x12, 0x29, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65,
+	0x6f, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x57, 0x61, 0x74, 0x63,
+	0x68, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x22, 0x2e, 0x63, 0x72, 0x6f, 0x6e, 0x75, 0x73, 0x65, 0x6f, 0x2e, 0x63, 0x68, 0x65,
+	0x63, 0x6b, 0x2e, 0x47, 0x72, 0x70, 0x63, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x43,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x22, 0x00, 0x30, 0x01, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -176,19 +1634,67 @@ func file_proto_check_proto_rawDescGZIP() []byte {
 	return file_proto_check_proto_rawDescData
 }
 
-var file_proto_check_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_proto_check_proto_goTypes = []interface{}{
-	(*GrpcCheckRequest)(nil),  // 0: cronuseo.check.GrpcCheckRequest
-	(*GrpcCheckResponse)(nil), // 1: cronuseo.check.GrpcCheckResponse
+var file_proto_check_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_proto_check_proto_goTypes = []any{
+	(*GrpcCheckRequest)(nil),                 // 0: cronuseo.check.GrpcCheckRequest
+	(*GrpcCheckResponse)(nil),                // 1: cronuseo.check.GrpcCheckResponse
+	(*GrpcCheckTrace)(nil),                   // 2: cronuseo.check.GrpcCheckTrace
+	(*GrpcRoleTrace)(nil),                    // 3: cronuseo.check.GrpcRoleTrace
+	(*GrpcPolicyTrace)(nil),                  // 4: cronuseo.check.GrpcPolicyTrace
+	(*GrpcBatchCheckItem)(nil),               // 5: cronuseo.check.GrpcBatchCheckItem
+	(*GrpcBatchCheckRequest)(nil),            // 6: cronuseo.check.GrpcBatchCheckRequest
+	(*GrpcBatchCheckResponse)(nil),           // 7: cronuseo.check.GrpcBatchCheckResponse
+	(*GrpcEffectivePermissionsRequest)(nil),  // 8: cronuseo.check.GrpcEffectivePermissionsRequest
+	(*GrpcPermissionGrant)(nil),              // 9: cronuseo.check.GrpcPermissionGrant
+	(*GrpcEffectivePermission)(nil),          // 10: cronuseo.check.GrpcEffectivePermission
+	(*GrpcEffectivePermissionsResponse)(nil), // 11: cronuseo.check.GrpcEffectivePermissionsResponse
+	(*GrpcRelationTuple)(nil),                // 12: cronuseo.check.GrpcRelationTuple
+	(*GrpcRelationPrecondition)(nil),         // 13: cronuseo.check.GrpcRelationPrecondition
+	(*GrpcWriteRelationsRequest)(nil),        // 14: cronuseo.check.GrpcWriteRelationsRequest
+	(*GrpcWriteRelationsResponse)(nil),       // 15: cronuseo.check.GrpcWriteRelationsResponse
+	(*GrpcReadRelationsRequest)(nil),         // 16: cronuseo.check.GrpcReadRelationsRequest
+	(*GrpcReadRelationsResponse)(nil),        // 17: cronuseo.check.GrpcReadRelationsResponse
+	(*GrpcWatchRelationsRequest)(nil),        // 18: cronuseo.check.GrpcWatchRelationsRequest
+	(*GrpcRelationChange)(nil),               // 19: cronuseo.check.GrpcRelationChange
+	nil,                                      // 20: cronuseo.check.GrpcCheckRequest.ContextEntry
+	nil,                                      // 21: cronuseo.check.GrpcBatchCheckItem.ContextEntry
 }
 var file_proto_check_proto_depIdxs = []int32{
-	0, // 0: cronuseo.check.Check.check:input_type -> cronuseo.check.GrpcCheckRequest
-	1, // 1: cronuseo.check.Check.check:output_type -> cronuseo.check.GrpcCheckResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	20, // 0: cronuseo.check.GrpcCheckRequest.context:type_name -> cronuseo.check.GrpcCheckRequest.ContextEntry
+	2,  // 1: cronuseo.check.GrpcCheckResponse.trace:type_name -> cronuseo.check.GrpcCheckTrace
+	3,  // 2: cronuseo.check.GrpcCheckTrace.roles:type_name -> cronuseo.check.GrpcRoleTrace
+	4,  // 3: cronuseo.check.GrpcCheckTrace.policies:type_name -> cronuseo.check.GrpcPolicyTrace
+	21, // 4: cronuseo.check.GrpcBatchCheckItem.context:type_name -> cronuseo.check.GrpcBatchCheckItem.ContextEntry
+	5,  // 5: cronuseo.check.GrpcBatchCheckRequest.checks:type_name -> cronuseo.check.GrpcBatchCheckItem
+	1,  // 6: cronuseo.check.GrpcBatchCheckResponse.results:type_name -> cronuseo.check.GrpcCheckResponse
+	9,  // 7: cronuseo.check.GrpcEffectivePermission.grants:type_name -> cronuseo.check.GrpcPermissionGrant
+	10, // 8: cronuseo.check.GrpcEffectivePermissionsResponse.permissions:type_name -> cronuseo.check.GrpcEffectivePermission
+	4,  // 9: cronuseo.check.GrpcEffectivePermissionsResponse.policies:type_name -> cronuseo.check.GrpcPolicyTrace
+	10, // 10: cronuseo.check.GrpcEffectivePermissionsResponse.denied:type_name -> cronuseo.check.GrpcEffectivePermission
+	12, // 11: cronuseo.check.GrpcRelationPrecondition.filter:type_name -> cronuseo.check.GrpcRelationTuple
+	12, // 12: cronuseo.check.GrpcWriteRelationsRequest.writes:type_name -> cronuseo.check.GrpcRelationTuple
+	12, // 13: cronuseo.check.GrpcWriteRelationsRequest.deletes:type_name -> cronuseo.check.GrpcRelationTuple
+	13, // 14: cronuseo.check.GrpcWriteRelationsRequest.preconditions:type_name -> cronuseo.check.GrpcRelationPrecondition
+	12, // 15: cronuseo.check.GrpcReadRelationsRequest.filter:type_name -> cronuseo.check.GrpcRelationTuple
+	12, // 16: cronuseo.check.GrpcReadRelationsResponse.relations:type_name -> cronuseo.check.GrpcRelationTuple
+	12, // 17: cronuseo.check.GrpcRelationChange.tuple:type_name -> cronuseo.check.GrpcRelationTuple
+	0,  // 18: cronuseo.check.Check.check:input_type -> cronuseo.check.GrpcCheckRequest
+	6,  // 19: cronuseo.check.Check.batchCheck:input_type -> cronuseo.check.GrpcBatchCheckRequest
+	8,  // 20: cronuseo.check.Check.effectivePermissions:input_type -> cronuseo.check.GrpcEffectivePermissionsRequest
+	14, // 21: cronuseo.check.Relation.write:input_type -> cronuseo.check.GrpcWriteRelationsRequest
+	16, // 22: cronuseo.check.Relation.read:input_type -> cronuseo.check.GrpcReadRelationsRequest
+	18, // 23: cronuseo.check.Relation.watch:input_type -> cronuseo.check.GrpcWatchRelationsRequest
+	1,  // 24: cronuseo.check.Check.check:output_type -> cronuseo.check.GrpcCheckResponse
+	7,  // 25: cronuseo.check.Check.batchCheck:output_type -> cronuseo.check.GrpcBatchCheckResponse
+	11, // 26: cronuseo.check.Check.effectivePermissions:output_type -> cronuseo.check.GrpcEffectivePermissionsResponse
+	15, // 27: cronuseo.check.Relation.write:output_type -> cronuseo.check.GrpcWriteRelationsResponse
+	17, // 28: cronuseo.check.Relation.read:output_type -> cronuseo.check.GrpcReadRelationsResponse
+	19, // 29: cronuseo.check.Relation.watch:output_type -> cronuseo.check.GrpcRelationChange
+	24, // [24:30] is the sub-list for method output_type
+	18, // [18:24] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_proto_check_proto_init() }
@@ -197,7 +1703,7 @@ func file_proto_check_proto_init() {
 		return
 	}
 	if !protoimpl.UnsafeEnabled {
-		file_proto_check_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+		file_proto_check_proto_msgTypes[0].Exporter = func(v any, i int) any {
 			switch v := v.(*GrpcCheckRequest); i {
 			case 0:
 				return &v.state
@@ -209,7 +1715,7 @@ func file_proto_check_proto_init() {
 				return nil
 			}
 		}
-		file_proto_check_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+		file_proto_check_proto_msgTypes[1].Exporter = func(v any, i int) any {
 			switch v := v.(*GrpcCheckResponse); i {
 			case 0:
 				return &v.state
@@ -221,6 +1727,222 @@ func file_proto_check_proto_init() {
 				return nil
 			}
 		}
+		file_proto_check_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcCheckTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcRoleTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcPolicyTrace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcBatchCheckItem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcBatchCheckRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcBatchCheckResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcEffectivePermissionsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcPermissionGrant); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcEffectivePermission); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcEffectivePermissionsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcRelationTuple); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcRelationPrecondition); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcWriteRelationsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcWriteRelationsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcReadRelationsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcReadRelationsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcWatchRelationsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_check_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*GrpcRelationChange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -228,9 +1950,9 @@ func file_proto_check_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_check_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   22,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_check_proto_goTypes,
 		DependencyIndexes: file_proto_check_proto_depIdxs,
@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Check_Check_FullMethodName = "/cronuseo.check.Check/check"
+	Check_Check_FullMethodName                = "/cronuseo.check.Check/check"
+	Check_BatchCheck_FullMethodName           = "/cronuseo.check.Check/batchCheck"
+	Check_EffectivePermissions_FullMethodName = "/cronuseo.check.Check/effectivePermissions"
 )
 
 // CheckClient is the client API for Check service.
@@ -27,6 +29,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CheckClient interface {
 	Check(ctx context.Context, in *GrpcCheckRequest, opts ...grpc.CallOption) (*GrpcCheckResponse, error)
+	BatchCheck(ctx context.Context, in *GrpcBatchCheckRequest, opts ...grpc.CallOption) (*GrpcBatchCheckResponse, error)
+	EffectivePermissions(ctx context.Context, in *GrpcEffectivePermissionsRequest, opts ...grpc.CallOption) (*GrpcEffectivePermissionsResponse, error)
 }
 
 type checkClient struct {
@@ -46,11 +50,31 @@ func (c *checkClient) Check(ctx context.Context, in *GrpcCheckRequest, opts ...g
 	return out, nil
 }
 
+func (c *checkClient) BatchCheck(ctx context.Context, in *GrpcBatchCheckRequest, opts ...grpc.CallOption) (*GrpcBatchCheckResponse, error) {
+	out := new(GrpcBatchCheckResponse)
+	err := c.cc.Invoke(ctx, Check_BatchCheck_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkClient) EffectivePermissions(ctx context.Context, in *GrpcEffectivePermissionsRequest, opts ...grpc.CallOption) (*GrpcEffectivePermissionsResponse, error) {
+	out := new(GrpcEffectivePermissionsResponse)
+	err := c.cc.Invoke(ctx, Check_EffectivePermissions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CheckServer is the server API for Check service.
 // All implementations must embed UnimplementedCheckServer
 // for forward compatibility
 type CheckServer interface {
 	Check(context.Context, *GrpcCheckRequest) (*GrpcCheckResponse, error)
+	BatchCheck(context.Context, *GrpcBatchCheckRequest) (*GrpcBatchCheckResponse, error)
+	EffectivePermissions(context.Context, *GrpcEffectivePermissionsRequest) (*GrpcEffectivePermissionsResponse, error)
 }
 
 // UnimplementedCheckServer must be embedded to have forward compatible implementations.
@@ -60,6 +84,12 @@ type UnimplementedCheckServer struct {
 func (UnimplementedCheckServer) Check(context.Context, *GrpcCheckRequest) (*GrpcCheckResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
 }
+func (UnimplementedCheckServer) BatchCheck(context.Context, *GrpcBatchCheckRequest) (*GrpcBatchCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCheck not implemented")
+}
+func (UnimplementedCheckServer) EffectivePermissions(context.Context, *GrpcEffectivePermissionsRequest) (*GrpcEffectivePermissionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EffectivePermissions not implemented")
+}
 func (UnimplementedCheckServer) mustEmbedUnimplementedCheckServer() {}
 
 // UnsafeCheckServer may be embedded to opt out of forward compatibility for this service.
@@ -91,6 +121,42 @@ func _Check_Check_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Check_BatchCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrpcBatchCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).BatchCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_BatchCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).BatchCheck(ctx, req.(*GrpcBatchCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Check_EffectivePermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrpcEffectivePermissionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckServer).EffectivePermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Check_EffectivePermissions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckServer).EffectivePermissions(ctx, req.(*GrpcEffectivePermissionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Check_ServiceDesc is the grpc.ServiceDesc for Check service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -102,7 +168,206 @@ var Check_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "check",
 			Handler:    _Check_Check_Handler,
 		},
+		{
+			MethodName: "batchCheck",
+			Handler:    _Check_BatchCheck_Handler,
+		},
+		{
+			MethodName: "effectivePermissions",
+			Handler:    _Check_EffectivePermissions_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/check.proto",
 }
+
+const (
+	Relation_Write_FullMethodName = "/cronuseo.check.Relation/write"
+	Relation_Read_FullMethodName  = "/cronuseo.check.Relation/read"
+	Relation_Watch_FullMethodName = "/cronuseo.check.Relation/watch"
+)
+
+// RelationClient is the client API for Relation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RelationClient interface {
+	Write(ctx context.Context, in *GrpcWriteRelationsRequest, opts ...grpc.CallOption) (*GrpcWriteRelationsResponse, error)
+	Read(ctx context.Context, in *GrpcReadRelationsRequest, opts ...grpc.CallOption) (*GrpcReadRelationsResponse, error)
+	Watch(ctx context.Context, in *GrpcWatchRelationsRequest, opts ...grpc.CallOption) (Relation_WatchClient, error)
+}
+
+type relationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRelationClient(cc grpc.ClientConnInterface) RelationClient {
+	return &relationClient{cc}
+}
+
+func (c *relationClient) Write(ctx context.Context, in *GrpcWriteRelationsRequest, opts ...grpc.CallOption) (*GrpcWriteRelationsResponse, error) {
+	out := new(GrpcWriteRelationsResponse)
+	err := c.cc.Invoke(ctx, Relation_Write_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relationClient) Read(ctx context.Context, in *GrpcReadRelationsRequest, opts ...grpc.CallOption) (*GrpcReadRelationsResponse, error) {
+	out := new(GrpcReadRelationsResponse)
+	err := c.cc.Invoke(ctx, Relation_Read_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relationClient) Watch(ctx context.Context, in *GrpcWatchRelationsRequest, opts ...grpc.CallOption) (Relation_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Relation_ServiceDesc.Streams[0], Relation_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &relationWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Relation_WatchClient interface {
+	Recv() (*GrpcRelationChange, error)
+	grpc.ClientStream
+}
+
+type relationWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *relationWatchClient) Recv() (*GrpcRelationChange, error) {
+	m := new(GrpcRelationChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RelationServer is the server API for Relation service.
+// All implementations must embed UnimplementedRelationServer
+// for forward compatibility
+type RelationServer interface {
+	Write(context.Context, *GrpcWriteRelationsRequest) (*GrpcWriteRelationsResponse, error)
+	Read(context.Context, *GrpcReadRelationsRequest) (*GrpcReadRelationsResponse, error)
+	Watch(*GrpcWatchRelationsRequest, Relation_WatchServer) error
+}
+
+// UnimplementedRelationServer must be embedded to have forward compatible implementations.
+type UnimplementedRelationServer struct {
+}
+
+func (UnimplementedRelationServer) Write(context.Context, *GrpcWriteRelationsRequest) (*GrpcWriteRelationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Write not implemented")
+}
+func (UnimplementedRelationServer) Read(context.Context, *GrpcReadRelationsRequest) (*GrpcReadRelationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Read not implemented")
+}
+func (UnimplementedRelationServer) Watch(*GrpcWatchRelationsRequest, Relation_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedRelationServer) mustEmbedUnimplementedRelationServer() {}
+
+// UnsafeRelationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RelationServer will
+// result in compilation errors.
+type UnsafeRelationServer interface {
+	mustEmbedUnimplementedRelationServer()
+}
+
+func RegisterRelationServer(s grpc.ServiceRegistrar, srv RelationServer) {
+	s.RegisterService(&Relation_ServiceDesc, srv)
+}
+
+func _Relation_Write_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrpcWriteRelationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelationServer).Write(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Relation_Write_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelationServer).Write(ctx, req.(*GrpcWriteRelationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Relation_Read_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrpcReadRelationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelationServer).Read(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Relation_Read_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelationServer).Read(ctx, req.(*GrpcReadRelationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Relation_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GrpcWatchRelationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RelationServer).Watch(m, &relationWatchServer{stream})
+}
+
+type Relation_WatchServer interface {
+	Send(*GrpcRelationChange) error
+	grpc.ServerStream
+}
+
+type relationWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *relationWatchServer) Send(m *GrpcRelationChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Relation_ServiceDesc is the grpc.ServiceDesc for Relation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Relation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cronuseo.check.Relation",
+	HandlerType: (*RelationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "write",
+			Handler:    _Relation_Write_Handler,
+		},
+		{
+			MethodName: "read",
+			Handler:    _Relation_Read_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "watch",
+			Handler:       _Relation_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/check.proto",
+}